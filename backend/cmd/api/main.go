@@ -16,6 +16,15 @@ import (
 // @securityDefinitions.apiKey ApiKeyAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apiKey UserApiKey
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.oauth2.application petstore_auth
+// @tokenUrl /oauth/token
+// @scope.read:pets read your pets
+// @scope.write:pets modify pets in your account
 func main() {
 
 	a, err := app.NewApp()
@@ -2,19 +2,32 @@ package app
 
 import (
 	"backend/internal/lib/e"
+	"backend/internal/lib/imaging"
+	"backend/internal/lib/oidc"
+	"backend/internal/lib/passwords"
 	"backend/internal/lib/rr"
+	"backend/internal/mail"
+	"backend/internal/mail/filemail"
+	"backend/internal/mail/smtpmail"
 	"backend/internal/modules"
+	ae "backend/internal/modules/auth/entities"
+	au "backend/internal/modules/auth/service"
 	"backend/internal/repository"
 	"backend/internal/repository/dbrepo"
+	"backend/internal/storage"
+	"backend/internal/storage/filestore"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -30,15 +43,36 @@ type Server interface {
 }
 
 type App struct {
-	Host        string
-	Port        string
-	JWTSecret   string
-	server      *http.Server
-	signalChan  chan os.Signal
-	DSN         string
-	DB          repository.Repository
-	services    *modules.Services
-	controllers *modules.Controllers
+	Host              string
+	Port              string
+	JWTSecret         string
+	JWTKeys           []au.SigningKey
+	server            *http.Server
+	signalChan        chan os.Signal
+	DSN               string
+	DB                repository.Repository
+	StorageDir        string
+	Storage           storage.Storage
+	Images            *imaging.Processor
+	PasswordPolicy    *passwords.Policy
+	PasswordHasher    *passwords.Hasher
+	SMTPAddr          string
+	SMTPUsername      string
+	SMTPPassword      string
+	MailFrom          string
+	MailDir           string
+	MailLinkUrl       string
+	Mailer            mail.Mailer
+	OIDCProvider      *oidc.Provider // nil unless external login is enabled
+	OIDCDefaultRole   ae.Role
+	AdminUsername     string
+	AdminPassword     string
+	AdminEmail        string
+	ProblemDetails    bool
+	LegacyLogin       bool
+	LegacyLoginSunset time.Time
+	services          *modules.Services
+	controllers       *modules.Controllers
 }
 
 func NewApp() (a *App, err error) {
@@ -88,6 +122,89 @@ func (a *App) readConfig(envPath ...string) (err error) {
 	a.Port = os.Getenv("PORT")
 	a.JWTSecret = os.Getenv("JWT_SECRET")
 
+	// JWT_KEYS is a manifest of asymmetric signing keys, tokens signed with JWT_SECRET are
+	// still accepted alongside them until it is unset
+	if keys := os.Getenv("JWT_KEYS"); keys != "" {
+		if a.JWTKeys, err = au.LoadSigningKeys(keys); err != nil {
+			return e.Wrap("invalid JWT_KEYS", err)
+		}
+	}
+	if a.JWTSecret == "" && len(a.JWTKeys) == 0 {
+		return errors.New("JWT_SECRET or JWT_KEYS is required")
+	}
+	a.StorageDir = os.Getenv("STORAGE_DIR")
+
+	// mails go through SMTP if a server is set, otherwise they are saved to MAIL_DIR or logged
+	a.SMTPAddr = os.Getenv("SMTP_ADDR")
+	a.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	a.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	a.MailFrom = os.Getenv("MAIL_FROM")
+	a.MailDir = os.Getenv("MAIL_DIR")
+	a.MailLinkUrl = os.Getenv("MAIL_LINK_URL")
+	if a.MailLinkUrl == "" {
+		a.MailLinkUrl = fmt.Sprintf("http://%s:%s", os.Getenv("HOST"), os.Getenv("PORT"))
+	}
+
+	if a.OIDCProvider, a.OIDCDefaultRole, err = readOIDC(); err != nil {
+		return err
+	}
+
+	// ADMIN_USERNAME makes the first admin on start, see UserService.BootstrapAdmin; it
+	// can be unset again once there is an admin
+	a.AdminUsername = os.Getenv("ADMIN_USERNAME")
+	a.AdminPassword = os.Getenv("ADMIN_PASSWORD")
+	a.AdminEmail = os.Getenv("ADMIN_EMAIL")
+	if a.AdminUsername != "" && a.AdminPassword == "" {
+		return errors.New("ADMIN_PASSWORD is required with ADMIN_USERNAME")
+	}
+
+	var imageOptions []imaging.ProcessorOption
+	if maxBytes := os.Getenv("IMAGE_MAX_BYTES"); maxBytes != "" {
+		n, err := strconv.ParseInt(maxBytes, 10, 64)
+		if err != nil {
+			return e.Wrap("invalid IMAGE_MAX_BYTES", err)
+		}
+		imageOptions = append(imageOptions, imaging.WithMaxBytes(n))
+	}
+	if sizes := os.Getenv("IMAGE_THUMBNAIL_SIZES"); sizes != "" {
+		var thumbnailSizes []int
+		for _, size := range strings.Split(sizes, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(size))
+			if err != nil || n <= 0 {
+				return errors.New("invalid IMAGE_THUMBNAIL_SIZES")
+			}
+			thumbnailSizes = append(thumbnailSizes, n)
+		}
+		imageOptions = append(imageOptions, imaging.WithThumbnailSizes(thumbnailSizes...))
+	}
+	a.Images = imaging.NewProcessor(imageOptions...)
+
+	if a.PasswordPolicy, err = readPasswordPolicy(); err != nil {
+		return err
+	}
+	if a.PasswordHasher, err = readPasswordHasher(); err != nil {
+		return err
+	}
+
+	if problemDetails := os.Getenv("PROBLEM_DETAILS"); problemDetails != "" {
+		if a.ProblemDetails, err = strconv.ParseBool(problemDetails); err != nil {
+			return e.Wrap("invalid PROBLEM_DETAILS", err)
+		}
+	}
+
+	// the deprecated GET /user/login is served until turned off, the sunset announces when that happens
+	a.LegacyLogin = true
+	if legacyLogin := os.Getenv("LEGACY_LOGIN"); legacyLogin != "" {
+		if a.LegacyLogin, err = strconv.ParseBool(legacyLogin); err != nil {
+			return e.Wrap("invalid LEGACY_LOGIN", err)
+		}
+	}
+	if sunset := os.Getenv("LEGACY_LOGIN_SUNSET"); sunset != "" {
+		if a.LegacyLoginSunset, err = time.Parse(time.RFC3339, sunset); err != nil {
+			return e.Wrap("invalid LEGACY_LOGIN_SUNSET", err)
+		}
+	}
+
 	a.DSN = fmt.Sprintf( // database source name
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable timezone=UTC connect_timeout=5\n",
 		os.Getenv("POSTGRES_HOST"),
@@ -111,10 +228,43 @@ func (a *App) init() error {
 	}
 	a.DB = dbrepo.NewPostgresDBRepo(conn)
 
-	a.services = modules.NewServices(a.DB, a.Host, a.Host, a.JWTSecret, a.Host)
+	if a.Storage, err = filestore.NewFileStorage(a.StorageDir); err != nil {
+		return err
+	}
+
+	if a.Mailer, err = a.newMailer(); err != nil {
+		return err
+	}
+
+	serviceOptions := []modules.ServicesOption{
+		modules.WithSigningKeys(a.JWTKeys...),
+		modules.WithPasswordHasher(a.PasswordHasher),
+		modules.WithPasswordPolicy(a.PasswordPolicy),
+		modules.WithMailer(a.Mailer, a.MailLinkUrl),
+		modules.WithImageProcessor(a.Images),
+	}
+	if a.OIDCProvider != nil {
+		serviceOptions = append(serviceOptions, modules.WithExternalLogin(a.OIDCProvider, a.OIDCDefaultRole))
+	}
+
+	// tokens are issued by and for this host
+	tokens := modules.TokenConfig{Issuer: a.Host, Audience: a.Host, Secret: a.JWTSecret}
+	a.services = modules.NewServices(a.DB, a.Storage, fmt.Sprintf("http://%s:%s", a.Host, a.Port), tokens, serviceOptions...)
+
+	if a.AdminUsername != "" {
+		err = a.services.User.BootstrapAdmin(context.Background(), a.AdminUsername, a.AdminPassword, a.AdminEmail)
+		if err != nil {
+			return e.Wrap("couldn't create admin", err)
+		}
+	}
+
+	rrOptions := []rr.ReadRespondOption{rr.WithMaxBytes(1 << 10)}
+	if a.ProblemDetails {
+		rrOptions = append(rrOptions, rr.WithProblemDetails())
+	}
 	a.controllers = modules.NewControllers(
 		a.services,
-		rr.NewReadRespond(rr.WithMaxBytes(1<<10)),
+		rr.NewReadRespond(rrOptions...),
 	)
 
 	a.server = &http.Server{
@@ -130,6 +280,145 @@ func (a *App) init() error {
 	return nil
 }
 
+// readPasswordPolicy reads the password policy from the environment. PASSWORD_BREACHED_LIST
+// is a file of SHA-1 hashes replacing the bundled list, "none" turns the check off.
+func readPasswordPolicy() (*passwords.Policy, error) {
+	var options []passwords.PolicyOption
+
+	if minLength := os.Getenv("PASSWORD_MIN_LENGTH"); minLength != "" {
+		n, err := strconv.Atoi(minLength)
+		if err != nil || n < 1 {
+			return nil, errors.New("invalid PASSWORD_MIN_LENGTH")
+		}
+		options = append(options, passwords.WithLength(n, 72))
+	}
+
+	if minClasses := os.Getenv("PASSWORD_MIN_CLASSES"); minClasses != "" {
+		n, err := strconv.Atoi(minClasses)
+		if err != nil || n < 0 || n > 4 {
+			return nil, errors.New("invalid PASSWORD_MIN_CLASSES")
+		}
+		options = append(options, passwords.WithMinClasses(n))
+	}
+
+	switch path := os.Getenv("PASSWORD_BREACHED_LIST"); path {
+	case "":
+	case "none":
+		options = append(options, passwords.WithBreachedList(nil))
+	default:
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, e.Wrap("can't open PASSWORD_BREACHED_LIST", err)
+		}
+		defer file.Close()
+
+		list, err := passwords.LoadList(file)
+		if err != nil {
+			return nil, e.Wrap("invalid PASSWORD_BREACHED_LIST", err)
+		}
+		options = append(options, passwords.WithBreachedList(list))
+	}
+
+	return passwords.NewPolicy(options...), nil
+}
+
+// readPasswordHasher reads how new password hashes are made. Existing hashes made with
+// another algorithm or weaker parameters are upgraded on the next successful login.
+func readPasswordHasher() (*passwords.Hasher, error) {
+	params := passwords.DefaultParams
+
+	readUint := func(key string, bits int, value *uint64) error {
+		if s := os.Getenv(key); s != "" {
+			n, err := strconv.ParseUint(s, 10, bits)
+			if err != nil || n == 0 {
+				return errors.New("invalid " + key)
+			}
+			*value = n
+		}
+		return nil
+	}
+
+	memory, iterations, threads := uint64(params.Memory), uint64(params.Time), uint64(params.Threads)
+	if err := readUint("PASSWORD_ARGON2_MEMORY", 32, &memory); err != nil {
+		return nil, err
+	}
+	if err := readUint("PASSWORD_ARGON2_TIME", 32, &iterations); err != nil {
+		return nil, err
+	}
+	if err := readUint("PASSWORD_ARGON2_THREADS", 8, &threads); err != nil {
+		return nil, err
+	}
+
+	switch algorithm := os.Getenv("PASSWORD_HASH"); algorithm {
+	case "", passwords.Argon2id:
+		return passwords.NewHasher(passwords.WithArgon2id(uint32(memory), uint32(iterations), uint8(threads))), nil
+	case passwords.Bcrypt:
+		cost := params.Cost
+		if s := os.Getenv("PASSWORD_BCRYPT_COST"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < bcrypt.MinCost || n > bcrypt.MaxCost {
+				return nil, errors.New("invalid PASSWORD_BCRYPT_COST")
+			}
+			cost = n
+		}
+		return passwords.NewHasher(passwords.WithBcrypt(cost)), nil
+	default:
+		return nil, errors.New("invalid PASSWORD_HASH")
+	}
+}
+
+// readOIDC reads the OpenID provider users can log in with, external login is enabled by
+// setting OIDC_ISSUER. The redirect URL registered with the provider defaults to our callback.
+// Users created on their first login get OIDC_DEFAULT_ROLE, admins are only made by admins.
+func readOIDC() (*oidc.Provider, ae.Role, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, "", nil
+	}
+
+	clientId := os.Getenv("OIDC_CLIENT_ID")
+	if clientId == "" {
+		return nil, "", errors.New("OIDC_CLIENT_ID is required with OIDC_ISSUER")
+	}
+
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = fmt.Sprintf("http://%s:%s/user/login/oidc/callback", os.Getenv("HOST"), os.Getenv("PORT"))
+	}
+
+	role := ae.RoleCustomer
+	if s := os.Getenv("OIDC_DEFAULT_ROLE"); s != "" {
+		role = ae.Role(s)
+		if !role.Valid() || role == ae.RoleAdmin {
+			return nil, "", errors.New("invalid OIDC_DEFAULT_ROLE")
+		}
+	}
+
+	var options []oidc.ProviderOption
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		options = append(options, oidc.WithScopes(strings.Fields(scopes)...))
+	}
+
+	return oidc.NewProvider(issuer, clientId, os.Getenv("OIDC_CLIENT_SECRET"), redirectURL, options...), role, nil
+}
+
+func (a *App) newMailer() (mail.Mailer, error) {
+	if a.SMTPAddr == "" {
+		return filemail.NewFileMailer(a.MailDir)
+	}
+
+	if a.MailFrom == "" {
+		return nil, errors.New("MAIL_FROM is required with SMTP_ADDR")
+	}
+
+	var options []smtpmail.SMTPMailerOption
+	if a.SMTPUsername != "" {
+		options = append(options, smtpmail.WithAuth(a.SMTPUsername, a.SMTPPassword))
+	}
+
+	return smtpmail.NewSMTPMailer(a.SMTPAddr, a.MailFrom, options...), nil
+}
+
 func (a *App) connectToDB() (conn *sql.DB, err error) {
 	defer func() { err = e.WrapIfErr("failed to connect to database", err) }()
 
@@ -1,18 +1,135 @@
 package app
 
 import (
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 )
 
+// requireAuthentication accepts an API key in the X-API-Key header or an access token
+// in the Authorization header
 func (a *App) requireAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _, err := a.services.Auth.VerifyRequest(w, r)
-		if err != nil {
-			log.Println(err.Error())
+		w.Header().Add("Vary", "X-API-Key")
+
+		var principal ae.Principal
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			var err error
+			if principal, err = a.services.Auth.VerifyAPIKey(r.Context(), key); err != nil {
+				log.Println(err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else {
+			_, claims, err := a.services.Auth.VerifyRequest(w, r)
+			if err != nil {
+				log.Println(err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			principal = claims.Principal()
+		}
+
+		next.ServeHTTP(w, r.WithContext(as.NewContext(r.Context(), principal)))
+	})
+}
+
+// allowAuthentication authenticates requests carrying credentials like requireAuthentication
+// and lets anonymous ones through, for public routes that show more to some callers
+func (a *App) allowAuthentication(next http.Handler) http.Handler {
+	authenticated := a.requireAuthentication(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == "" && r.Header.Get("Authorization") == "" {
+			w.Header().Add("Vary", "Authorization")
+			w.Header().Add("Vary", "X-API-Key")
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// requireScope only lets through tokens granted the OAuth2 scope, users get the scopes
+// of their role (see ae.Role.Scopes) and machine clients the ones they requested.
+// Like every authorization middleware it has to run after requireAuthentication.
+func (a *App) requireScope(scope string) func(http.Handler) http.Handler {
+	return a.authorize(func(principal ae.Principal, _ *http.Request) bool {
+		return principal.HasScope(scope)
+	})
+}
+
+// requireUser rejects tokens issued to machine clients and API keys, for routes acting on
+// behalf of a logged in user; API keys are meant for the pet and inventory endpoints
+func (a *App) requireUser(next http.Handler) http.Handler {
+	return a.authorize(func(principal ae.Principal, _ *http.Request) bool {
+		return !principal.IsClient() && !principal.IsAPIKey()
+	})(next)
+}
+
+// requireTwoFactor only lets through users who have set up two-factor authentication, staff
+// can't change the pet catalog without it whatever they authenticate with. Machine clients
+// have no second factor and are left to requireScope.
+func (a *App) requireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := as.PrincipalFromContext(r.Context())
+		if !ok {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+
+		if !principal.IsClient() {
+			enabled, err := a.services.User.TwoFactorEnabled(r.Context(), principal.UserId)
+			if err != nil {
+				log.Println(err.Error())
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !enabled {
+				log.Printf("%s without two-factor authentication is not allowed to %s %s",
+					principal.Actor(), r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (a *App) authorize(allowed func(principal ae.Principal, r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := as.PrincipalFromContext(r.Context())
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !allowed(principal, r) {
+				log.Printf("%s with roles %v and scopes %v is not allowed to %s %s",
+					principal.Actor(), principal.Roles, principal.Scopes, r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deprecated announces that a route is going away (RFC 9745 and RFC 8594) and points
+// clients at its successor; a zero sunset means the removal date isn't set yet
+func (a *App) deprecated(since, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package app
+
+import (
+	"backend/internal/lib/e"
+	mock_service "backend/internal/mocks/mock_auth_service"
+	mock_user_service "backend/internal/mocks/mock_user_service"
+	"backend/internal/modules"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApp_authorize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		claims     *ae.Claims
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"customer reads pets", claims("johndoe001", ae.RoleCustomer), http.MethodGet, "/pet", http.StatusOK},
+		{"staff writes pets", claims("jenstar", ae.RoleStaff), http.MethodPost, "/pet", http.StatusOK},
+		{"admin writes pets", claims("wanomir", ae.RoleAdmin), http.MethodPost, "/pet", http.StatusOK},
+		{"customer writes pets", claims("johndoe001", ae.RoleCustomer), http.MethodPost, "/pet", http.StatusForbidden},
+		{"unknown role", claims("johndoe001", "owner"), http.MethodGet, "/pet", http.StatusForbidden},
+		{"not authenticated", nil, http.MethodPost, "/pet", http.StatusUnauthorized},
+		{"client reads pets", clientClaims("inventory-sync", ae.ScopeReadPets), http.MethodGet, "/pet", http.StatusOK},
+		{"client writes pets", clientClaims("inventory-sync", ae.ScopeWritePets), http.MethodPost, "/pet", http.StatusOK},
+		{"client without scope", clientClaims("inventory-sync", ae.ScopeReadPets), http.MethodPost, "/pet", http.StatusForbidden},
+		{"user lists sessions", claims("johndoe001", ae.RoleCustomer), http.MethodGet, "/user/sessions", http.StatusOK},
+		{"client lists sessions", clientClaims("johndoe001", ae.ScopeReadPets), http.MethodGet, "/user/sessions", http.StatusForbidden},
+	}
+
+	apiKeyCases := []struct {
+		name       string
+		principal  ae.Principal
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"API key reads pets", apiKeyPrincipal("johndoe001", ae.ScopeReadPets), http.MethodGet, "/pet", http.StatusOK},
+		{"API key without scope", apiKeyPrincipal("johndoe001", ae.ScopeReadPets), http.MethodPost, "/pet", http.StatusForbidden},
+		{"API key lists sessions", apiKeyPrincipal("johndoe001", ae.ScopeReadPets), http.MethodGet, "/user/sessions", http.StatusForbidden},
+	}
+
+	a := &App{}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.With(a.requireScope(ae.ScopeReadPets)).Get("/pet", ok)
+	r.With(a.requireScope(ae.ScopeWritePets)).Post("/pet", ok)
+	r.With(a.requireUser).Get("/user/sessions", ok)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.claims != nil {
+				req = req.WithContext(as.NewContext(req.Context(), tc.claims.Principal()))
+			}
+			wr := httptest.NewRecorder()
+
+			r.ServeHTTP(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+
+	for _, tc := range apiKeyCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req = req.WithContext(as.NewContext(req.Context(), tc.principal))
+			wr := httptest.NewRecorder()
+
+			r.ServeHTTP(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestApp_requireTwoFactor(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	// only the user with id 1 has two-factor authentication
+	mockUser := mock_user_service.NewMockUserServicer(controller)
+	mockUser.EXPECT().TwoFactorEnabled(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int) (bool, error) {
+		return userId == 1, nil
+	}).AnyTimes()
+
+	a := &App{services: &modules.Services{User: mockUser}}
+	handler := a.requireScope(ae.ScopeWritePets)(a.requireTwoFactor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	withId := func(claims *ae.Claims, userId int) ae.Principal {
+		claims.UserId = userId
+		return claims.Principal()
+	}
+	apiKey := apiKeyPrincipal("jenstar", ae.ScopeWritePets)
+	apiKey.UserId = 2
+
+	testCases := []struct {
+		name       string
+		principal  ae.Principal
+		wantStatus int
+	}{
+		{"staff with two-factor authentication", withId(claims("jenstar", ae.RoleStaff), 1), http.StatusOK},
+		{"staff without two-factor authentication", withId(claims("jenstar", ae.RoleStaff), 2), http.StatusForbidden},
+		{"admin without two-factor authentication", withId(claims("wanomir", ae.RoleAdmin), 2), http.StatusForbidden},
+		{"API key of staff without two-factor authentication", apiKey, http.StatusForbidden},
+		{"client", clientClaims("inventory-sync", ae.ScopeWritePets).Principal(), http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/pet", nil)
+			req = req.WithContext(as.NewContext(req.Context(), tc.principal))
+			wr := httptest.NewRecorder()
+
+			handler.ServeHTTP(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestApp_requireAuthentication(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAuth := mock_service.NewMockAuthServicer(controller)
+	mockAuth.EXPECT().VerifyAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key string) (ae.Principal, error) {
+		if key != "psk_valid" {
+			return ae.Principal{}, e.Unauthorized("invalid API key")
+		}
+		return apiKeyPrincipal("johndoe001", ae.ScopeReadPets), nil
+	}).AnyTimes()
+	mockAuth.EXPECT().VerifyRequest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ http.ResponseWriter, r *http.Request) (string, *ae.Claims, error) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			return "", nil, e.Unauthorized("invalid token")
+		}
+		return "valid", claims("johndoe001", ae.RoleCustomer), nil
+	}).AnyTimes()
+
+	a := &App{services: &modules.Services{Auth: mockAuth}}
+	handler := a.requireAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := as.PrincipalFromContext(r.Context())
+		w.Header().Set("X-Caller", fmt.Sprintf("%s api-key=%v", principal.Username, principal.IsAPIKey()))
+	}))
+
+	testCases := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantCaller string
+	}{
+		{"API key", map[string]string{"X-API-Key": "psk_valid"}, http.StatusOK, "johndoe001 api-key=true"},
+		{"access token", map[string]string{"Authorization": "Bearer valid"}, http.StatusOK, "johndoe001 api-key=false"},
+		{"invalid API key", map[string]string{"X-API-Key": "psk_invalid"}, http.StatusUnauthorized, ""},
+		// a wrong key isn't made up for by a valid token
+		{"invalid API key with token", map[string]string{"X-API-Key": "psk_invalid", "Authorization": "Bearer valid"}, http.StatusUnauthorized, ""},
+		{"neither", nil, http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/pet", nil)
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+			wr := httptest.NewRecorder()
+
+			handler.ServeHTTP(wr, req)
+
+			if wr.Code != tc.wantStatus || wr.Header().Get("X-Caller") != tc.wantCaller {
+				t.Errorf("want status %d and caller %q, got %d and %q", tc.wantStatus, tc.wantCaller, wr.Code, wr.Header().Get("X-Caller"))
+			}
+		})
+	}
+}
+
+func TestApp_allowAuthentication(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAuth := mock_service.NewMockAuthServicer(controller)
+	mockAuth.EXPECT().VerifyRequest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ http.ResponseWriter, r *http.Request) (string, *ae.Claims, error) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			return "", nil, e.Unauthorized("invalid token")
+		}
+		return "valid", claims("johndoe001", ae.RoleCustomer), nil
+	}).AnyTimes()
+
+	a := &App{services: &modules.Services{Auth: mockAuth}}
+	handler := a.allowAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := as.PrincipalFromContext(r.Context())
+		w.Header().Set("X-Caller", fmt.Sprintf("%s authenticated=%v", principal.Username, ok))
+	}))
+
+	testCases := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantCaller string
+	}{
+		{"access token", map[string]string{"Authorization": "Bearer valid"}, http.StatusOK, "johndoe001 authenticated=true"},
+		{"anonymous", nil, http.StatusOK, " authenticated=false"},
+		// invalid credentials are rejected rather than ignored
+		{"invalid token", map[string]string{"Authorization": "Bearer invalid"}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user/johndoe001", nil)
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+			wr := httptest.NewRecorder()
+
+			handler.ServeHTTP(wr, req)
+
+			if wr.Code != tc.wantStatus || wr.Header().Get("X-Caller") != tc.wantCaller {
+				t.Errorf("want status %d and caller %q, got %d and %q", tc.wantStatus, tc.wantCaller, wr.Code, wr.Header().Get("X-Caller"))
+			}
+		})
+	}
+}
+
+func TestApp_deprecated(t *testing.T) {
+	since := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	a := &App{}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.With(a.deprecated(since, sunset, "/user/login")).Get("/user/login", ok)
+	r.With(a.deprecated(since, time.Time{}, "/user/login")).Get("/user/logout", ok)
+
+	t.Run("with sunset", func(t *testing.T) {
+		wr := httptest.NewRecorder()
+		r.ServeHTTP(wr, httptest.NewRequest(http.MethodGet, "/user/login", nil))
+
+		want := map[string]string{
+			"Deprecation": "@1792281600",
+			"Sunset":      "Thu, 01 Apr 2027 00:00:00 GMT",
+			"Link":        `</user/login>; rel="successor-version"`,
+		}
+		for header, value := range want {
+			if got := wr.Header().Get(header); got != value {
+				t.Errorf("%s = %q, want %q", header, got, value)
+			}
+		}
+	})
+
+	t.Run("without sunset", func(t *testing.T) {
+		wr := httptest.NewRecorder()
+		r.ServeHTTP(wr, httptest.NewRequest(http.MethodGet, "/user/logout", nil))
+
+		if wr.Header().Get("Deprecation") == "" || wr.Header().Get("Sunset") != "" {
+			t.Errorf("Deprecation = %q, Sunset = %q", wr.Header().Get("Deprecation"), wr.Header().Get("Sunset"))
+		}
+	})
+}
+
+func claims(username string, role ae.Role) *ae.Claims {
+	return &ae.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: username},
+		Role:             role,
+		Scope:            strings.Join(role.Scopes(), " "),
+	}
+}
+
+func clientClaims(clientId string, scopes ...string) *ae.Claims {
+	return &ae.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: clientId},
+		Scope:            strings.Join(scopes, " "),
+		ClientId:         clientId,
+	}
+}
+
+func apiKeyPrincipal(username string, scopes ...string) ae.Principal {
+	return ae.Principal{Username: username, Roles: []ae.Role{ae.RoleCustomer}, Scopes: scopes, APIKeyId: 1}
+}
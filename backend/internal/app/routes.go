@@ -1,26 +1,44 @@
 package app
 
 import (
+	ae "backend/internal/modules/auth/entities"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"time"
 )
 
+// legacyLoginDeprecation is when GET /user/login, taking credentials in the query string,
+// was deprecated in favour of POST /user/login
+var legacyLoginDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
 func (a *App) routes() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Recoverer)
 
 	r.Route("/pet", func(r chi.Router) {
-		r.Use(a.requireAuthentication)
-		r.Get("/{petId}", a.controllers.Pet.GetById)
-		r.Post("/{petId}", a.controllers.Pet.UpdateWithForm)
-		r.Delete("/{petId}", a.controllers.Pet.Delete)
-		r.Post("/{petId}/uploadImage", a.controllers.Pet.UploadImage)
-		r.Post("/", a.controllers.Pet.Create)
-		r.Put("/", a.controllers.Pet.Update)
-		r.Get("/findByStatus", a.controllers.Pet.GetByStatus)
+		r.Group(func(r chi.Router) {
+			r.Use(a.requireAuthentication)
+			r.Group(func(r chi.Router) {
+				r.Use(a.requireScope(ae.ScopeReadPets))
+				r.Get("/{petId}", a.controllers.Pet.GetById)
+				r.Get("/", a.controllers.Pet.List)
+				r.Get("/findByStatus", a.controllers.Pet.GetByStatus)
+				r.Get("/findByTags", a.controllers.Pet.GetByTags)
+			})
+			r.Group(func(r chi.Router) {
+				// staff need two-factor authentication to change the catalog
+				r.Use(a.requireScope(ae.ScopeWritePets), a.requireTwoFactor)
+				r.Post("/{petId}", a.controllers.Pet.UpdateWithForm)
+				r.Delete("/{petId}", a.controllers.Pet.Delete)
+				r.Post("/{petId}/uploadImage", a.controllers.Pet.UploadImage)
+				r.Post("/", a.controllers.Pet.Create)
+				r.Put("/", a.controllers.Pet.Update)
+			})
+		})
+		r.Get("/{petId}/images/{imageName}", a.controllers.Pet.GetImage)
 	})
 
 	r.Route("/store", func(r chi.Router) {
@@ -28,22 +46,56 @@ func (a *App) routes() *chi.Mux {
 			r.Use(a.requireAuthentication)
 			r.Get("/inventory", a.controllers.Store.GetInventory)
 		})
-		r.Post("/order", a.controllers.Store.CreateOrder)
-		r.Get("/order/{orderId}", a.controllers.Store.GetOrderById)
-		r.Delete("/order/{orderId}", a.controllers.Store.DeleteOrder)
-
+		r.Group(func(r chi.Router) {
+			// the store service checks that customers only see their own orders, unless staff
+			r.Use(a.requireAuthentication, a.requireUser)
+			r.Post("/order", a.controllers.Store.CreateOrder)
+			r.Get("/order/{orderId}", a.controllers.Store.GetOrderById)
+			r.Delete("/order/{orderId}", a.controllers.Store.DeleteOrder)
+		})
 	})
 
 	r.Route("/user", func(r chi.Router) {
-		r.Get("/{username}", a.controllers.User.GetByUsername)
-		r.Put("/{username}", a.controllers.User.Update)
-		r.Delete("/{username}", a.controllers.User.Delete)
+		// anyone sees the profile, the user and admins its private fields as well
+		r.With(a.allowAuthentication).Get("/{username}", a.controllers.User.GetByUsername)
+		r.Group(func(r chi.Router) {
+			// the user service checks that callers only manage their own account, unless admins
+			r.Use(a.requireAuthentication, a.requireUser)
+			r.Put("/{username}", a.controllers.User.Update)
+			r.Delete("/{username}", a.controllers.User.Delete)
+			r.Delete("/{username}/lockout", a.controllers.User.Unlock)
+			r.Post("/{username}/2fa", a.controllers.User.EnrollTwoFactor)
+			r.Post("/{username}/2fa/verify", a.controllers.User.ConfirmTwoFactor)
+			r.Delete("/{username}/2fa", a.controllers.User.DisableTwoFactor)
+			r.Post("/{username}/apikeys", a.controllers.User.CreateAPIKey)
+			r.Get("/{username}/apikeys", a.controllers.User.APIKeys)
+			r.Delete("/{username}/apikeys/{keyId}", a.controllers.User.RevokeAPIKey)
+		})
 		r.Post("/", a.controllers.User.Create)
 		r.Post("/createWithArray", a.controllers.User.CreateWithArray)
-		r.Get("/login", a.controllers.User.Login)
+		r.Post("/verify", a.controllers.User.VerifyEmail)
+		r.Post("/password/forgot", a.controllers.User.ForgotPassword)
+		r.Post("/password/reset", a.controllers.User.ResetPassword)
+		r.Post("/login", a.controllers.User.Login)
+		r.Post("/login/2fa", a.controllers.User.LoginTwoFactor)
+		r.Get("/login/oidc", a.controllers.User.LoginExternal)
+		r.Get("/login/oidc/callback", a.controllers.User.LoginExternalCallback)
+		if a.LegacyLogin {
+			r.With(a.deprecated(legacyLoginDeprecation, a.LegacyLoginSunset, "/user/login")).
+				Get("/login", a.controllers.User.LegacyLogin)
+		}
+		r.Post("/refresh", a.controllers.User.Refresh)
 		r.Get("/logout", a.controllers.User.Logout)
+		r.Group(func(r chi.Router) {
+			r.Use(a.requireAuthentication, a.requireUser)
+			r.Get("/sessions", a.controllers.User.Sessions)
+			r.Delete("/sessions", a.controllers.User.LogoutAll)
+		})
 	})
 
+	r.Post("/oauth/token", a.controllers.Auth.Token)
+	r.Get("/.well-known/jwks.json", a.controllers.Auth.JWKS)
+
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL(fmt.Sprintf("http://%s:%s/swagger/doc.json", a.Host, a.Port)),
 	))
@@ -0,0 +1,109 @@
+package e
+
+import (
+	"errors"
+	"time"
+)
+
+// Error kinds shared by repositories, services and controllers. Use errors.Is to check
+// the kind of an error, it survives any number of Wrap calls.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("too many requests")
+	ErrInternal     = errors.New("internal error")
+)
+
+// Violation describes a single invalid field of a request
+type Violation struct {
+	Field   string
+	Message string
+}
+
+// Error is an error of a specific kind with a message safe to show to the client
+// and an optional underlying cause, which is only shown for client errors; the cause of
+// an internal error is logged instead. Validation errors may list the offending fields,
+// rate limit errors tell when the client may try again.
+type Error struct {
+	Kind       error
+	Msg        string
+	Err        error
+	Violations []Violation
+	RetryAfter time.Duration
+}
+
+func (err *Error) Error() string {
+	if err.Err != nil {
+		return err.Msg + ": " + err.Err.Error()
+	}
+	return err.Msg
+}
+
+func (err *Error) Unwrap() []error {
+	if err.Err != nil {
+		return []error{err.Kind, err.Err}
+	}
+	return []error{err.Kind}
+}
+
+func NotFound(msg string) error {
+	return &Error{Kind: ErrNotFound, Msg: msg}
+}
+
+func Conflict(msg string) error {
+	return &Error{Kind: ErrConflict, Msg: msg}
+}
+
+func Validation(msg string, violations ...Violation) error {
+	return &Error{Kind: ErrValidation, Msg: msg, Violations: violations}
+}
+
+func Unauthorized(msg string) error {
+	return &Error{Kind: ErrUnauthorized, Msg: msg}
+}
+
+// Forbidden means the client is authenticated but not allowed to do what it asked for
+func Forbidden(msg string) error {
+	return &Error{Kind: ErrForbidden, Msg: msg}
+}
+
+// RateLimited means the client has to wait retryAfter before trying again
+func RateLimited(msg string, retryAfter time.Duration) error {
+	return &Error{Kind: ErrRateLimited, Msg: msg, RetryAfter: retryAfter}
+}
+
+// Internal marks err as a failure of the server itself, e.g. a lost database connection
+func Internal(msg string, err error) error {
+	return &Error{Kind: ErrInternal, Msg: msg, Err: err}
+}
+
+// ViolationsOf returns the field violations carried by err, if any
+func ViolationsOf(err error) []Violation {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Violations
+	}
+	return nil
+}
+
+// RetryAfterOf returns how long the client has to wait before retrying, zero if err doesn't say
+func RetryAfterOf(err error) time.Duration {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.RetryAfter
+	}
+	return 0
+}
+
+// KindOf returns the kind of err or nil if err carries none
+func KindOf(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrUnauthorized, ErrForbidden, ErrRateLimited, ErrInternal} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register gif decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+var (
+	ErrNotImage = errors.New("file is not a supported image")
+	ErrTooLarge = errors.New("image is too large")
+)
+
+const (
+	defaultMaxBytes    = 10 << 20
+	defaultMaxPixels   = 40_000_000
+	defaultJPEGQuality = 90
+)
+
+// Variant is a thumbnail size, the image is scaled down to fit a Size x Size box.
+type Variant struct {
+	Name string
+	Size int
+}
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+type Thumbnail struct {
+	Variant
+	Image
+}
+
+type Result struct {
+	Original   Image
+	Thumbnails []Thumbnail
+}
+
+// Processor validates uploaded images, strips their metadata and renders thumbnails.
+type Processor struct {
+	maxBytes    int64
+	maxPixels   int
+	jpegQuality int
+	variants    []Variant
+}
+
+type ProcessorOption func(*Processor)
+
+func WithMaxBytes(maxBytes int64) ProcessorOption {
+	return func(p *Processor) {
+		p.maxBytes = maxBytes
+	}
+}
+
+func WithMaxPixels(maxPixels int) ProcessorOption {
+	return func(p *Processor) {
+		p.maxPixels = maxPixels
+	}
+}
+
+// WithThumbnailSizes replaces the default thumbnail variants, each one is named after its size.
+func WithThumbnailSizes(sizes ...int) ProcessorOption {
+	return func(p *Processor) {
+		p.variants = make([]Variant, 0, len(sizes))
+		for _, size := range sizes {
+			p.variants = append(p.variants, Variant{Name: strconv.Itoa(size), Size: size})
+		}
+	}
+}
+
+func NewProcessor(options ...ProcessorOption) *Processor {
+	p := &Processor{
+		maxBytes:    defaultMaxBytes,
+		maxPixels:   defaultMaxPixels,
+		jpegQuality: defaultJPEGQuality,
+		variants:    []Variant{{Name: "128", Size: 128}, {Name: "512", Size: 512}},
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+func (p *Processor) MaxBytes() int64 {
+	return p.maxBytes
+}
+
+func (p *Processor) Process(r io.Reader) (Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, p.maxBytes+1))
+	if err != nil {
+		return Result{}, err
+	}
+
+	if int64(len(data)) > p.maxBytes {
+		return Result{}, fmt.Errorf("%w: file exceeds %d bytes", ErrTooLarge, p.maxBytes)
+	}
+
+	// trust the content itself rather than the file name or the client supplied type
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return Result{}, fmt.Errorf("%w: detected %s", ErrNotImage, contentType)
+	}
+
+	// check dimensions before decoding to avoid allocating huge images
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	if config.Width*config.Height > p.maxPixels {
+		return Result{}, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, config.Width, config.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	img := toRGBA(decoded)
+
+	var result Result
+
+	switch contentType {
+	case "image/jpeg":
+		// apply the exif orientation before it gets stripped along with the rest of the metadata
+		img = orient(img, exifOrientation(data))
+		if result.Original, err = p.encode(img, contentType); err != nil {
+			return Result{}, err
+		}
+	case "image/png":
+		if result.Original, err = p.encode(img, contentType); err != nil {
+			return Result{}, err
+		}
+	case "image/gif":
+		// gif carries no exif, keep the original bytes to preserve animation
+		result.Original = Image{Data: data, ContentType: contentType, Ext: ".gif", Width: config.Width, Height: config.Height}
+		contentType = "image/png"
+	}
+
+	for _, variant := range p.variants {
+		thumbnail, err := p.encode(fit(img, variant.Size), contentType)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Thumbnails = append(result.Thumbnails, Thumbnail{Variant: variant, Image: thumbnail})
+	}
+
+	return result, nil
+}
+
+func (p *Processor) encode(img *image.RGBA, contentType string) (Image, error) {
+	buf := new(bytes.Buffer)
+	result := Image{ContentType: contentType, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		result.Ext = ".jpg"
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: p.jpegQuality})
+	default:
+		result.ContentType, result.Ext = "image/png", ".png"
+		err = png.Encode(buf, img)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	result.Data = buf.Bytes()
+
+	return result, nil
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// fit scales the image down with an area average so that it fits into a size x size box
+func fit(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, sh*size/sw)
+	} else {
+		dw = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, max((dy+1)*sh/dh, dy*sh/dh+1)
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, max((dx+1)*sw/dw, dx*sw/dw+1)
+
+			var r, g, b, a, n int
+			for y := y0; y < y1; y++ {
+				i := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			j := dst.PixOffset(dx, dy)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
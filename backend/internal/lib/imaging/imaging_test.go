@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestProcessor_Process(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       []byte
+		wantErr    error
+		wantType   string
+		wantWidth  int
+		wantHeight int
+	}{
+		{"png", encodePNG(t, 300, 200), nil, "image/png", 300, 200},
+		{"jpeg", encodeJPEG(t, 300, 200, 1), nil, "image/jpeg", 300, 200},
+		{"jpeg rotated by exif", encodeJPEG(t, 300, 200, 6), nil, "image/jpeg", 200, 300},
+		{"not an image", []byte("<html></html>"), ErrNotImage, "", 0, 0},
+		{"too many bytes", make([]byte, 1<<20+1), ErrTooLarge, "", 0, 0},
+		{"too many pixels", encodePNG(t, 1100, 1000), ErrTooLarge, "", 0, 0},
+	}
+
+	p := NewProcessor(WithMaxBytes(1<<20), WithMaxPixels(1_000_000), WithThumbnailSizes(64, 500))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := p.Process(bytes.NewReader(tc.data))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Process() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			original := result.Original
+			if original.ContentType != tc.wantType || original.Width != tc.wantWidth || original.Height != tc.wantHeight {
+				t.Errorf("Process() original = %s %dx%d, want %s %dx%d",
+					original.ContentType, original.Width, original.Height, tc.wantType, tc.wantWidth, tc.wantHeight)
+			}
+
+			if bytes.Contains(original.Data, []byte("Exif")) {
+				t.Errorf("Process() original still contains exif metadata")
+			}
+
+			if len(result.Thumbnails) != 2 {
+				t.Fatalf("Process() thumbnails = %d, want 2", len(result.Thumbnails))
+			}
+
+			small, large := result.Thumbnails[0], result.Thumbnails[1]
+			if max(small.Width, small.Height) != 64 {
+				t.Errorf("Process() thumbnail %s is %dx%d", small.Name, small.Width, small.Height)
+			}
+			// images are never scaled up
+			if large.Width != tc.wantWidth || large.Height != tc.wantHeight {
+				t.Errorf("Process() thumbnail %s is %dx%d", large.Name, large.Width, large.Height)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1 image: red, blue
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(src.Pix, []uint8{255, 0, 0, 255, 0, 0, 255, 255})
+
+	testCases := []struct {
+		orientation int
+		wantRed     image.Point
+	}{
+		{1, image.Pt(0, 0)},
+		{2, image.Pt(1, 0)},
+		{3, image.Pt(1, 0)},
+		{6, image.Pt(0, 0)},
+		{8, image.Pt(0, 1)},
+	}
+
+	for _, tc := range testCases {
+		dst := orient(src, tc.orientation)
+		if r, _, _, _ := dst.At(tc.wantRed.X, tc.wantRed.Y).RGBA(); r != 0xffff {
+			t.Errorf("orient(%d) red pixel is not at %v", tc.orientation, tc.wantRed)
+		}
+	}
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG creates a jpeg with an exif segment holding the given orientation
+func encodeJPEG(t *testing.T, width, height, orientation int) []byte {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian header, first ifd at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, // orientation, short, count 1
+		0, 0, 0, 0, // no next ifd
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1, byte((len(segment) + 2) >> 8), byte(len(segment) + 2)}, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
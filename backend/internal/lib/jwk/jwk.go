@@ -0,0 +1,184 @@
+// Package jwk converts between public keys and JSON Web Keys (RFC 7517), so tokens
+// signed with our keys can be verified by others and tokens signed by others by us.
+// RSA, ECDSA on the NIST curves and Ed25519 keys are supported.
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported key type")
+
+// Key is a public JSON Web Key, fields of other key types are left empty
+type Key struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // EC and OKP curve
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// Set is a JWK Set as served at a jwks_uri
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Algorithm returns the JWS algorithm tokens are signed with using the private part of key:
+// RS256 for RSA, ES256, ES384 or ES512 depending on the curve, and EdDSA for Ed25519
+func Algorithm(key crypto.PublicKey) (string, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	}
+	return "", ErrUnsupportedKey
+}
+
+// FromPublicKey returns the JWK of a signing key
+func FromPublicKey(kid string, key crypto.PublicKey) (Key, error) {
+	alg, err := Algorithm(key)
+	if err != nil {
+		return Key{}, err
+	}
+
+	jwk := Key{KeyId: kid, Use: "sig", Algorithm: alg}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encoding.EncodeToString(key.N.Bytes())
+		jwk.E = encoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// coordinates are padded to the size of the curve, RFC 7518 section 6.2.1.2
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = encoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encoding.EncodeToString(key)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey returns the key k describes
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := encoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := encoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		x, errX := encoding.DecodeString(k.X)
+		y, errY := encoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := encoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// Find returns the key with kid
+func (s Set) Find(kid string) (Key, bool) {
+	for _, key := range s.Keys {
+		if key.KeyId == kid {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// ParsePrivateKey reads a PEM encoded private key in the PKCS #8, PKCS #1 (RSA) or
+// SEC 1 (EC) format, as written by openssl
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	if _, err = Algorithm(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,124 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+)
+
+func TestFromPublicKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	testCases := []struct {
+		name    string
+		key     crypto.Signer
+		wantKty string
+		wantAlg string
+	}{
+		{"rsa", rsaKey, "RSA", "RS256"},
+		{"ecdsa", ecKey, "EC", "ES256"},
+		{"ed25519", edKey, "OKP", "EdDSA"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jwk, err := FromPublicKey("key-1", tc.key.Public())
+			if err != nil {
+				t.Fatalf("FromPublicKey() error = %v", err)
+			}
+			if jwk.KeyType != tc.wantKty || jwk.Algorithm != tc.wantAlg || jwk.KeyId != "key-1" || jwk.Use != "sig" {
+				t.Errorf("FromPublicKey() = %+v, want kty %s and alg %s", jwk, tc.wantKty, tc.wantAlg)
+			}
+
+			// keys survive the round trip through JSON
+			data, _ := json.Marshal(Set{Keys: []Key{jwk}})
+			var set Set
+			if err = json.Unmarshal(data, &set); err != nil {
+				t.Fatal(err)
+			}
+			found, ok := set.Find("key-1")
+			if !ok {
+				t.Fatalf("Find() didn't find the key in %s", data)
+			}
+			public, err := found.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey() error = %v", err)
+			}
+			if !public.(interface{ Equal(crypto.PublicKey) bool }).Equal(tc.key.Public()) {
+				t.Errorf("PublicKey() = %v, want %v", public, tc.key.Public())
+			}
+		})
+	}
+}
+
+func TestKey_PublicKey(t *testing.T) {
+	testCases := []struct {
+		name string
+		key  Key
+	}{
+		{"unknown type", Key{KeyType: "oct"}},
+		{"unknown curve", Key{KeyType: "EC", Curve: "secp256k1"}},
+		{"point off the curve", Key{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"}},
+		{"short Ed25519 key", Key{KeyType: "OKP", Curve: "Ed25519", X: "AQ"}},
+		{"missing RSA exponent", Key{KeyType: "RSA", N: "AQ"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.key.PublicKey(); err == nil {
+				t.Errorf("PublicKey() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+
+	testCases := []struct {
+		name    string
+		block   *pem.Block
+		wantAlg string
+	}{
+		{"pkcs8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, "EdDSA"},
+		{"pkcs1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, "RS256"},
+		{"sec1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, "ES384"},
+		{"certificate", &pem.Block{Type: "CERTIFICATE", Bytes: sec1}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := ParsePrivateKey(pem.EncodeToMemory(tc.block))
+			if tc.wantAlg == "" {
+				if err == nil {
+					t.Errorf("ParsePrivateKey() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePrivateKey() error = %v", err)
+			}
+			if alg, _ := Algorithm(signer.Public()); alg != tc.wantAlg {
+				t.Errorf("Algorithm() = %s, want %s", alg, tc.wantAlg)
+			}
+		})
+	}
+
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("ParsePrivateKey() error = nil for garbage, want an error")
+	}
+}
@@ -0,0 +1,361 @@
+// Package oidc implements the relying party side of OpenID Connect (OpenID Connect Core 1.0):
+// the authorization code flow with PKCE (RFC 7636), provider discovery and the validation
+// of ID tokens against the keys the provider publishes.
+package oidc
+
+import (
+	"backend/internal/lib/jwk"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxResponseSize limits the documents read from the provider
+	maxResponseSize = 1 << 20
+	// keysRefreshInterval is how often the keys are refetched for a kid that isn't known,
+	// so tokens with made up kids can't make us hammer the provider
+	keysRefreshInterval = time.Minute
+	// keysMaxAge is how long fetched keys are used before they are refetched anyway
+	keysMaxAge = time.Hour
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid ID token")
+	// ErrRejected is returned when the provider refuses to exchange the code, e.g. because
+	// it was already used or the PKCE verifier doesn't match
+	ErrRejected = errors.New("code exchange rejected")
+)
+
+// signingMethods are the algorithms ID tokens are accepted with; "none" and the HMAC
+// algorithms, which would use the client secret as key, are not among them
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Identity is the user the provider authenticated, taken from the claims of the ID token
+type Identity struct {
+	Issuer            string
+	Subject           string // unique and never reassigned within the issuer
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+}
+
+// Discovery is the part of the provider metadata (OpenID Connect Discovery 1.0) the flow needs
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider is an OpenID provider our users log in with. The metadata is fetched from the
+// discovery document on first use and kept, the keys are refetched when they rotate.
+type Provider struct {
+	Issuer       string
+	ClientId     string
+	clientSecret string
+	RedirectURL  string
+	Scopes       []string
+	client       *http.Client
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	keys          jwk.Set
+	keysFetchedAt time.Time
+}
+
+type ProviderOption func(*Provider)
+
+// WithHTTPClient sets the client the provider is called with, by default one with a 10 second timeout
+func WithHTTPClient(client *http.Client) ProviderOption {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithScopes sets the scopes requested in addition to openid, by default email and profile
+func WithScopes(scopes ...string) ProviderOption {
+	return func(p *Provider) {
+		p.Scopes = append([]string{"openid"}, slices.DeleteFunc(scopes, func(scope string) bool { return scope == "openid" })...)
+	}
+}
+
+// NewProvider returns the provider at issuer, redirectURL is the address of our callback
+// registered with the provider for the client
+func NewProvider(issuer, clientId, clientSecret, redirectURL string, options ...ProviderOption) *Provider {
+	p := &Provider{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientId:     clientId,
+		clientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// AuthCodeURL returns the address the user is sent to to log in at the provider. state and
+// nonce are random values bound to the browser of the user, codeChallenge is derived from
+// the PKCE verifier, see NewVerifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientId},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code the provider redirected the user back with and
+// returns the identity in the ID token it was exchanged for
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (Identity, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic, the credentials are form encoded first, RFC 6749 section 2.3.1
+	req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.clientSecret))
+
+	var tokens struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = p.do(req, &tokens); err != nil && tokens.Error == "" {
+		return Identity{}, fmt.Errorf("token request failed: %w", err)
+	}
+	if tokens.Error != "" {
+		return Identity{}, fmt.Errorf("%w: %s %s", ErrRejected, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IdToken == "" {
+		return Identity{}, errors.New("token response has no ID token")
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IdToken, nonce)
+}
+
+// idTokenClaims are the claims of an ID token, OpenID Connect Core 1.0 section 2
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // some providers send "true"
+	Name              string `json:"name"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// VerifyIDToken validates the ID token as OpenID Connect Core 1.0 section 3.1.3.7 requires:
+// the signature against the keys of the provider, the issuer, the audience, the expiry and
+// the nonce the flow was started with
+func (p *Provider) VerifyIDToken(ctx context.Context, token, nonce string) (Identity, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	claims := new(idTokenClaims)
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, discovery, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	// tokens issued to several clients must name us as the party they were issued to
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientId {
+		return Identity{}, fmt.Errorf("%w: issued to another party", ErrInvalidToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+
+	return Identity{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     verified && claims.Email != "",
+		Name:              claims.Name,
+		GivenName:         claims.GivenName,
+		FamilyName:        claims.FamilyName,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// Discover returns the metadata of the provider, fetched on first use. The issuer in the
+// document has to be the one configured, OpenID Connect Discovery 1.0 section 4.3.
+func (p *Provider) Discover(ctx context.Context) (Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return *p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	var discovery Discovery
+	if err = p.do(req, &discovery); err != nil {
+		return Discovery{}, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	if discovery.Issuer != p.Issuer {
+		return Discovery{}, fmt.Errorf("discovery failed: issuer %q doesn't match %q", discovery.Issuer, p.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return Discovery{}, errors.New("discovery failed: endpoints are missing")
+	}
+	// providers that don't list the methods may still support PKCE, those that list them must include S256
+	if len(discovery.CodeChallengeMethods) > 0 && !slices.Contains(discovery.CodeChallengeMethods, "S256") {
+		return Discovery{}, errors.New("discovery failed: provider doesn't support PKCE with S256")
+	}
+
+	p.discovery = &discovery
+	return discovery, nil
+}
+
+// key returns the public key with kid, refetching the keys if kid is unknown since the
+// provider may have rotated them. Tokens without a kid are accepted if there is a single key.
+func (p *Provider) key(ctx context.Context, discovery Discovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	find := func() (jwk.Key, bool) {
+		if kid == "" && len(p.keys.Keys) == 1 {
+			return p.keys.Keys[0], true
+		}
+		return p.keys.Find(kid)
+	}
+
+	key, ok := find()
+	stale := time.Since(p.keysFetchedAt) > keysMaxAge
+	if (!ok || stale) && time.Since(p.keysFetchedAt) > keysRefreshInterval {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys jwk.Set
+		if err = p.do(req, &keys); err != nil {
+			return nil, fmt.Errorf("couldn't fetch keys: %w", err)
+		}
+		p.keys, p.keysFetchedAt = keys, time.Now()
+
+		key, ok = find()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	// keys published for encryption must not verify signatures
+	if key.Use != "" && key.Use != "sig" {
+		return nil, fmt.Errorf("key %q is not a signing key", kid)
+	}
+
+	return key.PublicKey()
+}
+
+// do sends req and decodes the JSON response into v, which is decoded for error responses
+// as well since they carry the error code
+func (p *Provider) do(req *http.Request, v any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return decodeErr
+}
+
+// NewVerifier returns a PKCE code verifier along with its S256 challenge, RFC 7636 section 4
+func NewVerifier() (verifier, challenge string, err error) {
+	if verifier, err = RandomString(); err != nil {
+		return "", "", err
+	}
+
+	hash := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// RandomString returns 256 random bits, URL-safe, e.g. for the state and nonce
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
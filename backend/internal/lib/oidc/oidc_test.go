@@ -0,0 +1,170 @@
+package oidc_test
+
+import (
+	"backend/internal/lib/oidc"
+	"backend/internal/lib/oidc/oidctest"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const redirectURL = "http://localhost:8080/user/login/oidc/callback"
+
+func TestProvider_Exchange(t *testing.T) {
+	idp := oidctest.NewProvider("petstore", "secret")
+	defer idp.Close()
+
+	provider := oidc.NewProvider(idp.Issuer(), "petstore", "secret", redirectURL)
+	ctx := context.Background()
+
+	verifier, challenge, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	parsed, _ := url.Parse(authURL)
+	if query := parsed.Query(); query.Get("scope") != "openid email profile" || query.Get("redirect_uri") != redirectURL {
+		t.Errorf("AuthCodeURL() = %s, want the scopes and the redirect URL", authURL)
+	}
+
+	code, state, err := idp.Login(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Errorf("state = %s, want state-1", state)
+	}
+
+	// the verifier has to match the challenge
+	if _, err = provider.Exchange(ctx, code, verifier+"x", "nonce-1"); !errors.Is(err, oidc.ErrRejected) {
+		t.Errorf("Exchange() error = %v for a wrong verifier, want %v", err, oidc.ErrRejected)
+	}
+
+	code, _, _ = idp.Login(authURL)
+	identity, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	want := oidc.Identity{
+		Issuer:        idp.Issuer(),
+		Subject:       "subject-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+	}
+	if identity != want {
+		t.Errorf("Exchange() = %+v, want %+v", identity, want)
+	}
+
+	// codes are single use
+	if _, err = provider.Exchange(ctx, code, verifier, "nonce-1"); !errors.Is(err, oidc.ErrRejected) {
+		t.Errorf("Exchange() error = %v for a used code, want %v", err, oidc.ErrRejected)
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	idp := oidctest.NewProvider("petstore", "secret")
+	defer idp.Close()
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	testCases := []struct {
+		name   string
+		claims map[string]any
+		token  func() string
+		nonce  string
+	}{
+		{name: "other audience", claims: map[string]any{"aud": "other"}},
+		{name: "issued to another party", claims: map[string]any{"aud": []string{"petstore", "other"}, "azp": "other"}},
+		{name: "other issuer", claims: map[string]any{"iss": "https://idp.example.com"}},
+		{name: "expired", claims: map[string]any{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{name: "no expiry", claims: map[string]any{"exp": nil}},
+		{name: "no subject", claims: map[string]any{"sub": nil}},
+		{name: "nonce mismatch", nonce: "other"},
+		{name: "signed with another key", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+				"iss": idp.Issuer(), "sub": "subject-1", "aud": "petstore", "nonce": "nonce-1",
+				"exp": time.Now().Add(time.Minute).Unix(),
+			})
+			token.Header["kid"] = idp.KeyId
+			signed, _ := token.SignedString(otherKey)
+			return signed
+		}},
+		{name: "signed with the client secret", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"iss": idp.Issuer(), "sub": "subject-1", "aud": "petstore", "nonce": "nonce-1",
+				"exp": time.Now().Add(time.Minute).Unix(),
+			})
+			signed, _ := token.SignedString([]byte("secret"))
+			return signed
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := oidc.NewProvider(idp.Issuer(), "petstore", "secret", redirectURL)
+			idp.Claims = tc.claims
+
+			token := idp.IDToken("nonce-1")
+			if tc.token != nil {
+				token = tc.token()
+			}
+			nonce := "nonce-1"
+			if tc.nonce != "" {
+				nonce = tc.nonce
+			}
+
+			_, err := provider.VerifyIDToken(context.Background(), token, nonce)
+			if !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("VerifyIDToken() error = %v, want %v", err, oidc.ErrInvalidToken)
+			}
+		})
+	}
+
+	t.Run("multiple audiences", func(t *testing.T) {
+		provider := oidc.NewProvider(idp.Issuer(), "petstore", "secret", redirectURL)
+		idp.Claims = map[string]any{"aud": []string{"petstore", "other"}, "azp": "petstore", "email_verified": "true"}
+
+		identity, err := provider.VerifyIDToken(context.Background(), idp.IDToken("nonce-1"), "nonce-1")
+		if err != nil {
+			t.Fatalf("VerifyIDToken() error = %v", err)
+		}
+		if !identity.EmailVerified {
+			t.Errorf("VerifyIDToken() EmailVerified = false, want true")
+		}
+	})
+}
+
+func TestProvider_Discover(t *testing.T) {
+	idp := oidctest.NewProvider("petstore", "secret")
+	defer idp.Close()
+
+	// the issuer in the document has to be the one configured
+	provider := oidc.NewProvider(strings.Replace(idp.Issuer(), "127.0.0.1", "localhost", 1), "petstore", "secret", redirectURL)
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Errorf("Discover() error = nil for another issuer, want an error")
+	}
+
+	provider = oidc.NewProvider(idp.Issuer()+"/", "petstore", "secret", redirectURL)
+	discovery, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if discovery.TokenEndpoint != idp.URL+"/token" {
+		t.Errorf("Discover() TokenEndpoint = %s, want %s", discovery.TokenEndpoint, idp.URL+"/token")
+	}
+}
@@ -0,0 +1,225 @@
+// Package oidctest runs an in-process OpenID provider for tests of the relying party flow.
+// It serves discovery, the authorization endpoint, which logs in immediately, the token
+// endpoint, which checks the client credentials and the PKCE verifier, and its JWKS.
+package oidctest
+
+import (
+	"backend/internal/lib/jwk"
+	"backend/internal/lib/oidc"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Provider is a fake OpenID provider, its issuer is the URL of the server
+type Provider struct {
+	*httptest.Server
+	ClientId     string
+	ClientSecret string
+	KeyId        string
+	Key          crypto.Signer
+	// Claims are added to the ID tokens issued, overriding the defaults; a nil value removes a claim
+	Claims map[string]any
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+type authRequest struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewProvider starts a provider the client is registered with, close it when done
+func NewProvider(clientId, clientSecret string) *Provider {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &Provider{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		KeyId:        "test-key",
+		Key:          key,
+		Claims:       map[string]any{},
+		codes:        make(map[string]authRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// Issuer returns the issuer identifier of the provider
+func (p *Provider) Issuer() string {
+	return p.URL
+}
+
+// Login follows authURL as the browser of a user who logs in at once and returns the
+// code and state the provider redirects back with
+func (p *Provider) Login(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", "", errors.New("authorization failed: " + resp.Status)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+// IDToken returns an ID token for the client with the nonce, the default claims and Claims
+func (p *Provider) IDToken(nonce string) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer(),
+		"sub":            "subject-1",
+		"aud":            p.ClientId,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+	for name, value := range p.Claims {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+
+	return p.Sign(claims)
+}
+
+// Sign signs claims with the key of the provider
+func (p *Provider) Sign(claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = p.KeyId
+
+	signed, err := token.SignedString(p.Key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+
+	if query.Get("client_id") != p.ClientId || redirectURI == "" || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := oidc.RandomString()
+
+	p.mu.Lock()
+	p.codes[code] = authRequest{redirectURI: redirectURI, challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	p.mu.Unlock()
+
+	location, _ := url.Parse(redirectURI)
+	values := location.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	location.RawQuery = values.Encode()
+
+	http.Redirect(w, r, location.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, _ := r.BasicAuth()
+	clientId, _ = url.QueryUnescape(clientId)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	if clientId != p.ClientId || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// codes are single use
+	p.mu.Lock()
+	request, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || request.redirectURI != r.PostFormValue("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(hash[:]) != request.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     p.IDToken(request.nonce),
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	key, err := jwk.FromPublicKey(p.KeyId, p.Key.Public())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, jwk.Set{Keys: []jwk.Key{key}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
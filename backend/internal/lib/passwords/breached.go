@@ -0,0 +1,92 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BreachedList is a k-anonymity range lookup of breached password hashes: it is asked for
+// the suffixes of the SHA-1 hashes starting with a 5 character prefix, so the password, or
+// even its full hash, never has to leave the caller. The format follows the range API of
+// Have I Been Pwned, an online implementation can be dropped in later.
+type BreachedList interface {
+	Range(prefix string) ([]string, error)
+}
+
+// IsBreached tells whether password is in list
+func IsBreached(list BreachedList, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := list.Range(hash[:5])
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(suffixes, hash[5:]), nil
+}
+
+// HashList is a BreachedList held in memory
+type HashList map[string][]string
+
+func (l HashList) Range(prefix string) ([]string, error) {
+	return l[strings.ToUpper(prefix)], nil
+}
+
+// LoadList reads a list of hashes, one "<prefix>:<suffix>" or full SHA-1 hash per line.
+// Empty lines and lines starting with # are skipped, as are counts after a second colon.
+func LoadList(r io.Reader) (HashList, error) {
+	list := make(HashList)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(strings.ToUpper(line), ":")
+		hash := parts[0]
+		if len(hash) == 5 && len(parts) > 1 {
+			hash += parts[1]
+		}
+		if len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d is not a sha1 hash", n)
+		}
+
+		list[hash[:5]] = append(list[hash[:5]], hash[5:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+//go:embed breached.txt
+var bundled string
+
+var (
+	bundledOnce sync.Once
+	bundledList HashList
+)
+
+// Bundled returns the list of common breached passwords shipped with the server
+func Bundled() HashList {
+	bundledOnce.Do(func() {
+		list, err := LoadList(strings.NewReader(bundled))
+		if err != nil {
+			panic("invalid bundled breached password list: " + err.Error())
+		}
+		bundledList = list
+	})
+	return bundledList
+}
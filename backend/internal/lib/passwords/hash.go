@@ -0,0 +1,201 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// Hash algorithms, named as in their PHC strings
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Params are the parameters new hashes are made with. Hashes made with other parameters,
+// or with the other algorithm, still verify but report that they need a rehash.
+type Params struct {
+	Algorithm string
+	Memory    uint32 // argon2id memory in KiB
+	Time      uint32 // argon2id passes over the memory
+	Threads   uint8  // argon2id parallelism
+	KeyLength uint32 // argon2id hash length in bytes
+	Cost      int    // bcrypt cost
+}
+
+// DefaultParams follow the second recommended argon2id option of RFC 9106 scaled down to
+// 64 MiB, which keeps a login in the tens of milliseconds
+var DefaultParams = Params{
+	Algorithm: Argon2id,
+	Memory:    64 * 1024,
+	Time:      3,
+	Threads:   2,
+	KeyLength: 32,
+	Cost:      bcrypt.DefaultCost,
+}
+
+const saltLength = 16
+
+// Hasher makes and verifies password hashes in the PHC string format, e.g.
+// "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>". bcrypt hashes keep their own
+// "$2a$10$..." format, which the PHC format grew out of, so existing hashes stay valid.
+type Hasher struct {
+	params Params
+}
+
+type HasherOption func(*Hasher)
+
+// WithArgon2id makes new hashes with argon2id, memory is in KiB
+func WithArgon2id(memory, time uint32, threads uint8) HasherOption {
+	return func(h *Hasher) {
+		h.params.Algorithm = Argon2id
+		h.params.Memory = memory
+		h.params.Time = time
+		h.params.Threads = threads
+	}
+}
+
+// WithBcrypt makes new hashes with bcrypt
+func WithBcrypt(cost int) HasherOption {
+	return func(h *Hasher) {
+		h.params.Algorithm = Bcrypt
+		h.params.Cost = cost
+	}
+}
+
+func NewHasher(options ...HasherOption) *Hasher {
+	h := &Hasher{params: DefaultParams}
+
+	for _, option := range options {
+		option(h)
+	}
+
+	return h
+}
+
+func (h *Hasher) Params() Params {
+	return h.params
+}
+
+// Hash returns the hash of password made with the current parameters
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.Cost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify tells whether password matches hash, along with whether the hash should be
+// replaced by one made with the current parameters
+func (h *Hasher) Verify(password, hash string) (ok bool, rehash bool, err error) {
+	params, err := parseParams(hash)
+	if err != nil {
+		return false, false, err
+	}
+
+	if params.Algorithm == Bcrypt {
+		if err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+		return true, h.outdated(params), nil
+	}
+
+	salt, key, err := argon2Values(hash)
+	if err != nil {
+		return false, false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	return true, h.outdated(params), nil
+}
+
+// NeedsRehash tells whether hash was made with other parameters than the current ones
+func (h *Hasher) NeedsRehash(hash string) bool {
+	params, err := parseParams(hash)
+	return err == nil && h.outdated(params)
+}
+
+func (h *Hasher) outdated(params Params) bool {
+	current := h.params
+	if params.Algorithm != current.Algorithm {
+		return true
+	}
+	if params.Algorithm == Bcrypt {
+		return params.Cost != current.Cost
+	}
+	return params.Memory != current.Memory || params.Time != current.Time ||
+		params.Threads != current.Threads || params.KeyLength != current.KeyLength
+}
+
+// parseParams reads the algorithm and parameters a hash was made with
+func parseParams(hash string) (Params, error) {
+	if cost, err := bcrypt.Cost([]byte(hash)); err == nil {
+		return Params{Algorithm: Bcrypt, Cost: cost}, nil
+	}
+
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, hash
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != Argon2id {
+		return Params{}, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, ErrUnknownHash
+	}
+
+	params := Params{Algorithm: Argon2id}
+	// argon2 panics on zero passes or threads
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil ||
+		params.Time == 0 || params.Threads == 0 {
+		return Params{}, ErrUnknownHash
+	}
+
+	_, key, err := argon2Values(hash)
+	if err != nil {
+		return Params{}, err
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, nil
+}
+
+func argon2Values(hash string) (salt, key []byte, err error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 {
+		return nil, nil, ErrUnknownHash
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil {
+		return nil, nil, ErrUnknownHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(fields[5]); err != nil || len(key) == 0 {
+		return nil, nil, ErrUnknownHash
+	}
+
+	return salt, key, nil
+}
@@ -0,0 +1,138 @@
+package passwords
+
+import (
+	"backend/internal/lib/e"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	testCases := []struct {
+		name           string
+		password       string
+		wantViolations int
+	}{
+		{"strong", "Correct-Horse-7", 0},
+		{"too short", "Ab1!", 1},
+		{"too long", "Ab1!" + strings.Repeat("x", 72), 1},
+		{"too few classes", "correcthorsebattery", 1},
+		{"username", "Wanomir2024!", 0},
+		{"same as username", "WANOMIR", 3},
+		{"same as email", "W.Anomir7@example.com", 1},
+		{"same as email local part", "W.anomir7", 1},
+		{"breached", "P@ssw0rd", 1},
+		{"breached and short", "123456", 3},
+	}
+
+	policy := NewPolicy(WithMinClasses(3))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, "wanomir", "w.anomir7@example.com")
+			if tc.wantViolations == 0 {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var ve *e.Error
+			if !errors.As(err, &ve) || !errors.Is(err, e.ErrValidation) {
+				t.Fatalf("Check() error = %v, want %v", err, e.ErrValidation)
+			}
+			if len(ve.Violations) != tc.wantViolations {
+				t.Errorf("Check() violations = %+v, want %d", ve.Violations, tc.wantViolations)
+			}
+			for _, violation := range ve.Violations {
+				if violation.Field != Field {
+					t.Errorf("Check() violation field = %s, want %s", violation.Field, Field)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckWithoutBreachedList(t *testing.T) {
+	if err := NewPolicy(WithBreachedList(nil)).Check("P@ssw0rd"); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
+
+func TestLoadList(t *testing.T) {
+	// sha1 of "password" in range format, as a full hash with a count, and a malformed line
+	list, err := LoadList(strings.NewReader("# comment\n\n5BAA6:1E4C9B93F3F0682250B6CF8331B7EE68FD8\n7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577\n"))
+	if err != nil {
+		t.Fatalf("LoadList() error = %v", err)
+	}
+
+	for _, password := range []string{"password", "123456"} {
+		if breached, _ := IsBreached(list, password); !breached {
+			t.Errorf("IsBreached(%q) = false, want true", password)
+		}
+	}
+	if breached, _ := IsBreached(list, "Correct-Horse-7"); breached {
+		t.Errorf("IsBreached() = true, want false")
+	}
+
+	if _, err = LoadList(strings.NewReader("5BAA6:1E4C\n")); err == nil {
+		t.Errorf("LoadList() error = nil, want an error for a truncated hash")
+	}
+}
+
+func TestHasher(t *testing.T) {
+	// small argon2id parameters keep the test fast
+	hasher := NewHasher(WithArgon2id(1024, 1, 1))
+
+	hash, err := hasher.Hash("Correct-Horse-7")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Hash() = %s, want a PHC argon2id string", hash)
+	}
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("Correct-Horse-7"), bcrypt.MinCost)
+	other, _ := NewHasher(WithArgon2id(2048, 1, 1)).Hash("Correct-Horse-7")
+
+	testCases := []struct {
+		name       string
+		password   string
+		hash       string
+		wantOk     bool
+		wantRehash bool
+		wantErr    bool
+	}{
+		{"current parameters", "Correct-Horse-7", hash, true, false, false},
+		{"wrong password", "Correct-Horse-8", hash, false, false, false},
+		{"bcrypt", "Correct-Horse-7", string(legacy), true, true, false},
+		{"wrong password for bcrypt", "Correct-Horse-8", string(legacy), false, false, false},
+		{"outdated parameters", "Correct-Horse-7", other, true, true, false},
+		{"unknown format", "Correct-Horse-7", "$md5$abc", false, false, true},
+		{"zero threads", "Correct-Horse-7", strings.Replace(hash, "p=1", "p=0", 1), false, false, true},
+		{"corrupted hash", "Correct-Horse-7", strings.TrimSuffix(hash, hash[len(hash)-44:]) + "!", false, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash, err := hasher.Verify(tc.password, tc.hash)
+			if ok != tc.wantOk || rehash != tc.wantRehash || (err != nil) != tc.wantErr {
+				t.Errorf("Verify() = %v, %v, %v, want %v, %v, error %v", ok, rehash, err, tc.wantOk, tc.wantRehash, tc.wantErr)
+			}
+			if tc.wantOk && hasher.NeedsRehash(tc.hash) != tc.wantRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", !tc.wantRehash, tc.wantRehash)
+			}
+		})
+	}
+
+	t.Run("bcrypt cost", func(t *testing.T) {
+		hasher := NewHasher(WithBcrypt(bcrypt.MinCost + 1))
+		if !hasher.NeedsRehash(string(legacy)) {
+			t.Errorf("NeedsRehash() = false for a lower bcrypt cost, want true")
+		}
+		if upgraded, _ := hasher.Hash("Correct-Horse-7"); hasher.NeedsRehash(upgraded) {
+			t.Errorf("NeedsRehash() = true for a hash with the current cost, want false")
+		}
+	})
+}
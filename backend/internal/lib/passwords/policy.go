@@ -0,0 +1,127 @@
+// Package passwords checks new passwords against a configurable policy: length, a mix of
+// character classes, not being the username or email, and not appearing in a list of
+// breached passwords. It also hashes passwords, see Hasher.
+package passwords
+
+import (
+	"backend/internal/lib/e"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Field is the name violations of the policy are reported under
+const Field = "password"
+
+type Policy struct {
+	MinLength  int // in characters
+	MaxLength  int // in bytes, bcrypt ignores anything past 72
+	MinClasses int // of lowercase letters, uppercase letters, digits and symbols
+	breached   BreachedList
+}
+
+type PolicyOption func(*Policy)
+
+func WithLength(min, max int) PolicyOption {
+	return func(p *Policy) {
+		p.MinLength = min
+		p.MaxLength = max
+	}
+}
+
+func WithMinClasses(n int) PolicyOption {
+	return func(p *Policy) {
+		p.MinClasses = n
+	}
+}
+
+// WithBreachedList sets the list breached passwords are looked up in, nil turns the
+// check off. By default the bundled list of common passwords is used.
+func WithBreachedList(list BreachedList) PolicyOption {
+	return func(p *Policy) {
+		p.breached = list
+	}
+}
+
+func NewPolicy(options ...PolicyOption) *Policy {
+	p := &Policy{MinLength: 8, MaxLength: 72, MinClasses: 3, breached: Bundled()}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// Check returns a validation error listing every rule the password breaks, or nil.
+// identities are the username and email of the account, the password must not be one
+// of them; for emails the part before the @ counts as well.
+func (p *Policy) Check(password string, identities ...string) error {
+	var violations []e.Violation
+	violate := func(msg string) {
+		violations = append(violations, e.Violation{Field: Field, Message: msg})
+	}
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		violate(fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violate(fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	if classes(password) < p.MinClasses {
+		violate(fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+
+	if matchesIdentity(password, identities) {
+		violate("must not be the username or email")
+	}
+
+	if p.breached != nil {
+		breached, err := IsBreached(p.breached, password)
+		if err != nil {
+			return e.Internal("failed to check password", err)
+		}
+		if breached {
+			violate("appears in a list of breached passwords, choose another one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return e.Validation("password is too weak", violations...)
+	}
+	return nil
+}
+
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func matchesIdentity(password string, identities []string) bool {
+	for _, identity := range identities {
+		if identity == "" {
+			continue
+		}
+		if strings.EqualFold(password, identity) {
+			return true
+		}
+		if local, _, ok := strings.Cut(identity, "@"); ok && local != "" && strings.EqualFold(password, local) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package rr
+
+import (
+	"backend/internal/lib/e"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a single field level violation reported in Problem.Errors
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// problemTypes identifies every error kind with its own problem type, errors without a kind are "about:blank"
+var problemTypes = map[error]string{
+	e.ErrNotFound:     "/problems/not-found",
+	e.ErrConflict:     "/problems/conflict",
+	e.ErrValidation:   "/problems/validation",
+	e.ErrUnauthorized: "/problems/unauthorized",
+	e.ErrForbidden:    "/problems/forbidden",
+	e.ErrRateLimited:  "/problems/rate-limited",
+	e.ErrInternal:     "/problems/internal",
+}
+
+// WithProblemDetails makes application/problem+json the default error format
+// instead of the legacy JSONResponse envelope.
+func WithProblemDetails() ReadRespondOption {
+	return func(r *ReadRespond) {
+		r.problemDetails = true
+	}
+}
+
+// WriteError writes err either as a problem+json document or as the legacy JSONResponse
+// envelope. Problem details are used when enabled with WithProblemDetails or when the
+// client asks for them in the Accept header. The status is resolved as in WriteJSONError.
+func (rr *ReadRespond) WriteError(w http.ResponseWriter, r *http.Request, err error, status ...int) error {
+	if !rr.problemDetails {
+		// caches must not serve one format to a client that asked for the other
+		w.Header().Add("Vary", "Accept")
+		if !acceptsProblem(r) {
+			return rr.WriteJSONError(w, err, status...)
+		}
+	}
+
+	statusCode := http.StatusBadRequest
+	if len(status) > 0 {
+		statusCode = status[0]
+	}
+	statusCode = StatusCode(err, statusCode)
+	setRetryAfter(w, err)
+
+	return rr.WriteProblem(w, NewProblem(r, err, statusCode))
+}
+
+// NewProblem describes err as a problem that occurred while serving r
+func NewProblem(r *http.Request, err error, status int) Problem {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: message(err, status),
+	}
+
+	if problemType, ok := problemTypes[e.KindOf(err)]; ok {
+		problem.Type = problemType
+	}
+
+	if r != nil && r.URL != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	for _, violation := range e.ViolationsOf(err) {
+		problem.Errors = append(problem.Errors, FieldError{Field: violation.Field, Message: violation.Message})
+	}
+
+	return problem
+}
+
+func (rr *ReadRespond) WriteProblem(w http.ResponseWriter, problem Problem) error {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+
+	return json.NewEncoder(w).Encode(problem)
+}
+
+// acceptsProblem reports whether the Accept header of r lists application/problem+json
+func acceptsProblem(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && mediaType == problemContentType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
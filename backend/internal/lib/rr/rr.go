@@ -1,16 +1,23 @@
 package rr
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/validate"
 	"encoding/json"
 	"errors"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type ReadResponder interface {
 	ReadJSON(w http.ResponseWriter, r *http.Request, data any) error
+	ReadPartialJSON(w http.ResponseWriter, r *http.Request, data any) error
 	WriteJSON(w http.ResponseWriter, status int, data any, headers ...http.Header) error
 	WriteJSONError(w http.ResponseWriter, err error, status ...int) error
+	WriteError(w http.ResponseWriter, r *http.Request, err error, status ...int) error
 }
 
 type JSONResponse struct {
@@ -20,7 +27,8 @@ type JSONResponse struct {
 }
 
 type ReadRespond struct {
-	maxBytes int
+	maxBytes       int
+	problemDetails bool
 }
 
 type ReadRespondOption func(*ReadRespond)
@@ -40,7 +48,26 @@ func NewReadRespond(options ...ReadRespondOption) *ReadRespond {
 	return rr
 }
 
+// ReadJSON decodes the request body into data and enforces the rules declared in its binding tags
 func (rr *ReadRespond) ReadJSON(w http.ResponseWriter, r *http.Request, data any) error {
+	if err := rr.decodeJSON(w, r, data); err != nil {
+		return err
+	}
+
+	return validate.Struct(data)
+}
+
+// ReadPartialJSON is ReadJSON for partial updates: the fields that are set are validated
+// but none of them is required.
+func (rr *ReadRespond) ReadPartialJSON(w http.ResponseWriter, r *http.Request, data any) error {
+	if err := rr.decodeJSON(w, r, data); err != nil {
+		return err
+	}
+
+	return validate.Partial(data)
+}
+
+func (rr *ReadRespond) decodeJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	if rr.maxBytes > 0 {
 		r.Body = http.MaxBytesReader(w, r.Body, int64(rr.maxBytes))
 	}
@@ -74,17 +101,67 @@ func (rr *ReadRespond) WriteJSON(w http.ResponseWriter, status int, data any, he
 	return json.NewEncoder(w).Encode(data)
 }
 
+// WriteJSONError writes err with the status code matching its kind (see StatusCode).
+// The optional status is used for errors that carry no kind and defaults to 400.
 func (rr *ReadRespond) WriteJSONError(w http.ResponseWriter, err error, status ...int) error {
 
 	statusCode := http.StatusBadRequest
 	if len(status) > 0 {
 		statusCode = status[0]
 	}
+	statusCode = StatusCode(err, statusCode)
+	setRetryAfter(w, err)
 
 	response := &JSONResponse{
 		Error:   true,
-		Message: err.Error(),
+		Message: message(err, statusCode),
 	}
 
 	return rr.WriteJSON(w, statusCode, response)
 }
+
+// message returns what the client is told about err. Failures of the server are logged and
+// only described by the message of the internal error, since its cause may hold details of
+// the database or driver that the client must not see.
+func message(err error, status int) string {
+	if status < http.StatusInternalServerError {
+		return err.Error()
+	}
+
+	log.Println("internal error:", err)
+
+	var internal *e.Error
+	if errors.As(err, &internal) && internal.Kind == e.ErrInternal {
+		return internal.Msg
+	}
+	return http.StatusText(status)
+}
+
+// StatusCode maps the kind of err to an HTTP status code, or returns fallback if err has no kind
+func StatusCode(err error, fallback int) int {
+	switch e.KindOf(err) {
+	case e.ErrNotFound:
+		return http.StatusNotFound
+	case e.ErrConflict:
+		return http.StatusConflict
+	case e.ErrValidation:
+		return http.StatusUnprocessableEntity
+	case e.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case e.ErrForbidden:
+		return http.StatusForbidden
+	case e.ErrRateLimited:
+		return http.StatusTooManyRequests
+	case e.ErrInternal:
+		return http.StatusInternalServerError
+	default:
+		return fallback
+	}
+}
+
+// setRetryAfter tells the client when to retry if err says so, rounding up to whole seconds
+func setRetryAfter(w http.ResponseWriter, err error) {
+	if retryAfter := e.RetryAfterOf(err); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+	}
+}
@@ -0,0 +1,190 @@
+package rr
+
+import (
+	"backend/internal/lib/e"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRespond_WriteJSONError(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		status     []int
+		wantStatus int
+	}{
+		{"no kind", errors.New("invalid id supplied"), nil, http.StatusBadRequest},
+		{"no kind with status", errors.New("file too large"), []int{http.StatusRequestEntityTooLarge}, http.StatusRequestEntityTooLarge},
+		{"not found", e.NotFound("pet not found"), nil, http.StatusNotFound},
+		{"wrapped not found", e.Wrap("couldn't get pet", e.NotFound("pet not found")), nil, http.StatusNotFound},
+		{"conflict", e.Conflict("user already exists"), nil, http.StatusConflict},
+		{"validation", e.Validation("invalid status"), nil, http.StatusUnprocessableEntity},
+		{"unauthorized", e.Unauthorized("invalid credentials"), nil, http.StatusUnauthorized},
+		{"forbidden", e.Forbidden("admin role required"), nil, http.StatusForbidden},
+		{"rate limited", e.RateLimited("too many failed logins", time.Second), nil, http.StatusTooManyRequests},
+		{"internal overrides status", e.Internal("failed to execute query", errors.New("connection refused")), []int{http.StatusNotFound}, http.StatusInternalServerError},
+	}
+
+	rr := NewReadRespond()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := rr.WriteJSONError(w, tc.err, tc.status...); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.wantStatus {
+				t.Errorf("WriteJSONError() status = %d, want %d", w.Code, tc.wantStatus)
+			}
+		})
+	}
+}
+
+// the cause of a server failure is logged, the client only gets the safe message
+func TestReadRespond_InternalCause(t *testing.T) {
+	cause := errors.New(`ERROR: relation "users" does not exist (SQLSTATE 42P01)`)
+
+	testCases := []struct {
+		name    string
+		rr      *ReadRespond
+		err     error
+		status  []int
+		wantMsg string
+	}{
+		{"legacy envelope", NewReadRespond(), e.Wrap("couldn't get user", e.Internal("failed to execute query", cause)), nil, "failed to execute query"},
+		{"problem details", NewReadRespond(WithProblemDetails()), e.Wrap("couldn't get user", e.Internal("failed to execute query", cause)), nil, "failed to execute query"},
+		{"no kind", NewReadRespond(), e.Wrap("couldn't upload image", cause), []int{http.StatusInternalServerError}, "Internal Server Error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := tc.rr.WriteError(w, httptest.NewRequest(http.MethodGet, "/user/wanomir", nil), tc.err, tc.status...); err != nil {
+				t.Fatal(err)
+			}
+
+			body := w.Body.String()
+			if w.Code != http.StatusInternalServerError || !strings.Contains(body, tc.wantMsg) {
+				t.Errorf("WriteError() = %d %s, want 500 with %q", w.Code, body, tc.wantMsg)
+			}
+			if strings.Contains(body, "SQLSTATE") || strings.Contains(body, "couldn't") {
+				t.Errorf("WriteError() body = %s, want no details of the cause", body)
+			}
+		})
+	}
+}
+
+func TestReadRespond_RetryAfter(t *testing.T) {
+	testCases := []struct {
+		name string
+		rr   *ReadRespond
+		err  error
+		want string
+	}{
+		{"legacy envelope", NewReadRespond(), e.RateLimited("account is locked", 15*time.Minute), "900"},
+		{"problem details", NewReadRespond(WithProblemDetails()), e.Wrap("couldn't authorize user", e.RateLimited("too many failed logins", 1500*time.Millisecond)), "2"},
+		{"no retry", NewReadRespond(), e.Unauthorized("invalid credentials"), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := tc.rr.WriteError(w, httptest.NewRequest(http.MethodPost, "/user/login", nil), tc.err); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("Retry-After"); got != tc.want {
+				t.Errorf("Retry-After = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadRespond_WriteError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		rr          *ReadRespond
+		accept      string
+		err         error
+		wantType    string
+		wantVary    string
+		wantProblem Problem
+	}{
+		{
+			"legacy envelope",
+			NewReadRespond(),
+			"application/json",
+			e.NotFound("pet not found"),
+			"application/json;charset=utf-8",
+			"Accept",
+			Problem{},
+		},
+		{
+			"requested with accept header",
+			NewReadRespond(),
+			"application/json, application/problem+json;q=0.9",
+			e.NotFound("pet not found"),
+			problemContentType,
+			"Accept",
+			Problem{Type: "/problems/not-found", Title: "Not Found", Status: 404, Detail: "pet not found", Instance: "/pet/10"},
+		},
+		{
+			"enabled by configuration",
+			NewReadRespond(WithProblemDetails()),
+			"",
+			errors.New("invalid id supplied"),
+			problemContentType,
+			"",
+			Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "invalid id supplied", Instance: "/pet/10"},
+		},
+		{
+			"field violations",
+			NewReadRespond(WithProblemDetails()),
+			"",
+			e.Validation("invalid pet", e.Violation{Field: "name", Message: "is required"}, e.Violation{Field: "status", Message: "must be one of available pending sold"}),
+			problemContentType,
+			"",
+			Problem{
+				Type: "/problems/validation", Title: "Unprocessable Entity", Status: 422, Detail: "invalid pet", Instance: "/pet/10",
+				Errors: []FieldError{{"name", "is required"}, {"status", "must be one of available pending sold"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/pet/10", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			if err := tc.rr.WriteError(w, r, tc.err); err != nil {
+				t.Fatal(err)
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.wantType {
+				t.Fatalf("WriteError() content type = %s, want %s", contentType, tc.wantType)
+			}
+			if vary := w.Header().Get("Vary"); vary != tc.wantVary {
+				t.Errorf("WriteError() vary = %q, want %q", vary, tc.wantVary)
+			}
+			if tc.wantType != problemContentType {
+				return
+			}
+
+			var problem Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(problem, tc.wantProblem) {
+				t.Errorf("WriteError() problem = %+v, want %+v", problem, tc.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// the SHA1 test vectors of RFC 6238 appendix B, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := encoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		time int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		got, err := Code(secret, Step(time.Unix(tc.time, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("Code() at %d = %s, want %s", tc.time, got, tc.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1792281600, 0)
+	current := Step(now)
+
+	testCases := []struct {
+		name     string
+		step     int64
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current", current, current, true},
+		{"previous", current - 1, current - 1, true},
+		{"next", current + 1, current + 1, true},
+		{"too old", current - 2, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, _ := Code(secret, tc.step)
+			if step, ok := Validate(secret, code, now); step != tc.wantStep || ok != tc.wantOk {
+				t.Errorf("Validate() = %d, %v, want %d, %v", step, ok, tc.wantStep, tc.wantOk)
+			}
+		})
+	}
+
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Errorf("Validate() accepted a code of the wrong length")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Petstore", "john doe", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Petstore:john%20doe?") {
+		t.Errorf("URI() = %s, want otpauth://totp/Petstore:john%%20doe?...", uri)
+	}
+	for _, param := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Petstore", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("URI() = %s, want it to contain %s", uri, param)
+		}
+	}
+}
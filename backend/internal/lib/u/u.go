@@ -1,8 +1,22 @@
 package u
 
-import "strings"
+import (
+	"net"
+	"net/http"
+	"strings"
+)
 
 func ParamFromPath(path string) string {
 	parts := strings.Split(path, "/")
 	return parts[len(parts)-1]
 }
+
+// ClientIP returns the address of the peer that sent the request, without the port.
+// Forwarding headers are ignored since any client can set them.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,192 @@
+// Package validate checks structs against the rules listed in their `binding` tags.
+//
+// Rules are separated by commas, e.g. `binding:"required,max=255"`:
+//
+//	required        the field must not be a zero value
+//	oneof=a b c     the value must be one of the space separated options
+//	email           the value must be a valid email address
+//	min=n, max=n    length bounds for strings and slices, value bounds for numbers
+//	gt=n            numbers must be greater than n
+//
+// Every rule but required is skipped for zero values, so optional fields are only
+// checked when set. Nested structs and slices of structs are validated as well.
+// Partial skips required, for updates that only carry the fields being changed.
+package validate
+
+import (
+	"backend/internal/lib/e"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const tagName = "binding"
+
+// Struct validates v, a struct, a slice of structs or a pointer to either, and returns
+// a validation error listing every violated rule, or nil if there are none.
+func Struct(v any) error {
+	return (&validator{}).validate(v)
+}
+
+// Partial validates v like Struct but does not require any field to be set
+func Partial(v any) error {
+	return (&validator{partial: true}).validate(v)
+}
+
+type validator struct {
+	partial    bool
+	violations []e.Violation
+}
+
+func (vr *validator) validate(v any) error {
+	vr.walk(reflect.ValueOf(v), "")
+
+	if len(vr.violations) > 0 {
+		return e.Validation("invalid request", vr.violations...)
+	}
+	return nil
+}
+
+func (vr *validator) walk(v reflect.Value, path string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			fieldPath := join(path, fieldName(field))
+			if rules, ok := field.Tag.Lookup(tagName); ok {
+				for _, message := range vr.check(v.Field(i), rules) {
+					vr.violations = append(vr.violations, e.Violation{Field: fieldPath, Message: message})
+				}
+			}
+			// a missing nested struct is reported by its own rules, not by the rules of its fields
+			if !v.Field(i).IsZero() {
+				vr.walk(v.Field(i), fieldPath)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vr.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// check applies the comma separated rules to v and returns a message for every failed rule
+func (vr *validator) check(v reflect.Value, rules string) []string {
+	var messages []string
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name == "required" {
+			if v.IsZero() && !vr.partial {
+				// nothing else can be said about a missing value
+				return []string{"is required"}
+			}
+			continue
+		}
+
+		if v.IsZero() {
+			continue
+		}
+
+		if message := apply(v, name, param); message != "" {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+func apply(v reflect.Value, rule, param string) string {
+	switch rule {
+	case "oneof":
+		options := strings.Fields(param)
+		value := fmt.Sprint(v.Interface())
+		for _, option := range options {
+			if value == option {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(options, ", ")
+
+	case "email":
+		address, err := mail.ParseAddress(v.String())
+		if err != nil || address.Address != v.String() {
+			return "must be a valid email address"
+		}
+
+	case "min", "max", "gt":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid %s parameter %q", rule, param))
+		}
+
+		size, unit, ok := measure(v)
+		if !ok {
+			panic(fmt.Sprintf("validate: rule %s does not apply to %s", rule, v.Kind()))
+		}
+
+		switch {
+		case rule == "min" && size < limit:
+			return fmt.Sprintf("must be at least %s%s", param, unit)
+		case rule == "max" && size > limit:
+			return fmt.Sprintf("must be at most %s%s", param, unit)
+		case rule == "gt" && size <= limit:
+			return "must be greater than " + param
+		}
+
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q", rule))
+	}
+
+	return ""
+}
+
+// measure returns the value of a number or the length of a string or slice along with its unit
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters long", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	default:
+		return 0, "", false
+	}
+}
+
+// fieldName returns the name a field has in JSON documents
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,99 @@
+package validate
+
+import (
+	"backend/internal/lib/e"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testTag struct {
+	Name string `json:"name" binding:"required,max=5"`
+}
+
+type testPet struct {
+	Id       int       `json:"id"`
+	Name     string    `json:"name" binding:"required,min=2,max=10"`
+	Status   string    `json:"status" binding:"required,oneof=available pending sold"`
+	Owner    string    `json:"owner" binding:"email"`
+	Quantity int       `json:"quantity" binding:"gt=0"`
+	Tags     []testTag `json:"tags" binding:"max=2"`
+	Parent   *testTag  `json:"parent"`
+}
+
+func TestStruct(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value any
+		want  []e.Violation
+	}{
+		{
+			"valid",
+			&testPet{Name: "Poppy", Status: "available", Owner: "john@example.com", Quantity: 1, Tags: []testTag{{"calm"}}},
+			nil,
+		},
+		{
+			"optional fields are skipped",
+			testPet{Name: "Poppy", Status: "sold"},
+			nil,
+		},
+		{
+			"missing required fields",
+			testPet{},
+			[]e.Violation{{Field: "name", Message: "is required"}, {Field: "status", Message: "is required"}},
+		},
+		{
+			"every violation is reported",
+			testPet{Name: "P", Status: "lost", Owner: "john", Quantity: -1, Tags: []testTag{{"calm"}, {""}, {"fluffy"}}},
+			[]e.Violation{
+				{Field: "name", Message: "must be at least 2 characters long"},
+				{Field: "status", Message: "must be one of: available, pending, sold"},
+				{Field: "owner", Message: "must be a valid email address"},
+				{Field: "quantity", Message: "must be greater than 0"},
+				{Field: "tags", Message: "must be at most 2 items"},
+				{Field: "tags[1].name", Message: "is required"},
+				{Field: "tags[2].name", Message: "must be at most 5 characters long"},
+			},
+		},
+		{
+			"nested pointer",
+			testPet{Name: "Poppy", Status: "sold", Parent: &testTag{}},
+			[]e.Violation{{Field: "parent.name", Message: "is required"}},
+		},
+		{
+			"slice of structs",
+			[]testTag{{"calm"}, {}},
+			[]e.Violation{{Field: "[1].name", Message: "is required"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Struct(tc.value)
+			if (err != nil) != (tc.want != nil) {
+				t.Fatalf("Struct() error = %v, want violations %v", err, tc.want)
+			}
+			if err == nil {
+				return
+			}
+
+			if !errors.Is(err, e.ErrValidation) {
+				t.Errorf("Struct() error = %v, want validation error", err)
+			}
+			if violations := e.ViolationsOf(err); !reflect.DeepEqual(violations, tc.want) {
+				t.Errorf("Struct() violations = %v, want %v", violations, tc.want)
+			}
+		})
+	}
+}
+
+func TestPartial(t *testing.T) {
+	if err := Partial(&testPet{Name: "Bobby"}); err != nil {
+		t.Errorf("Partial() error = %v, want nil", err)
+	}
+
+	want := []e.Violation{{Field: "status", Message: "must be one of: available, pending, sold"}}
+	if violations := e.ViolationsOf(Partial(&testPet{Status: "lost"})); !reflect.DeepEqual(violations, want) {
+		t.Errorf("Partial() violations = %v, want %v", violations, want)
+	}
+}
@@ -0,0 +1,59 @@
+package filemail
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/mail"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer is the mailer for development: instead of sending messages it saves each one
+// as an .eml file in dir, or only logs it if dir is empty, so the flows work offline
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, e.Wrap("failed to create mail directory", err)
+		}
+	}
+
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg mail.Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), hex.EncodeToString(suffix))
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s", msg.To, msg.Subject, now.Format(time.RFC1123Z), msg.Body)
+
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return e.Wrap("failed to save mail", err)
+	}
+
+	log.Printf("mail to %s saved to %s", msg.To, path)
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+//go:generate mockgen -source=./mail.go -destination=../mocks/mock_mail/mock_mail.go
+
+// Mailer delivers plain text messages to users, e.g. email verification and password
+// reset links. Backends are expected to be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Validate rejects messages that can't be delivered or would inject headers,
+// the addresses and subject end up in the message header as they are
+func (m Message) Validate() error {
+	if m.To == "" {
+		return errors.New("message has no recipient")
+	}
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return errors.New("message header contains a line break")
+	}
+	return nil
+}
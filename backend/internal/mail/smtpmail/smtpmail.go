@@ -0,0 +1,96 @@
+package smtpmail
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/mail"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server. The connection is upgraded with
+// STARTTLS when the server supports it, which net/smtp requires for authentication
+// unless the server is on localhost.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+type SMTPMailerOption func(*SMTPMailer)
+
+// WithAuth sets the PLAIN credentials to log in to the server with
+func WithAuth(username, password string) SMTPMailerOption {
+	return func(m *SMTPMailer) {
+		host, _, _ := net.SplitHostPort(m.addr)
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+}
+
+// NewSMTPMailer returns a mailer sending through the server at addr, e.g. "smtp.example.com:587",
+// with from as the sender address
+func NewSMTPMailer(addr, from string, options ...SMTPMailerOption) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg mail.Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	body, err := m.compose(msg)
+	if err != nil {
+		return e.Internal("failed to compose mail", err)
+	}
+
+	// net/smtp has no context support, the send is only skipped if ctx is already done
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if err = smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, body); err != nil {
+		return e.Internal("failed to send mail", err)
+	}
+
+	return nil
+}
+
+// compose renders msg as a plain text RFC 5322 message
+func (m *SMTPMailer) compose(msg mail.Message) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	domain, _, _ := net.SplitHostPort(m.addr)
+	if at := strings.LastIndexByte(m.from, '@'); at >= 0 {
+		domain = m.from[at+1:]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+
+	return buf.Bytes(), nil
+}
@@ -5,7 +5,9 @@
 package mock_service
 
 import (
+	jwk "backend/internal/lib/jwk"
 	entities "backend/internal/modules/auth/entities"
+	context "context"
 	http "net/http"
 	reflect "reflect"
 
@@ -35,6 +37,21 @@ func (m *MockAuthServicer) EXPECT() *MockAuthServicerMockRecorder {
 	return m.recorder
 }
 
+// ClientCredentials mocks base method.
+func (m *MockAuthServicer) ClientCredentials(ctx context.Context, clientId, clientSecret, scope string) (entities.ClientToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientCredentials", ctx, clientId, clientSecret, scope)
+	ret0, _ := ret[0].(entities.ClientToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClientCredentials indicates an expected call of ClientCredentials.
+func (mr *MockAuthServicerMockRecorder) ClientCredentials(ctx, clientId, clientSecret, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientCredentials", reflect.TypeOf((*MockAuthServicer)(nil).ClientCredentials), ctx, clientId, clientSecret, scope)
+}
+
 // CreateCookie mocks base method.
 func (m *MockAuthServicer) CreateCookie(refreshToken string) *http.Cookie {
 	m.ctrl.T.Helper()
@@ -63,6 +80,34 @@ func (mr *MockAuthServicerMockRecorder) CreateExpiredCookie() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExpiredCookie", reflect.TypeOf((*MockAuthServicer)(nil).CreateExpiredCookie))
 }
 
+// CreateExpiredLoginStateCookie mocks base method.
+func (m *MockAuthServicer) CreateExpiredLoginStateCookie() *http.Cookie {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExpiredLoginStateCookie")
+	ret0, _ := ret[0].(*http.Cookie)
+	return ret0
+}
+
+// CreateExpiredLoginStateCookie indicates an expected call of CreateExpiredLoginStateCookie.
+func (mr *MockAuthServicerMockRecorder) CreateExpiredLoginStateCookie() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExpiredLoginStateCookie", reflect.TypeOf((*MockAuthServicer)(nil).CreateExpiredLoginStateCookie))
+}
+
+// CreateLoginStateCookie mocks base method.
+func (m *MockAuthServicer) CreateLoginStateCookie(token string) *http.Cookie {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginStateCookie", token)
+	ret0, _ := ret[0].(*http.Cookie)
+	return ret0
+}
+
+// CreateLoginStateCookie indicates an expected call of CreateLoginStateCookie.
+func (mr *MockAuthServicerMockRecorder) CreateLoginStateCookie(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginStateCookie", reflect.TypeOf((*MockAuthServicer)(nil).CreateLoginStateCookie), token)
+}
+
 // EncryptPassword mocks base method.
 func (m *MockAuthServicer) EncryptPassword(password string) (string, error) {
 	m.ctrl.T.Helper()
@@ -78,19 +123,232 @@ func (mr *MockAuthServicerMockRecorder) EncryptPassword(password interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptPassword", reflect.TypeOf((*MockAuthServicer)(nil).EncryptPassword), password)
 }
 
-// GenerateToken mocks base method.
-func (m *MockAuthServicer) GenerateToken(subject string) (string, error) {
+// GenerateAPIKey mocks base method.
+func (m *MockAuthServicer) GenerateAPIKey() (string, entities.APIKey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateToken", subject)
+	ret := m.ctrl.Call(m, "GenerateAPIKey")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(entities.APIKey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateAPIKey indicates an expected call of GenerateAPIKey.
+func (mr *MockAuthServicerMockRecorder) GenerateAPIKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAPIKey", reflect.TypeOf((*MockAuthServicer)(nil).GenerateAPIKey))
+}
+
+// GenerateChallengeToken mocks base method.
+func (m *MockAuthServicer) GenerateChallengeToken(userId int, subject string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateChallengeToken", userId, subject)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GenerateChallengeToken indicates an expected call of GenerateChallengeToken.
+func (mr *MockAuthServicerMockRecorder) GenerateChallengeToken(userId, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateChallengeToken", reflect.TypeOf((*MockAuthServicer)(nil).GenerateChallengeToken), userId, subject)
+}
+
+// GenerateLoginState mocks base method.
+func (m *MockAuthServicer) GenerateLoginState(state entities.LoginState) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateLoginState", state)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateLoginState indicates an expected call of GenerateLoginState.
+func (mr *MockAuthServicerMockRecorder) GenerateLoginState(state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateLoginState", reflect.TypeOf((*MockAuthServicer)(nil).GenerateLoginState), state)
+}
+
+// GenerateRefreshToken mocks base method.
+func (m *MockAuthServicer) GenerateRefreshToken() (string, entities.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateRefreshToken")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(entities.RefreshToken)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateRefreshToken indicates an expected call of GenerateRefreshToken.
+func (mr *MockAuthServicerMockRecorder) GenerateRefreshToken() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockAuthServicer)(nil).GenerateRefreshToken))
+}
+
+// GenerateToken mocks base method.
+func (m *MockAuthServicer) GenerateToken(userId int, subject string, role entities.Role) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateToken", userId, subject, role)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GenerateToken indicates an expected call of GenerateToken.
-func (mr *MockAuthServicerMockRecorder) GenerateToken(subject interface{}) *gomock.Call {
+func (mr *MockAuthServicerMockRecorder) GenerateToken(userId, subject, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuthServicer)(nil).GenerateToken), userId, subject, role)
+}
+
+// GenerateUserToken mocks base method.
+func (m *MockAuthServicer) GenerateUserToken(purpose entities.TokenPurpose, userId int, subject, email string) (string, entities.UserToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateUserToken", purpose, userId, subject, email)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(entities.UserToken)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateUserToken indicates an expected call of GenerateUserToken.
+func (mr *MockAuthServicerMockRecorder) GenerateUserToken(purpose, userId, subject, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuthServicer)(nil).GenerateToken), subject)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateUserToken", reflect.TypeOf((*MockAuthServicer)(nil).GenerateUserToken), purpose, userId, subject, email)
+}
+
+// HashRefreshToken mocks base method.
+func (m *MockAuthServicer) HashRefreshToken(token string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HashRefreshToken", token)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// HashRefreshToken indicates an expected call of HashRefreshToken.
+func (mr *MockAuthServicerMockRecorder) HashRefreshToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HashRefreshToken", reflect.TypeOf((*MockAuthServicer)(nil).HashRefreshToken), token)
+}
+
+// JWKS mocks base method.
+func (m *MockAuthServicer) JWKS() jwk.Set {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(jwk.Set)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockAuthServicerMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockAuthServicer)(nil).JWKS))
+}
+
+// LoginStateFromCookie mocks base method.
+func (m *MockAuthServicer) LoginStateFromCookie(r *http.Request) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginStateFromCookie", r)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// LoginStateFromCookie indicates an expected call of LoginStateFromCookie.
+func (mr *MockAuthServicerMockRecorder) LoginStateFromCookie(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginStateFromCookie", reflect.TypeOf((*MockAuthServicer)(nil).LoginStateFromCookie), r)
+}
+
+// NeedsRehash mocks base method.
+func (m *MockAuthServicer) NeedsRehash(encryptedPassword string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NeedsRehash", encryptedPassword)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NeedsRehash indicates an expected call of NeedsRehash.
+func (mr *MockAuthServicerMockRecorder) NeedsRehash(encryptedPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NeedsRehash", reflect.TypeOf((*MockAuthServicer)(nil).NeedsRehash), encryptedPassword)
+}
+
+// RefreshTokenFromCookie mocks base method.
+func (m *MockAuthServicer) RefreshTokenFromCookie(r *http.Request) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokenFromCookie", r)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RefreshTokenFromCookie indicates an expected call of RefreshTokenFromCookie.
+func (mr *MockAuthServicerMockRecorder) RefreshTokenFromCookie(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokenFromCookie", reflect.TypeOf((*MockAuthServicer)(nil).RefreshTokenFromCookie), r)
+}
+
+// RevokeTokens mocks base method.
+func (m *MockAuthServicer) RevokeTokens(ctx context.Context, tokens ...entities.RevokedToken) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range tokens {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeTokens", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeTokens indicates an expected call of RevokeTokens.
+func (mr *MockAuthServicerMockRecorder) RevokeTokens(ctx interface{}, tokens ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, tokens...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokens", reflect.TypeOf((*MockAuthServicer)(nil).RevokeTokens), varargs...)
+}
+
+// VerifyAPIKey mocks base method.
+func (m *MockAuthServicer) VerifyAPIKey(ctx context.Context, key string) (entities.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAPIKey", ctx, key)
+	ret0, _ := ret[0].(entities.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAPIKey indicates an expected call of VerifyAPIKey.
+func (mr *MockAuthServicerMockRecorder) VerifyAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAPIKey", reflect.TypeOf((*MockAuthServicer)(nil).VerifyAPIKey), ctx, key)
+}
+
+// VerifyChallengeToken mocks base method.
+func (m *MockAuthServicer) VerifyChallengeToken(token string) (*entities.Claims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChallengeToken", token)
+	ret0, _ := ret[0].(*entities.Claims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyChallengeToken indicates an expected call of VerifyChallengeToken.
+func (mr *MockAuthServicerMockRecorder) VerifyChallengeToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChallengeToken", reflect.TypeOf((*MockAuthServicer)(nil).VerifyChallengeToken), token)
+}
+
+// VerifyLoginState mocks base method.
+func (m *MockAuthServicer) VerifyLoginState(token string) (entities.LoginState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyLoginState", token)
+	ret0, _ := ret[0].(entities.LoginState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyLoginState indicates an expected call of VerifyLoginState.
+func (mr *MockAuthServicerMockRecorder) VerifyLoginState(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyLoginState", reflect.TypeOf((*MockAuthServicer)(nil).VerifyLoginState), token)
 }
 
 // VerifyPassword mocks base method.
@@ -123,3 +381,18 @@ func (mr *MockAuthServicerMockRecorder) VerifyRequest(w, r interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRequest", reflect.TypeOf((*MockAuthServicer)(nil).VerifyRequest), w, r)
 }
+
+// VerifyUserToken mocks base method.
+func (m *MockAuthServicer) VerifyUserToken(purpose entities.TokenPurpose, token string) (*entities.Claims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserToken", purpose, token)
+	ret0, _ := ret[0].(*entities.Claims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyUserToken indicates an expected call of VerifyUserToken.
+func (mr *MockAuthServicerMockRecorder) VerifyUserToken(purpose, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserToken", reflect.TypeOf((*MockAuthServicer)(nil).VerifyUserToken), purpose, token)
+}
@@ -7,6 +7,7 @@ package mock_service
 import (
 	entities "backend/internal/modules/pet/entities"
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -94,6 +95,52 @@ func (mr *MockPetServicerMockRecorder) GetByStatus(ctx, status interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByStatus", reflect.TypeOf((*MockPetServicer)(nil).GetByStatus), ctx, status)
 }
 
+// GetByTags mocks base method.
+func (m *MockPetServicer) GetByTags(ctx context.Context, tags []string, matchAll bool) ([]entities.Pet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByTags", ctx, tags, matchAll)
+	ret0, _ := ret[0].([]entities.Pet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByTags indicates an expected call of GetByTags.
+func (mr *MockPetServicerMockRecorder) GetByTags(ctx, tags, matchAll interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByTags", reflect.TypeOf((*MockPetServicer)(nil).GetByTags), ctx, tags, matchAll)
+}
+
+// GetImage mocks base method.
+func (m *MockPetServicer) GetImage(ctx context.Context, petId int, imageName string) (io.ReadCloser, entities.PetImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImage", ctx, petId, imageName)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(entities.PetImage)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetImage indicates an expected call of GetImage.
+func (mr *MockPetServicerMockRecorder) GetImage(ctx, petId, imageName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockPetServicer)(nil).GetImage), ctx, petId, imageName)
+}
+
+// List mocks base method.
+func (m *MockPetServicer) List(ctx context.Context, filter entities.PetFilter) (entities.PetPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].(entities.PetPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockPetServicerMockRecorder) List(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPetServicer)(nil).List), ctx, filter)
+}
+
 // Update mocks base method.
 func (m *MockPetServicer) Update(ctx context.Context, pet entities.Pet) error {
 	m.ctrl.T.Helper()
@@ -121,3 +168,18 @@ func (mr *MockPetServicerMockRecorder) UpdateWithForm(ctx, id, name, status inte
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWithForm", reflect.TypeOf((*MockPetServicer)(nil).UpdateWithForm), ctx, id, name, status)
 }
+
+// UploadImage mocks base method.
+func (m *MockPetServicer) UploadImage(ctx context.Context, petId int, upload entities.ImageUpload) (entities.PetImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadImage", ctx, petId, upload)
+	ret0, _ := ret[0].(entities.PetImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadImage indicates an expected call of UploadImage.
+func (mr *MockPetServicerMockRecorder) UploadImage(ctx, petId, upload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockPetServicer)(nil).UploadImage), ctx, petId, upload)
+}
@@ -5,12 +5,15 @@
 package mock_repository
 
 import (
-	entities "backend/internal/modules/pet/entities"
-	entities0 "backend/internal/modules/store/entities"
-	entities1 "backend/internal/modules/user/entities"
+	entities "backend/internal/modules/auth/entities"
+	entities0 "backend/internal/modules/pet/entities"
+	entities1 "backend/internal/modules/store/entities"
+	entities2 "backend/internal/modules/user/entities"
+	repository "backend/internal/repository"
 	context "context"
 	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -52,8 +55,38 @@ func (mr *MockRepositoryMockRecorder) Connection() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connection", reflect.TypeOf((*MockRepository)(nil).Connection))
 }
 
+// CountUsersByRole mocks base method.
+func (m *MockRepository) CountUsersByRole(ctx context.Context, role entities.Role) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsersByRole", ctx, role)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsersByRole indicates an expected call of CountUsersByRole.
+func (mr *MockRepositoryMockRecorder) CountUsersByRole(ctx, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsersByRole", reflect.TypeOf((*MockRepository)(nil).CountUsersByRole), ctx, role)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key entities.APIKey) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockRepositoryMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockRepository)(nil).CreateAPIKey), ctx, key)
+}
+
 // CreateOrder mocks base method.
-func (m *MockRepository) CreateOrder(ctx context.Context, order entities0.Order) (int, error) {
+func (m *MockRepository) CreateOrder(ctx context.Context, order entities1.Order) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateOrder", ctx, order)
 	ret0, _ := ret[0].(int)
@@ -68,25 +101,25 @@ func (mr *MockRepositoryMockRecorder) CreateOrder(ctx, order interface{}) *gomoc
 }
 
 // CreatePet mocks base method.
-func (m *MockRepository) CreatePet(ctx context.Context, categoryId int, petName, petStatus string) (int, error) {
+func (m *MockRepository) CreatePet(ctx context.Context, categoryId int, petName, petStatus, createdBy string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePet", ctx, categoryId, petName, petStatus)
+	ret := m.ctrl.Call(m, "CreatePet", ctx, categoryId, petName, petStatus, createdBy)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreatePet indicates an expected call of CreatePet.
-func (mr *MockRepositoryMockRecorder) CreatePet(ctx, categoryId, petName, petStatus interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CreatePet(ctx, categoryId, petName, petStatus, createdBy interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePet", reflect.TypeOf((*MockRepository)(nil).CreatePet), ctx, categoryId, petName, petStatus)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePet", reflect.TypeOf((*MockRepository)(nil).CreatePet), ctx, categoryId, petName, petStatus, createdBy)
 }
 
 // CreatePetCategory mocks base method.
-func (m *MockRepository) CreatePetCategory(ctx context.Context, categoryName string) (entities.Category, error) {
+func (m *MockRepository) CreatePetCategory(ctx context.Context, categoryName string) (entities0.Category, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePetCategory", ctx, categoryName)
-	ret0, _ := ret[0].(entities.Category)
+	ret0, _ := ret[0].(entities0.Category)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -98,11 +131,12 @@ func (mr *MockRepositoryMockRecorder) CreatePetCategory(ctx, categoryName interf
 }
 
 // CreatePetPhotoUrl mocks base method.
-func (m *MockRepository) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) error {
+func (m *MockRepository) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePetPhotoUrl", ctx, petId, photoUrl)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreatePetPhotoUrl indicates an expected call of CreatePetPhotoUrl.
@@ -112,10 +146,10 @@ func (mr *MockRepositoryMockRecorder) CreatePetPhotoUrl(ctx, petId, photoUrl int
 }
 
 // CreatePetTagPair mocks base method.
-func (m *MockRepository) CreatePetTagPair(ctx context.Context, petId, tagId int) (entities.PetTag, error) {
+func (m *MockRepository) CreatePetTagPair(ctx context.Context, petId, tagId int) (entities0.PetTag, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePetTagPair", ctx, petId, tagId)
-	ret0, _ := ret[0].(entities.PetTag)
+	ret0, _ := ret[0].(entities0.PetTag)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -126,11 +160,41 @@ func (mr *MockRepositoryMockRecorder) CreatePetTagPair(ctx, petId, tagId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetTagPair", reflect.TypeOf((*MockRepository)(nil).CreatePetTagPair), ctx, petId, tagId)
 }
 
+// CreatePhotoThumbnail mocks base method.
+func (m *MockRepository) CreatePhotoThumbnail(ctx context.Context, thumbnail entities0.Thumbnail) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePhotoThumbnail", ctx, thumbnail)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePhotoThumbnail indicates an expected call of CreatePhotoThumbnail.
+func (mr *MockRepositoryMockRecorder) CreatePhotoThumbnail(ctx, thumbnail interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePhotoThumbnail", reflect.TypeOf((*MockRepository)(nil).CreatePhotoThumbnail), ctx, thumbnail)
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockRepository) CreateRefreshToken(ctx context.Context, token entities.RefreshToken) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, token)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockRepositoryMockRecorder) CreateRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockRepository)(nil).CreateRefreshToken), ctx, token)
+}
+
 // CreateTag mocks base method.
-func (m *MockRepository) CreateTag(ctx context.Context, tagName string) (entities.Tag, error) {
+func (m *MockRepository) CreateTag(ctx context.Context, tagName string) (entities0.Tag, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateTag", ctx, tagName)
-	ret0, _ := ret[0].(entities.Tag)
+	ret0, _ := ret[0].(entities0.Tag)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -142,7 +206,7 @@ func (mr *MockRepositoryMockRecorder) CreateTag(ctx, tagName interface{}) *gomoc
 }
 
 // CreateUser mocks base method.
-func (m *MockRepository) CreateUser(ctx context.Context, user entities1.User) (int, error) {
+func (m *MockRepository) CreateUser(ctx context.Context, user entities2.User) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
 	ret0, _ := ret[0].(int)
@@ -156,6 +220,20 @@ func (mr *MockRepositoryMockRecorder) CreateUser(ctx, user interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockRepository)(nil).CreateUser), ctx, user)
 }
 
+// CreateUserToken mocks base method.
+func (m *MockRepository) CreateUserToken(ctx context.Context, token entities.UserToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUserToken indicates an expected call of CreateUserToken.
+func (mr *MockRepositoryMockRecorder) CreateUserToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserToken", reflect.TypeOf((*MockRepository)(nil).CreateUserToken), ctx, token)
+}
+
 // DeleteOrder mocks base method.
 func (m *MockRepository) DeleteOrder(ctx context.Context, orderId int) error {
 	m.ctrl.T.Helper()
@@ -212,6 +290,20 @@ func (mr *MockRepositoryMockRecorder) DeletePhotoUrlsByPetId(ctx, petId interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePhotoUrlsByPetId", reflect.TypeOf((*MockRepository)(nil).DeletePhotoUrlsByPetId), ctx, petId)
 }
 
+// DeleteTwoFactor mocks base method.
+func (m *MockRepository) DeleteTwoFactor(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTwoFactor indicates an expected call of DeleteTwoFactor.
+func (mr *MockRepositoryMockRecorder) DeleteTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTwoFactor", reflect.TypeOf((*MockRepository)(nil).DeleteTwoFactor), ctx, userId)
+}
+
 // DeleteUser mocks base method.
 func (m *MockRepository) DeleteUser(ctx context.Context, username string) error {
 	m.ctrl.T.Helper()
@@ -226,631 +318,1613 @@ func (mr *MockRepositoryMockRecorder) DeleteUser(ctx, username interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockRepository)(nil).DeleteUser), ctx, username)
 }
 
-// GetOrderById mocks base method.
-func (m *MockRepository) GetOrderById(ctx context.Context, orderId int) (entities0.Order, error) {
+// EnableTwoFactor mocks base method.
+func (m *MockRepository) EnableTwoFactor(ctx context.Context, userId int, step int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrderById", ctx, orderId)
-	ret0, _ := ret[0].(entities0.Order)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "EnableTwoFactor", ctx, userId, step)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetOrderById indicates an expected call of GetOrderById.
-func (mr *MockRepositoryMockRecorder) GetOrderById(ctx, orderId interface{}) *gomock.Call {
+// EnableTwoFactor indicates an expected call of EnableTwoFactor.
+func (mr *MockRepositoryMockRecorder) EnableTwoFactor(ctx, userId, step interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderById", reflect.TypeOf((*MockRepository)(nil).GetOrderById), ctx, orderId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTwoFactor", reflect.TypeOf((*MockRepository)(nil).EnableTwoFactor), ctx, userId, step)
 }
 
-// GetPetById mocks base method.
-func (m *MockRepository) GetPetById(ctx context.Context, petId int) (entities.Pet, error) {
+// GetAPIKeyByHash mocks base method.
+func (m *MockRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (entities.APIKey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetById", ctx, petId)
-	ret0, _ := ret[0].(entities.Pet)
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(entities.APIKey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetById indicates an expected call of GetPetById.
-func (mr *MockRepositoryMockRecorder) GetPetById(ctx, petId interface{}) *gomock.Call {
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockRepositoryMockRecorder) GetAPIKeyByHash(ctx, keyHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetById", reflect.TypeOf((*MockRepository)(nil).GetPetById), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockRepository)(nil).GetAPIKeyByHash), ctx, keyHash)
 }
 
-// GetPetCategoryById mocks base method.
-func (m *MockRepository) GetPetCategoryById(ctx context.Context, categoryId int) (entities.Category, error) {
+// GetClientById mocks base method.
+func (m *MockRepository) GetClientById(ctx context.Context, clientId string) (entities.Client, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetCategoryById", ctx, categoryId)
-	ret0, _ := ret[0].(entities.Category)
+	ret := m.ctrl.Call(m, "GetClientById", ctx, clientId)
+	ret0, _ := ret[0].(entities.Client)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetCategoryById indicates an expected call of GetPetCategoryById.
-func (mr *MockRepositoryMockRecorder) GetPetCategoryById(ctx, categoryId interface{}) *gomock.Call {
+// GetClientById indicates an expected call of GetClientById.
+func (mr *MockRepositoryMockRecorder) GetClientById(ctx, clientId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryById", reflect.TypeOf((*MockRepository)(nil).GetPetCategoryById), ctx, categoryId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientById", reflect.TypeOf((*MockRepository)(nil).GetClientById), ctx, clientId)
 }
 
-// GetPetCategoryByName mocks base method.
-func (m *MockRepository) GetPetCategoryByName(ctx context.Context, categoryName string) (entities.Category, error) {
+// GetInventory mocks base method.
+func (m *MockRepository) GetInventory(ctx context.Context) ([]entities1.InventoryItem, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetCategoryByName", ctx, categoryName)
-	ret0, _ := ret[0].(entities.Category)
+	ret := m.ctrl.Call(m, "GetInventory", ctx)
+	ret0, _ := ret[0].([]entities1.InventoryItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetCategoryByName indicates an expected call of GetPetCategoryByName.
-func (mr *MockRepositoryMockRecorder) GetPetCategoryByName(ctx, categoryName interface{}) *gomock.Call {
+// GetInventory indicates an expected call of GetInventory.
+func (mr *MockRepositoryMockRecorder) GetInventory(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryByName", reflect.TypeOf((*MockRepository)(nil).GetPetCategoryByName), ctx, categoryName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventory", reflect.TypeOf((*MockRepository)(nil).GetInventory), ctx)
 }
 
-// GetPetTagPair mocks base method.
-func (m *MockRepository) GetPetTagPair(ctx context.Context, petId, tagId int) (entities.PetTag, error) {
+// GetLoginAttempts mocks base method.
+func (m *MockRepository) GetLoginAttempts(ctx context.Context, keys []string) ([]entities.LoginAttempts, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetTagPair", ctx, petId, tagId)
-	ret0, _ := ret[0].(entities.PetTag)
+	ret := m.ctrl.Call(m, "GetLoginAttempts", ctx, keys)
+	ret0, _ := ret[0].([]entities.LoginAttempts)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetTagPair indicates an expected call of GetPetTagPair.
-func (mr *MockRepositoryMockRecorder) GetPetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
+// GetLoginAttempts indicates an expected call of GetLoginAttempts.
+func (mr *MockRepositoryMockRecorder) GetLoginAttempts(ctx, keys interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPair", reflect.TypeOf((*MockRepository)(nil).GetPetTagPair), ctx, petId, tagId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempts", reflect.TypeOf((*MockRepository)(nil).GetLoginAttempts), ctx, keys)
 }
 
-// GetPetTagPairsByPetId mocks base method.
-func (m *MockRepository) GetPetTagPairsByPetId(ctx context.Context, petId int) ([]entities.PetTag, error) {
+// GetOrderById mocks base method.
+func (m *MockRepository) GetOrderById(ctx context.Context, orderId int) (entities1.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetTagPairsByPetId", ctx, petId)
-	ret0, _ := ret[0].([]entities.PetTag)
+	ret := m.ctrl.Call(m, "GetOrderById", ctx, orderId)
+	ret0, _ := ret[0].(entities1.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetTagPairsByPetId indicates an expected call of GetPetTagPairsByPetId.
-func (mr *MockRepositoryMockRecorder) GetPetTagPairsByPetId(ctx, petId interface{}) *gomock.Call {
+// GetOrderById indicates an expected call of GetOrderById.
+func (mr *MockRepositoryMockRecorder) GetOrderById(ctx, orderId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPairsByPetId", reflect.TypeOf((*MockRepository)(nil).GetPetTagPairsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderById", reflect.TypeOf((*MockRepository)(nil).GetOrderById), ctx, orderId)
 }
 
-// GetPetsByStatus mocks base method.
-func (m *MockRepository) GetPetsByStatus(ctx context.Context, petStatus string) ([]entities.Pet, error) {
+// GetPetById mocks base method.
+func (m *MockRepository) GetPetById(ctx context.Context, petId int) (entities0.Pet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetsByStatus", ctx, petStatus)
-	ret0, _ := ret[0].([]entities.Pet)
+	ret := m.ctrl.Call(m, "GetPetById", ctx, petId)
+	ret0, _ := ret[0].(entities0.Pet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetsByStatus indicates an expected call of GetPetsByStatus.
-func (mr *MockRepositoryMockRecorder) GetPetsByStatus(ctx, petStatus interface{}) *gomock.Call {
+// GetPetById indicates an expected call of GetPetById.
+func (mr *MockRepositoryMockRecorder) GetPetById(ctx, petId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByStatus", reflect.TypeOf((*MockRepository)(nil).GetPetsByStatus), ctx, petStatus)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetById", reflect.TypeOf((*MockRepository)(nil).GetPetById), ctx, petId)
 }
 
-// GetPhotoUrlsByPetId mocks base method.
-func (m *MockRepository) GetPhotoUrlsByPetId(ctx context.Context, petId int) ([]entities.PhotoUrl, error) {
+// GetPetCategoryByName mocks base method.
+func (m *MockRepository) GetPetCategoryByName(ctx context.Context, categoryName string) (entities0.Category, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPhotoUrlsByPetId", ctx, petId)
-	ret0, _ := ret[0].([]entities.PhotoUrl)
+	ret := m.ctrl.Call(m, "GetPetCategoryByName", ctx, categoryName)
+	ret0, _ := ret[0].(entities0.Category)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPhotoUrlsByPetId indicates an expected call of GetPhotoUrlsByPetId.
-func (mr *MockRepositoryMockRecorder) GetPhotoUrlsByPetId(ctx, petId interface{}) *gomock.Call {
+// GetPetCategoryByName indicates an expected call of GetPetCategoryByName.
+func (mr *MockRepositoryMockRecorder) GetPetCategoryByName(ctx, categoryName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPhotoUrlsByPetId", reflect.TypeOf((*MockRepository)(nil).GetPhotoUrlsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryByName", reflect.TypeOf((*MockRepository)(nil).GetPetCategoryByName), ctx, categoryName)
 }
 
-// GetTagById mocks base method.
-func (m *MockRepository) GetTagById(ctx context.Context, tagId int) (entities.Tag, error) {
+// GetPetTagPair mocks base method.
+func (m *MockRepository) GetPetTagPair(ctx context.Context, petId, tagId int) (entities0.PetTag, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTagById", ctx, tagId)
-	ret0, _ := ret[0].(entities.Tag)
+	ret := m.ctrl.Call(m, "GetPetTagPair", ctx, petId, tagId)
+	ret0, _ := ret[0].(entities0.PetTag)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTagById indicates an expected call of GetTagById.
-func (mr *MockRepositoryMockRecorder) GetTagById(ctx, tagId interface{}) *gomock.Call {
+// GetPetTagPair indicates an expected call of GetPetTagPair.
+func (mr *MockRepositoryMockRecorder) GetPetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagById", reflect.TypeOf((*MockRepository)(nil).GetTagById), ctx, tagId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPair", reflect.TypeOf((*MockRepository)(nil).GetPetTagPair), ctx, petId, tagId)
 }
 
-// GetTagByName mocks base method.
-func (m *MockRepository) GetTagByName(ctx context.Context, tagName string) (entities.Tag, error) {
+// GetPetsByStatus mocks base method.
+func (m *MockRepository) GetPetsByStatus(ctx context.Context, petStatus string) ([]entities0.Pet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTagByName", ctx, tagName)
-	ret0, _ := ret[0].(entities.Tag)
+	ret := m.ctrl.Call(m, "GetPetsByStatus", ctx, petStatus)
+	ret0, _ := ret[0].([]entities0.Pet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTagByName indicates an expected call of GetTagByName.
-func (mr *MockRepositoryMockRecorder) GetTagByName(ctx, tagName interface{}) *gomock.Call {
+// GetPetsByStatus indicates an expected call of GetPetsByStatus.
+func (mr *MockRepositoryMockRecorder) GetPetsByStatus(ctx, petStatus interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagByName", reflect.TypeOf((*MockRepository)(nil).GetTagByName), ctx, tagName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByStatus", reflect.TypeOf((*MockRepository)(nil).GetPetsByStatus), ctx, petStatus)
 }
 
-// GetUserByUsername mocks base method.
-func (m *MockRepository) GetUserByUsername(ctx context.Context, username string) (entities1.User, error) {
+// GetPetsByTags mocks base method.
+func (m *MockRepository) GetPetsByTags(ctx context.Context, tagNames []string, matchAll bool) ([]entities0.Pet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, username)
-	ret0, _ := ret[0].(entities1.User)
+	ret := m.ctrl.Call(m, "GetPetsByTags", ctx, tagNames, matchAll)
+	ret0, _ := ret[0].([]entities0.Pet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByUsername indicates an expected call of GetUserByUsername.
-func (mr *MockRepositoryMockRecorder) GetUserByUsername(ctx, username interface{}) *gomock.Call {
+// GetPetsByTags indicates an expected call of GetPetsByTags.
+func (mr *MockRepositoryMockRecorder) GetPetsByTags(ctx, tagNames, matchAll interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockRepository)(nil).GetUserByUsername), ctx, username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByTags", reflect.TypeOf((*MockRepository)(nil).GetPetsByTags), ctx, tagNames, matchAll)
 }
 
-// UpdatePet mocks base method.
-func (m *MockRepository) UpdatePet(ctx context.Context, pet entities.Pet) error {
+// GetPhotoUrlsByPetIds mocks base method.
+func (m *MockRepository) GetPhotoUrlsByPetIds(ctx context.Context, petIds []int) (map[int][]entities0.PhotoUrl, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdatePet", ctx, pet)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetPhotoUrlsByPetIds", ctx, petIds)
+	ret0, _ := ret[0].(map[int][]entities0.PhotoUrl)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// UpdatePet indicates an expected call of UpdatePet.
-func (mr *MockRepositoryMockRecorder) UpdatePet(ctx, pet interface{}) *gomock.Call {
+// GetPhotoUrlsByPetIds indicates an expected call of GetPhotoUrlsByPetIds.
+func (mr *MockRepositoryMockRecorder) GetPhotoUrlsByPetIds(ctx, petIds interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePet", reflect.TypeOf((*MockRepository)(nil).UpdatePet), ctx, pet)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPhotoUrlsByPetIds", reflect.TypeOf((*MockRepository)(nil).GetPhotoUrlsByPetIds), ctx, petIds)
 }
 
-// UpdateUser mocks base method.
-func (m *MockRepository) UpdateUser(ctx context.Context, user entities1.User) error {
+// GetRefreshTokenByHash mocks base method.
+func (m *MockRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", ctx, user)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(entities.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockRepositoryMockRecorder) UpdateUser(ctx, user interface{}) *gomock.Call {
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockRepositoryMockRecorder) GetRefreshTokenByHash(ctx, tokenHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepository)(nil).UpdateUser), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockRepository)(nil).GetRefreshTokenByHash), ctx, tokenHash)
 }
 
-// MockPetRepository is a mock of PetRepository interface.
-type MockPetRepository struct {
-	ctrl     *gomock.Controller
-	recorder *MockPetRepositoryMockRecorder
+// GetRevokedAccessTokens mocks base method.
+func (m *MockRepository) GetRevokedAccessTokens(ctx context.Context, since time.Time) ([]entities.RevokedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevokedAccessTokens", ctx, since)
+	ret0, _ := ret[0].([]entities.RevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockPetRepositoryMockRecorder is the mock recorder for MockPetRepository.
-type MockPetRepositoryMockRecorder struct {
-	mock *MockPetRepository
+// GetRevokedAccessTokens indicates an expected call of GetRevokedAccessTokens.
+func (mr *MockRepositoryMockRecorder) GetRevokedAccessTokens(ctx, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokedAccessTokens", reflect.TypeOf((*MockRepository)(nil).GetRevokedAccessTokens), ctx, since)
 }
 
-// NewMockPetRepository creates a new mock instance.
-func NewMockPetRepository(ctrl *gomock.Controller) *MockPetRepository {
-	mock := &MockPetRepository{ctrl: ctrl}
-	mock.recorder = &MockPetRepositoryMockRecorder{mock}
-	return mock
+// GetTagByName mocks base method.
+func (m *MockRepository) GetTagByName(ctx context.Context, tagName string) (entities0.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTagByName", ctx, tagName)
+	ret0, _ := ret[0].(entities0.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockPetRepository) EXPECT() *MockPetRepositoryMockRecorder {
-	return m.recorder
+// GetTagByName indicates an expected call of GetTagByName.
+func (mr *MockRepositoryMockRecorder) GetTagByName(ctx, tagName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagByName", reflect.TypeOf((*MockRepository)(nil).GetTagByName), ctx, tagName)
 }
 
-// CreatePet mocks base method.
-func (m *MockPetRepository) CreatePet(ctx context.Context, categoryId int, petName, petStatus string) (int, error) {
+// GetTagsByPetIds mocks base method.
+func (m *MockRepository) GetTagsByPetIds(ctx context.Context, petIds []int) (map[int][]entities0.Tag, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePet", ctx, categoryId, petName, petStatus)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "GetTagsByPetIds", ctx, petIds)
+	ret0, _ := ret[0].(map[int][]entities0.Tag)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreatePet indicates an expected call of CreatePet.
-func (mr *MockPetRepositoryMockRecorder) CreatePet(ctx, categoryId, petName, petStatus interface{}) *gomock.Call {
+// GetTagsByPetIds indicates an expected call of GetTagsByPetIds.
+func (mr *MockRepositoryMockRecorder) GetTagsByPetIds(ctx, petIds interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePet", reflect.TypeOf((*MockPetRepository)(nil).CreatePet), ctx, categoryId, petName, petStatus)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagsByPetIds", reflect.TypeOf((*MockRepository)(nil).GetTagsByPetIds), ctx, petIds)
 }
 
-// CreatePetCategory mocks base method.
-func (m *MockPetRepository) CreatePetCategory(ctx context.Context, categoryName string) (entities.Category, error) {
+// GetTwoFactor mocks base method.
+func (m *MockRepository) GetTwoFactor(ctx context.Context, userId int) (entities.TwoFactor, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePetCategory", ctx, categoryName)
-	ret0, _ := ret[0].(entities.Category)
+	ret := m.ctrl.Call(m, "GetTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(entities.TwoFactor)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreatePetCategory indicates an expected call of CreatePetCategory.
-func (mr *MockPetRepositoryMockRecorder) CreatePetCategory(ctx, categoryName interface{}) *gomock.Call {
+// GetTwoFactor indicates an expected call of GetTwoFactor.
+func (mr *MockRepositoryMockRecorder) GetTwoFactor(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetCategory", reflect.TypeOf((*MockPetRepository)(nil).CreatePetCategory), ctx, categoryName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTwoFactor", reflect.TypeOf((*MockRepository)(nil).GetTwoFactor), ctx, userId)
 }
 
-// CreatePetPhotoUrl mocks base method.
-func (m *MockPetRepository) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) error {
+// GetUserByIdentity mocks base method.
+func (m *MockRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (entities2.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePetPhotoUrl", ctx, petId, photoUrl)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetUserByIdentity", ctx, issuer, subject)
+	ret0, _ := ret[0].(entities2.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CreatePetPhotoUrl indicates an expected call of CreatePetPhotoUrl.
-func (mr *MockPetRepositoryMockRecorder) CreatePetPhotoUrl(ctx, petId, photoUrl interface{}) *gomock.Call {
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockRepositoryMockRecorder) GetUserByIdentity(ctx, issuer, subject interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetPhotoUrl", reflect.TypeOf((*MockPetRepository)(nil).CreatePetPhotoUrl), ctx, petId, photoUrl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockRepository)(nil).GetUserByIdentity), ctx, issuer, subject)
 }
 
-// CreatePetTagPair mocks base method.
-func (m *MockPetRepository) CreatePetTagPair(ctx context.Context, petId, tagId int) (entities.PetTag, error) {
+// GetUserByUsername mocks base method.
+func (m *MockRepository) GetUserByUsername(ctx context.Context, username string) (entities2.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePetTagPair", ctx, petId, tagId)
-	ret0, _ := ret[0].(entities.PetTag)
+	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, username)
+	ret0, _ := ret[0].(entities2.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreatePetTagPair indicates an expected call of CreatePetTagPair.
-func (mr *MockPetRepositoryMockRecorder) CreatePetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
+// GetUserByUsername indicates an expected call of GetUserByUsername.
+func (mr *MockRepositoryMockRecorder) GetUserByUsername(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetTagPair", reflect.TypeOf((*MockPetRepository)(nil).CreatePetTagPair), ctx, petId, tagId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockRepository)(nil).GetUserByUsername), ctx, username)
 }
 
-// CreateTag mocks base method.
-func (m *MockPetRepository) CreateTag(ctx context.Context, tagName string) (entities.Tag, error) {
+// GetUsersByEmail mocks base method.
+func (m *MockRepository) GetUsersByEmail(ctx context.Context, email string) ([]entities2.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTag", ctx, tagName)
-	ret0, _ := ret[0].(entities.Tag)
+	ret := m.ctrl.Call(m, "GetUsersByEmail", ctx, email)
+	ret0, _ := ret[0].([]entities2.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateTag indicates an expected call of CreateTag.
-func (mr *MockPetRepositoryMockRecorder) CreateTag(ctx, tagName interface{}) *gomock.Call {
+// GetUsersByEmail indicates an expected call of GetUsersByEmail.
+func (mr *MockRepositoryMockRecorder) GetUsersByEmail(ctx, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTag", reflect.TypeOf((*MockPetRepository)(nil).CreateTag), ctx, tagName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByEmail", reflect.TypeOf((*MockRepository)(nil).GetUsersByEmail), ctx, email)
 }
 
-// DeletePet mocks base method.
-func (m *MockPetRepository) DeletePet(ctx context.Context, petId int) error {
+// LinkIdentity mocks base method.
+func (m *MockRepository) LinkIdentity(ctx context.Context, userId int, issuer, subject string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePet", ctx, petId)
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, userId, issuer, subject)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeletePet indicates an expected call of DeletePet.
-func (mr *MockPetRepositoryMockRecorder) DeletePet(ctx, petId interface{}) *gomock.Call {
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockRepositoryMockRecorder) LinkIdentity(ctx, userId, issuer, subject interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePet", reflect.TypeOf((*MockPetRepository)(nil).DeletePet), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockRepository)(nil).LinkIdentity), ctx, userId, issuer, subject)
 }
 
-// DeletePetTagsByPetId mocks base method.
-func (m *MockPetRepository) DeletePetTagsByPetId(ctx context.Context, petId int) error {
+// ListAPIKeys mocks base method.
+func (m *MockRepository) ListAPIKeys(ctx context.Context, username string) ([]entities.APIKey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePetTagsByPetId", ctx, petId)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, username)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeletePetTagsByPetId indicates an expected call of DeletePetTagsByPetId.
-func (mr *MockPetRepositoryMockRecorder) DeletePetTagsByPetId(ctx, petId interface{}) *gomock.Call {
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockRepositoryMockRecorder) ListAPIKeys(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePetTagsByPetId", reflect.TypeOf((*MockPetRepository)(nil).DeletePetTagsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockRepository)(nil).ListAPIKeys), ctx, username)
 }
 
-// DeletePhotoUrlsByPetId mocks base method.
-func (m *MockPetRepository) DeletePhotoUrlsByPetId(ctx context.Context, petId int) error {
+// ListPets mocks base method.
+func (m *MockRepository) ListPets(ctx context.Context, filter entities0.PetFilter) ([]entities0.Pet, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePhotoUrlsByPetId", ctx, petId)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ListPets", ctx, filter)
+	ret0, _ := ret[0].([]entities0.Pet)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
-// DeletePhotoUrlsByPetId indicates an expected call of DeletePhotoUrlsByPetId.
-func (mr *MockPetRepositoryMockRecorder) DeletePhotoUrlsByPetId(ctx, petId interface{}) *gomock.Call {
+// ListPets indicates an expected call of ListPets.
+func (mr *MockRepositoryMockRecorder) ListPets(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePhotoUrlsByPetId", reflect.TypeOf((*MockPetRepository)(nil).DeletePhotoUrlsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPets", reflect.TypeOf((*MockRepository)(nil).ListPets), ctx, filter)
 }
 
-// GetPetById mocks base method.
-func (m *MockPetRepository) GetPetById(ctx context.Context, petId int) (entities.Pet, error) {
+// ListSessions mocks base method.
+func (m *MockRepository) ListSessions(ctx context.Context, username, currentTokenId string) ([]entities.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetById", ctx, petId)
-	ret0, _ := ret[0].(entities.Pet)
+	ret := m.ctrl.Call(m, "ListSessions", ctx, username, currentTokenId)
+	ret0, _ := ret[0].([]entities.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetById indicates an expected call of GetPetById.
-func (mr *MockPetRepositoryMockRecorder) GetPetById(ctx, petId interface{}) *gomock.Call {
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockRepositoryMockRecorder) ListSessions(ctx, username, currentTokenId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetById", reflect.TypeOf((*MockPetRepository)(nil).GetPetById), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockRepository)(nil).ListSessions), ctx, username, currentTokenId)
 }
 
-// GetPetCategoryById mocks base method.
-func (m *MockPetRepository) GetPetCategoryById(ctx context.Context, categoryId int) (entities.Category, error) {
+// LockLogin mocks base method.
+func (m *MockRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetCategoryById", ctx, categoryId)
-	ret0, _ := ret[0].(entities.Category)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "LockLogin", ctx, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetPetCategoryById indicates an expected call of GetPetCategoryById.
-func (mr *MockPetRepositoryMockRecorder) GetPetCategoryById(ctx, categoryId interface{}) *gomock.Call {
+// LockLogin indicates an expected call of LockLogin.
+func (mr *MockRepositoryMockRecorder) LockLogin(ctx, key, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryById", reflect.TypeOf((*MockPetRepository)(nil).GetPetCategoryById), ctx, categoryId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLogin", reflect.TypeOf((*MockRepository)(nil).LockLogin), ctx, key, until)
 }
 
-// GetPetCategoryByName mocks base method.
-func (m *MockPetRepository) GetPetCategoryByName(ctx context.Context, categoryName string) (entities.Category, error) {
+// RecordLoginFailure mocks base method.
+func (m *MockRepository) RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (entities.LoginAttempts, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetCategoryByName", ctx, categoryName)
-	ret0, _ := ret[0].(entities.Category)
+	ret := m.ctrl.Call(m, "RecordLoginFailure", ctx, key, at, windowStart)
+	ret0, _ := ret[0].(entities.LoginAttempts)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetCategoryByName indicates an expected call of GetPetCategoryByName.
-func (mr *MockPetRepositoryMockRecorder) GetPetCategoryByName(ctx, categoryName interface{}) *gomock.Call {
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockRepositoryMockRecorder) RecordLoginFailure(ctx, key, at, windowStart interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryByName", reflect.TypeOf((*MockPetRepository)(nil).GetPetCategoryByName), ctx, categoryName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockRepository)(nil).RecordLoginFailure), ctx, key, at, windowStart)
 }
 
-// GetPetTagPair mocks base method.
-func (m *MockPetRepository) GetPetTagPair(ctx context.Context, petId, tagId int) (entities.PetTag, error) {
+// ResetLoginAttempts mocks base method.
+func (m *MockRepository) ResetLoginAttempts(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetTagPair", ctx, petId, tagId)
-	ret0, _ := ret[0].(entities.PetTag)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetPetTagPair indicates an expected call of GetPetTagPair.
-func (mr *MockPetRepositoryMockRecorder) GetPetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockRepositoryMockRecorder) ResetLoginAttempts(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPair", reflect.TypeOf((*MockPetRepository)(nil).GetPetTagPair), ctx, petId, tagId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockRepository)(nil).ResetLoginAttempts), ctx, key)
 }
 
-// GetPetTagPairsByPetId mocks base method.
-func (m *MockPetRepository) GetPetTagPairsByPetId(ctx context.Context, petId int) ([]entities.PetTag, error) {
+// RevokeAPIKey mocks base method.
+func (m *MockRepository) RevokeAPIKey(ctx context.Context, username string, keyId int) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetTagPairsByPetId", ctx, petId)
-	ret0, _ := ret[0].([]entities.PetTag)
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, username, keyId)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPetTagPairsByPetId indicates an expected call of GetPetTagPairsByPetId.
-func (mr *MockPetRepositoryMockRecorder) GetPetTagPairsByPetId(ctx, petId interface{}) *gomock.Call {
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockRepositoryMockRecorder) RevokeAPIKey(ctx, username, keyId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPairsByPetId", reflect.TypeOf((*MockPetRepository)(nil).GetPetTagPairsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockRepository)(nil).RevokeAPIKey), ctx, username, keyId)
 }
 
-// GetPetsByStatus mocks base method.
-func (m *MockPetRepository) GetPetsByStatus(ctx context.Context, petStatus string) ([]entities.Pet, error) {
+// RevokeAccessTokens mocks base method.
+func (m *MockRepository) RevokeAccessTokens(ctx context.Context, tokens []entities.RevokedToken) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPetsByStatus", ctx, petStatus)
-	ret0, _ := ret[0].([]entities.Pet)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "RevokeAccessTokens", ctx, tokens)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetPetsByStatus indicates an expected call of GetPetsByStatus.
-func (mr *MockPetRepositoryMockRecorder) GetPetsByStatus(ctx, petStatus interface{}) *gomock.Call {
+// RevokeAccessTokens indicates an expected call of RevokeAccessTokens.
+func (mr *MockRepositoryMockRecorder) RevokeAccessTokens(ctx, tokens interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByStatus", reflect.TypeOf((*MockPetRepository)(nil).GetPetsByStatus), ctx, petStatus)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessTokens", reflect.TypeOf((*MockRepository)(nil).RevokeAccessTokens), ctx, tokens)
 }
 
-// GetPhotoUrlsByPetId mocks base method.
-func (m *MockPetRepository) GetPhotoUrlsByPetId(ctx context.Context, petId int) ([]entities.PhotoUrl, error) {
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId string) ([]entities.RevokedToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPhotoUrlsByPetId", ctx, petId)
-	ret0, _ := ret[0].([]entities.PhotoUrl)
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", ctx, familyId)
+	ret0, _ := ret[0].([]entities.RevokedToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPhotoUrlsByPetId indicates an expected call of GetPhotoUrlsByPetId.
-func (mr *MockPetRepositoryMockRecorder) GetPhotoUrlsByPetId(ctx, petId interface{}) *gomock.Call {
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockRepositoryMockRecorder) RevokeRefreshTokenFamily(ctx, familyId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPhotoUrlsByPetId", reflect.TypeOf((*MockPetRepository)(nil).GetPhotoUrlsByPetId), ctx, petId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockRepository)(nil).RevokeRefreshTokenFamily), ctx, familyId)
 }
 
-// GetTagById mocks base method.
-func (m *MockPetRepository) GetTagById(ctx context.Context, tagId int) (entities.Tag, error) {
+// RevokeUserRefreshTokens mocks base method.
+func (m *MockRepository) RevokeUserRefreshTokens(ctx context.Context, username string) ([]entities.RevokedToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTagById", ctx, tagId)
-	ret0, _ := ret[0].(entities.Tag)
+	ret := m.ctrl.Call(m, "RevokeUserRefreshTokens", ctx, username)
+	ret0, _ := ret[0].([]entities.RevokedToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTagById indicates an expected call of GetTagById.
-func (mr *MockPetRepositoryMockRecorder) GetTagById(ctx, tagId interface{}) *gomock.Call {
+// RevokeUserRefreshTokens indicates an expected call of RevokeUserRefreshTokens.
+func (mr *MockRepositoryMockRecorder) RevokeUserRefreshTokens(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagById", reflect.TypeOf((*MockPetRepository)(nil).GetTagById), ctx, tagId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserRefreshTokens", reflect.TypeOf((*MockRepository)(nil).RevokeUserRefreshTokens), ctx, username)
 }
 
-// GetTagByName mocks base method.
-func (m *MockPetRepository) GetTagByName(ctx context.Context, tagName string) (entities.Tag, error) {
+// RevokeUserTokens mocks base method.
+func (m *MockRepository) RevokeUserTokens(ctx context.Context, userId int, purpose entities.TokenPurpose) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userId, purpose)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockRepositoryMockRecorder) RevokeUserTokens(ctx, userId, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockRepository)(nil).RevokeUserTokens), ctx, userId, purpose)
+}
+
+// SaveTwoFactor mocks base method.
+func (m *MockRepository) SaveTwoFactor(ctx context.Context, twoFactor entities.TwoFactor, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTwoFactor", ctx, twoFactor, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTwoFactor indicates an expected call of SaveTwoFactor.
+func (mr *MockRepositoryMockRecorder) SaveTwoFactor(ctx, twoFactor, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTwoFactor", reflect.TypeOf((*MockRepository)(nil).SaveTwoFactor), ctx, twoFactor, recoveryCodeHashes)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockRepository) TouchAPIKey(ctx context.Context, keyId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, keyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockRepositoryMockRecorder) TouchAPIKey(ctx, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockRepository)(nil).TouchAPIKey), ctx, keyId)
+}
+
+// UpdatePet mocks base method.
+func (m *MockRepository) UpdatePet(ctx context.Context, pet entities0.Pet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePet", ctx, pet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePet indicates an expected call of UpdatePet.
+func (mr *MockRepositoryMockRecorder) UpdatePet(ctx, pet interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePet", reflect.TypeOf((*MockRepository)(nil).UpdatePet), ctx, pet)
+}
+
+// UpdateUser mocks base method.
+func (m *MockRepository) UpdateUser(ctx context.Context, user entities2.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockRepositoryMockRecorder) UpdateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepository)(nil).UpdateUser), ctx, user)
+}
+
+// UpdateUserPassword mocks base method.
+func (m *MockRepository) UpdateUserPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, userId, oldHash, newHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockRepositoryMockRecorder) UpdateUserPassword(ctx, userId, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockRepository)(nil).UpdateUserPassword), ctx, userId, oldHash, newHash)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockRepositoryMockRecorder) UseRecoveryCode(ctx, userId, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockRepository)(nil).UseRecoveryCode), ctx, userId, codeHash)
+}
+
+// UseRefreshToken mocks base method.
+func (m *MockRepository) UseRefreshToken(ctx context.Context, tokenId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRefreshToken", ctx, tokenId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRefreshToken indicates an expected call of UseRefreshToken.
+func (mr *MockRepositoryMockRecorder) UseRefreshToken(ctx, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRefreshToken", reflect.TypeOf((*MockRepository)(nil).UseRefreshToken), ctx, tokenId)
+}
+
+// UseTwoFactorStep mocks base method.
+func (m *MockRepository) UseTwoFactorStep(ctx context.Context, userId int, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTwoFactorStep", ctx, userId, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTwoFactorStep indicates an expected call of UseTwoFactorStep.
+func (mr *MockRepositoryMockRecorder) UseTwoFactorStep(ctx, userId, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTwoFactorStep", reflect.TypeOf((*MockRepository)(nil).UseTwoFactorStep), ctx, userId, step)
+}
+
+// UseUserToken mocks base method.
+func (m *MockRepository) UseUserToken(ctx context.Context, tokenId string, purpose entities.TokenPurpose) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserToken", ctx, tokenId, purpose)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseUserToken indicates an expected call of UseUserToken.
+func (mr *MockRepositoryMockRecorder) UseUserToken(ctx, tokenId, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserToken", reflect.TypeOf((*MockRepository)(nil).UseUserToken), ctx, tokenId, purpose)
+}
+
+// VerifyUserEmail mocks base method.
+func (m *MockRepository) VerifyUserEmail(ctx context.Context, userId int, email string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserEmail", ctx, userId, email)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyUserEmail indicates an expected call of VerifyUserEmail.
+func (mr *MockRepositoryMockRecorder) VerifyUserEmail(ctx, userId, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserEmail", reflect.TypeOf((*MockRepository)(nil).VerifyUserEmail), ctx, userId, email)
+}
+
+// WithTx mocks base method.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repository.Repository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepository)(nil).WithTx), ctx, fn)
+}
+
+// MockPetRepository is a mock of PetRepository interface.
+type MockPetRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPetRepositoryMockRecorder
+}
+
+// MockPetRepositoryMockRecorder is the mock recorder for MockPetRepository.
+type MockPetRepositoryMockRecorder struct {
+	mock *MockPetRepository
+}
+
+// NewMockPetRepository creates a new mock instance.
+func NewMockPetRepository(ctrl *gomock.Controller) *MockPetRepository {
+	mock := &MockPetRepository{ctrl: ctrl}
+	mock.recorder = &MockPetRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPetRepository) EXPECT() *MockPetRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePet mocks base method.
+func (m *MockPetRepository) CreatePet(ctx context.Context, categoryId int, petName, petStatus, createdBy string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePet", ctx, categoryId, petName, petStatus, createdBy)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePet indicates an expected call of CreatePet.
+func (mr *MockPetRepositoryMockRecorder) CreatePet(ctx, categoryId, petName, petStatus, createdBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePet", reflect.TypeOf((*MockPetRepository)(nil).CreatePet), ctx, categoryId, petName, petStatus, createdBy)
+}
+
+// CreatePetCategory mocks base method.
+func (m *MockPetRepository) CreatePetCategory(ctx context.Context, categoryName string) (entities0.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePetCategory", ctx, categoryName)
+	ret0, _ := ret[0].(entities0.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePetCategory indicates an expected call of CreatePetCategory.
+func (mr *MockPetRepositoryMockRecorder) CreatePetCategory(ctx, categoryName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetCategory", reflect.TypeOf((*MockPetRepository)(nil).CreatePetCategory), ctx, categoryName)
+}
+
+// CreatePetPhotoUrl mocks base method.
+func (m *MockPetRepository) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePetPhotoUrl", ctx, petId, photoUrl)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePetPhotoUrl indicates an expected call of CreatePetPhotoUrl.
+func (mr *MockPetRepositoryMockRecorder) CreatePetPhotoUrl(ctx, petId, photoUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetPhotoUrl", reflect.TypeOf((*MockPetRepository)(nil).CreatePetPhotoUrl), ctx, petId, photoUrl)
+}
+
+// CreatePetTagPair mocks base method.
+func (m *MockPetRepository) CreatePetTagPair(ctx context.Context, petId, tagId int) (entities0.PetTag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePetTagPair", ctx, petId, tagId)
+	ret0, _ := ret[0].(entities0.PetTag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePetTagPair indicates an expected call of CreatePetTagPair.
+func (mr *MockPetRepositoryMockRecorder) CreatePetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePetTagPair", reflect.TypeOf((*MockPetRepository)(nil).CreatePetTagPair), ctx, petId, tagId)
+}
+
+// CreatePhotoThumbnail mocks base method.
+func (m *MockPetRepository) CreatePhotoThumbnail(ctx context.Context, thumbnail entities0.Thumbnail) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePhotoThumbnail", ctx, thumbnail)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePhotoThumbnail indicates an expected call of CreatePhotoThumbnail.
+func (mr *MockPetRepositoryMockRecorder) CreatePhotoThumbnail(ctx, thumbnail interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePhotoThumbnail", reflect.TypeOf((*MockPetRepository)(nil).CreatePhotoThumbnail), ctx, thumbnail)
+}
+
+// CreateTag mocks base method.
+func (m *MockPetRepository) CreateTag(ctx context.Context, tagName string) (entities0.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTag", ctx, tagName)
+	ret0, _ := ret[0].(entities0.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTag indicates an expected call of CreateTag.
+func (mr *MockPetRepositoryMockRecorder) CreateTag(ctx, tagName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTag", reflect.TypeOf((*MockPetRepository)(nil).CreateTag), ctx, tagName)
+}
+
+// DeletePet mocks base method.
+func (m *MockPetRepository) DeletePet(ctx context.Context, petId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePet", ctx, petId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePet indicates an expected call of DeletePet.
+func (mr *MockPetRepositoryMockRecorder) DeletePet(ctx, petId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePet", reflect.TypeOf((*MockPetRepository)(nil).DeletePet), ctx, petId)
+}
+
+// DeletePetTagsByPetId mocks base method.
+func (m *MockPetRepository) DeletePetTagsByPetId(ctx context.Context, petId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePetTagsByPetId", ctx, petId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePetTagsByPetId indicates an expected call of DeletePetTagsByPetId.
+func (mr *MockPetRepositoryMockRecorder) DeletePetTagsByPetId(ctx, petId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePetTagsByPetId", reflect.TypeOf((*MockPetRepository)(nil).DeletePetTagsByPetId), ctx, petId)
+}
+
+// DeletePhotoUrlsByPetId mocks base method.
+func (m *MockPetRepository) DeletePhotoUrlsByPetId(ctx context.Context, petId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePhotoUrlsByPetId", ctx, petId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePhotoUrlsByPetId indicates an expected call of DeletePhotoUrlsByPetId.
+func (mr *MockPetRepositoryMockRecorder) DeletePhotoUrlsByPetId(ctx, petId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePhotoUrlsByPetId", reflect.TypeOf((*MockPetRepository)(nil).DeletePhotoUrlsByPetId), ctx, petId)
+}
+
+// GetPetById mocks base method.
+func (m *MockPetRepository) GetPetById(ctx context.Context, petId int) (entities0.Pet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPetById", ctx, petId)
+	ret0, _ := ret[0].(entities0.Pet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPetById indicates an expected call of GetPetById.
+func (mr *MockPetRepositoryMockRecorder) GetPetById(ctx, petId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetById", reflect.TypeOf((*MockPetRepository)(nil).GetPetById), ctx, petId)
+}
+
+// GetPetCategoryByName mocks base method.
+func (m *MockPetRepository) GetPetCategoryByName(ctx context.Context, categoryName string) (entities0.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPetCategoryByName", ctx, categoryName)
+	ret0, _ := ret[0].(entities0.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPetCategoryByName indicates an expected call of GetPetCategoryByName.
+func (mr *MockPetRepositoryMockRecorder) GetPetCategoryByName(ctx, categoryName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetCategoryByName", reflect.TypeOf((*MockPetRepository)(nil).GetPetCategoryByName), ctx, categoryName)
+}
+
+// GetPetTagPair mocks base method.
+func (m *MockPetRepository) GetPetTagPair(ctx context.Context, petId, tagId int) (entities0.PetTag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPetTagPair", ctx, petId, tagId)
+	ret0, _ := ret[0].(entities0.PetTag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPetTagPair indicates an expected call of GetPetTagPair.
+func (mr *MockPetRepositoryMockRecorder) GetPetTagPair(ctx, petId, tagId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetTagPair", reflect.TypeOf((*MockPetRepository)(nil).GetPetTagPair), ctx, petId, tagId)
+}
+
+// GetPetsByStatus mocks base method.
+func (m *MockPetRepository) GetPetsByStatus(ctx context.Context, petStatus string) ([]entities0.Pet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPetsByStatus", ctx, petStatus)
+	ret0, _ := ret[0].([]entities0.Pet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPetsByStatus indicates an expected call of GetPetsByStatus.
+func (mr *MockPetRepositoryMockRecorder) GetPetsByStatus(ctx, petStatus interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByStatus", reflect.TypeOf((*MockPetRepository)(nil).GetPetsByStatus), ctx, petStatus)
+}
+
+// GetPetsByTags mocks base method.
+func (m *MockPetRepository) GetPetsByTags(ctx context.Context, tagNames []string, matchAll bool) ([]entities0.Pet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPetsByTags", ctx, tagNames, matchAll)
+	ret0, _ := ret[0].([]entities0.Pet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPetsByTags indicates an expected call of GetPetsByTags.
+func (mr *MockPetRepositoryMockRecorder) GetPetsByTags(ctx, tagNames, matchAll interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPetsByTags", reflect.TypeOf((*MockPetRepository)(nil).GetPetsByTags), ctx, tagNames, matchAll)
+}
+
+// GetPhotoUrlsByPetIds mocks base method.
+func (m *MockPetRepository) GetPhotoUrlsByPetIds(ctx context.Context, petIds []int) (map[int][]entities0.PhotoUrl, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPhotoUrlsByPetIds", ctx, petIds)
+	ret0, _ := ret[0].(map[int][]entities0.PhotoUrl)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPhotoUrlsByPetIds indicates an expected call of GetPhotoUrlsByPetIds.
+func (mr *MockPetRepositoryMockRecorder) GetPhotoUrlsByPetIds(ctx, petIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPhotoUrlsByPetIds", reflect.TypeOf((*MockPetRepository)(nil).GetPhotoUrlsByPetIds), ctx, petIds)
+}
+
+// GetTagByName mocks base method.
+func (m *MockPetRepository) GetTagByName(ctx context.Context, tagName string) (entities0.Tag, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetTagByName", ctx, tagName)
-	ret0, _ := ret[0].(entities.Tag)
+	ret0, _ := ret[0].(entities0.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTagByName indicates an expected call of GetTagByName.
+func (mr *MockPetRepositoryMockRecorder) GetTagByName(ctx, tagName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagByName", reflect.TypeOf((*MockPetRepository)(nil).GetTagByName), ctx, tagName)
+}
+
+// GetTagsByPetIds mocks base method.
+func (m *MockPetRepository) GetTagsByPetIds(ctx context.Context, petIds []int) (map[int][]entities0.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTagsByPetIds", ctx, petIds)
+	ret0, _ := ret[0].(map[int][]entities0.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTagsByPetIds indicates an expected call of GetTagsByPetIds.
+func (mr *MockPetRepositoryMockRecorder) GetTagsByPetIds(ctx, petIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagsByPetIds", reflect.TypeOf((*MockPetRepository)(nil).GetTagsByPetIds), ctx, petIds)
+}
+
+// ListPets mocks base method.
+func (m *MockPetRepository) ListPets(ctx context.Context, filter entities0.PetFilter) ([]entities0.Pet, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPets", ctx, filter)
+	ret0, _ := ret[0].([]entities0.Pet)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListPets indicates an expected call of ListPets.
+func (mr *MockPetRepositoryMockRecorder) ListPets(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPets", reflect.TypeOf((*MockPetRepository)(nil).ListPets), ctx, filter)
+}
+
+// UpdatePet mocks base method.
+func (m *MockPetRepository) UpdatePet(ctx context.Context, pet entities0.Pet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePet", ctx, pet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePet indicates an expected call of UpdatePet.
+func (mr *MockPetRepositoryMockRecorder) UpdatePet(ctx, pet interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePet", reflect.TypeOf((*MockPetRepository)(nil).UpdatePet), ctx, pet)
+}
+
+// MockStoreRepository is a mock of StoreRepository interface.
+type MockStoreRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreRepositoryMockRecorder
+}
+
+// MockStoreRepositoryMockRecorder is the mock recorder for MockStoreRepository.
+type MockStoreRepositoryMockRecorder struct {
+	mock *MockStoreRepository
+}
+
+// NewMockStoreRepository creates a new mock instance.
+func NewMockStoreRepository(ctrl *gomock.Controller) *MockStoreRepository {
+	mock := &MockStoreRepository{ctrl: ctrl}
+	mock.recorder = &MockStoreRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStoreRepository) EXPECT() *MockStoreRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOrder mocks base method.
+func (m *MockStoreRepository) CreateOrder(ctx context.Context, order entities1.Order) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrder", ctx, order)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrder indicates an expected call of CreateOrder.
+func (mr *MockStoreRepositoryMockRecorder) CreateOrder(ctx, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockStoreRepository)(nil).CreateOrder), ctx, order)
+}
+
+// DeleteOrder mocks base method.
+func (m *MockStoreRepository) DeleteOrder(ctx context.Context, orderId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrder", ctx, orderId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOrder indicates an expected call of DeleteOrder.
+func (mr *MockStoreRepositoryMockRecorder) DeleteOrder(ctx, orderId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrder", reflect.TypeOf((*MockStoreRepository)(nil).DeleteOrder), ctx, orderId)
+}
+
+// GetInventory mocks base method.
+func (m *MockStoreRepository) GetInventory(ctx context.Context) ([]entities1.InventoryItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInventory", ctx)
+	ret0, _ := ret[0].([]entities1.InventoryItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInventory indicates an expected call of GetInventory.
+func (mr *MockStoreRepositoryMockRecorder) GetInventory(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventory", reflect.TypeOf((*MockStoreRepository)(nil).GetInventory), ctx)
+}
+
+// GetOrderById mocks base method.
+func (m *MockStoreRepository) GetOrderById(ctx context.Context, orderId int) (entities1.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderById", ctx, orderId)
+	ret0, _ := ret[0].(entities1.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderById indicates an expected call of GetOrderById.
+func (mr *MockStoreRepositoryMockRecorder) GetOrderById(ctx, orderId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderById", reflect.TypeOf((*MockStoreRepository)(nil).GetOrderById), ctx, orderId)
+}
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountUsersByRole mocks base method.
+func (m *MockUserRepository) CountUsersByRole(ctx context.Context, role entities.Role) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsersByRole", ctx, role)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsersByRole indicates an expected call of CountUsersByRole.
+func (mr *MockUserRepositoryMockRecorder) CountUsersByRole(ctx, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsersByRole", reflect.TypeOf((*MockUserRepository)(nil).CountUsersByRole), ctx, role)
+}
+
+// CreateUser mocks base method.
+func (m *MockUserRepository) CreateUser(ctx context.Context, user entities2.User) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserRepositoryMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), ctx, user)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserRepository) DeleteUser(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserRepositoryMockRecorder) DeleteUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepository)(nil).DeleteUser), ctx, username)
+}
+
+// GetUserByIdentity mocks base method.
+func (m *MockUserRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (entities2.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", ctx, issuer, subject)
+	ret0, _ := ret[0].(entities2.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockUserRepositoryMockRecorder) GetUserByIdentity(ctx, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockUserRepository)(nil).GetUserByIdentity), ctx, issuer, subject)
+}
+
+// GetUserByUsername mocks base method.
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (entities2.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, username)
+	ret0, _ := ret[0].(entities2.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUsername indicates an expected call of GetUserByUsername.
+func (mr *MockUserRepositoryMockRecorder) GetUserByUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockUserRepository)(nil).GetUserByUsername), ctx, username)
+}
+
+// GetUsersByEmail mocks base method.
+func (m *MockUserRepository) GetUsersByEmail(ctx context.Context, email string) ([]entities2.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByEmail", ctx, email)
+	ret0, _ := ret[0].([]entities2.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTagByName indicates an expected call of GetTagByName.
-func (mr *MockPetRepositoryMockRecorder) GetTagByName(ctx, tagName interface{}) *gomock.Call {
+// GetUsersByEmail indicates an expected call of GetUsersByEmail.
+func (mr *MockUserRepositoryMockRecorder) GetUsersByEmail(ctx, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagByName", reflect.TypeOf((*MockPetRepository)(nil).GetTagByName), ctx, tagName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByEmail", reflect.TypeOf((*MockUserRepository)(nil).GetUsersByEmail), ctx, email)
 }
 
-// UpdatePet mocks base method.
-func (m *MockPetRepository) UpdatePet(ctx context.Context, pet entities.Pet) error {
+// LinkIdentity mocks base method.
+func (m *MockUserRepository) LinkIdentity(ctx context.Context, userId int, issuer, subject string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdatePet", ctx, pet)
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, userId, issuer, subject)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdatePet indicates an expected call of UpdatePet.
-func (mr *MockPetRepositoryMockRecorder) UpdatePet(ctx, pet interface{}) *gomock.Call {
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockUserRepositoryMockRecorder) LinkIdentity(ctx, userId, issuer, subject interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePet", reflect.TypeOf((*MockPetRepository)(nil).UpdatePet), ctx, pet)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockUserRepository)(nil).LinkIdentity), ctx, userId, issuer, subject)
 }
 
-// MockStoreRepository is a mock of StoreRepository interface.
-type MockStoreRepository struct {
+// UpdateUser mocks base method.
+func (m *MockUserRepository) UpdateUser(ctx context.Context, user entities2.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockUserRepositoryMockRecorder) UpdateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserRepository)(nil).UpdateUser), ctx, user)
+}
+
+// UpdateUserPassword mocks base method.
+func (m *MockUserRepository) UpdateUserPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, userId, oldHash, newHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserPassword(ctx, userId, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserPassword), ctx, userId, oldHash, newHash)
+}
+
+// VerifyUserEmail mocks base method.
+func (m *MockUserRepository) VerifyUserEmail(ctx context.Context, userId int, email string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserEmail", ctx, userId, email)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyUserEmail indicates an expected call of VerifyUserEmail.
+func (mr *MockUserRepositoryMockRecorder) VerifyUserEmail(ctx, userId, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserEmail", reflect.TypeOf((*MockUserRepository)(nil).VerifyUserEmail), ctx, userId, email)
+}
+
+// MockAuthRepository is a mock of AuthRepository interface.
+type MockAuthRepository struct {
 	ctrl     *gomock.Controller
-	recorder *MockStoreRepositoryMockRecorder
+	recorder *MockAuthRepositoryMockRecorder
 }
 
-// MockStoreRepositoryMockRecorder is the mock recorder for MockStoreRepository.
-type MockStoreRepositoryMockRecorder struct {
-	mock *MockStoreRepository
+// MockAuthRepositoryMockRecorder is the mock recorder for MockAuthRepository.
+type MockAuthRepositoryMockRecorder struct {
+	mock *MockAuthRepository
 }
 
-// NewMockStoreRepository creates a new mock instance.
-func NewMockStoreRepository(ctrl *gomock.Controller) *MockStoreRepository {
-	mock := &MockStoreRepository{ctrl: ctrl}
-	mock.recorder = &MockStoreRepositoryMockRecorder{mock}
+// NewMockAuthRepository creates a new mock instance.
+func NewMockAuthRepository(ctrl *gomock.Controller) *MockAuthRepository {
+	mock := &MockAuthRepository{ctrl: ctrl}
+	mock.recorder = &MockAuthRepositoryMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockStoreRepository) EXPECT() *MockStoreRepositoryMockRecorder {
+func (m *MockAuthRepository) EXPECT() *MockAuthRepositoryMockRecorder {
 	return m.recorder
 }
 
-// CreateOrder mocks base method.
-func (m *MockStoreRepository) CreateOrder(ctx context.Context, order entities0.Order) (int, error) {
+// CreateAPIKey mocks base method.
+func (m *MockAuthRepository) CreateAPIKey(ctx context.Context, key entities.APIKey) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrder", ctx, order)
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateOrder indicates an expected call of CreateOrder.
-func (mr *MockStoreRepositoryMockRecorder) CreateOrder(ctx, order interface{}) *gomock.Call {
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAuthRepositoryMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockStoreRepository)(nil).CreateOrder), ctx, order)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAuthRepository)(nil).CreateAPIKey), ctx, key)
 }
 
-// DeleteOrder mocks base method.
-func (m *MockStoreRepository) DeleteOrder(ctx context.Context, orderId int) error {
+// CreateRefreshToken mocks base method.
+func (m *MockAuthRepository) CreateRefreshToken(ctx context.Context, token entities.RefreshToken) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOrder", ctx, orderId)
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, token)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) CreateRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).CreateRefreshToken), ctx, token)
+}
+
+// CreateUserToken mocks base method.
+func (m *MockAuthRepository) CreateUserToken(ctx context.Context, token entities.UserToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserToken", ctx, token)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteOrder indicates an expected call of DeleteOrder.
-func (mr *MockStoreRepositoryMockRecorder) DeleteOrder(ctx, orderId interface{}) *gomock.Call {
+// CreateUserToken indicates an expected call of CreateUserToken.
+func (mr *MockAuthRepositoryMockRecorder) CreateUserToken(ctx, token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrder", reflect.TypeOf((*MockStoreRepository)(nil).DeleteOrder), ctx, orderId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserToken", reflect.TypeOf((*MockAuthRepository)(nil).CreateUserToken), ctx, token)
 }
 
-// GetOrderById mocks base method.
-func (m *MockStoreRepository) GetOrderById(ctx context.Context, orderId int) (entities0.Order, error) {
+// DeleteTwoFactor mocks base method.
+func (m *MockAuthRepository) DeleteTwoFactor(ctx context.Context, userId int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrderById", ctx, orderId)
-	ret0, _ := ret[0].(entities0.Order)
+	ret := m.ctrl.Call(m, "DeleteTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTwoFactor indicates an expected call of DeleteTwoFactor.
+func (mr *MockAuthRepositoryMockRecorder) DeleteTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTwoFactor", reflect.TypeOf((*MockAuthRepository)(nil).DeleteTwoFactor), ctx, userId)
+}
+
+// EnableTwoFactor mocks base method.
+func (m *MockAuthRepository) EnableTwoFactor(ctx context.Context, userId int, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTwoFactor", ctx, userId, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTwoFactor indicates an expected call of EnableTwoFactor.
+func (mr *MockAuthRepositoryMockRecorder) EnableTwoFactor(ctx, userId, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTwoFactor", reflect.TypeOf((*MockAuthRepository)(nil).EnableTwoFactor), ctx, userId, step)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockAuthRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(entities.APIKey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOrderById indicates an expected call of GetOrderById.
-func (mr *MockStoreRepositoryMockRecorder) GetOrderById(ctx, orderId interface{}) *gomock.Call {
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockAuthRepositoryMockRecorder) GetAPIKeyByHash(ctx, keyHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderById", reflect.TypeOf((*MockStoreRepository)(nil).GetOrderById), ctx, orderId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockAuthRepository)(nil).GetAPIKeyByHash), ctx, keyHash)
 }
 
-// MockUserRepository is a mock of UserRepository interface.
-type MockUserRepository struct {
-	ctrl     *gomock.Controller
-	recorder *MockUserRepositoryMockRecorder
+// GetClientById mocks base method.
+func (m *MockAuthRepository) GetClientById(ctx context.Context, clientId string) (entities.Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientById", ctx, clientId)
+	ret0, _ := ret[0].(entities.Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
-type MockUserRepositoryMockRecorder struct {
-	mock *MockUserRepository
+// GetClientById indicates an expected call of GetClientById.
+func (mr *MockAuthRepositoryMockRecorder) GetClientById(ctx, clientId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientById", reflect.TypeOf((*MockAuthRepository)(nil).GetClientById), ctx, clientId)
 }
 
-// NewMockUserRepository creates a new mock instance.
-func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
-	mock := &MockUserRepository{ctrl: ctrl}
-	mock.recorder = &MockUserRepositoryMockRecorder{mock}
-	return mock
+// GetLoginAttempts mocks base method.
+func (m *MockAuthRepository) GetLoginAttempts(ctx context.Context, keys []string) ([]entities.LoginAttempts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginAttempts", ctx, keys)
+	ret0, _ := ret[0].([]entities.LoginAttempts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
-	return m.recorder
+// GetLoginAttempts indicates an expected call of GetLoginAttempts.
+func (mr *MockAuthRepositoryMockRecorder) GetLoginAttempts(ctx, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempts", reflect.TypeOf((*MockAuthRepository)(nil).GetLoginAttempts), ctx, keys)
 }
 
-// CreateUser mocks base method.
-func (m *MockUserRepository) CreateUser(ctx context.Context, user entities1.User) (int, error) {
+// GetRefreshTokenByHash mocks base method.
+func (m *MockAuthRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(entities.RefreshToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserRepositoryMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockAuthRepositoryMockRecorder) GetRefreshTokenByHash(ctx, tokenHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockAuthRepository)(nil).GetRefreshTokenByHash), ctx, tokenHash)
 }
 
-// DeleteUser mocks base method.
-func (m *MockUserRepository) DeleteUser(ctx context.Context, username string) error {
+// GetRevokedAccessTokens mocks base method.
+func (m *MockAuthRepository) GetRevokedAccessTokens(ctx context.Context, since time.Time) ([]entities.RevokedToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", ctx, username)
+	ret := m.ctrl.Call(m, "GetRevokedAccessTokens", ctx, since)
+	ret0, _ := ret[0].([]entities.RevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevokedAccessTokens indicates an expected call of GetRevokedAccessTokens.
+func (mr *MockAuthRepositoryMockRecorder) GetRevokedAccessTokens(ctx, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokedAccessTokens", reflect.TypeOf((*MockAuthRepository)(nil).GetRevokedAccessTokens), ctx, since)
+}
+
+// GetTwoFactor mocks base method.
+func (m *MockAuthRepository) GetTwoFactor(ctx context.Context, userId int) (entities.TwoFactor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(entities.TwoFactor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTwoFactor indicates an expected call of GetTwoFactor.
+func (mr *MockAuthRepositoryMockRecorder) GetTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTwoFactor", reflect.TypeOf((*MockAuthRepository)(nil).GetTwoFactor), ctx, userId)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockAuthRepository) ListAPIKeys(ctx context.Context, username string) ([]entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, username)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAuthRepositoryMockRecorder) ListAPIKeys(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAuthRepository)(nil).ListAPIKeys), ctx, username)
+}
+
+// ListSessions mocks base method.
+func (m *MockAuthRepository) ListSessions(ctx context.Context, username, currentTokenId string) ([]entities.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, username, currentTokenId)
+	ret0, _ := ret[0].([]entities.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockAuthRepositoryMockRecorder) ListSessions(ctx, username, currentTokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockAuthRepository)(nil).ListSessions), ctx, username, currentTokenId)
+}
+
+// LockLogin mocks base method.
+func (m *MockAuthRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLogin", ctx, key, until)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserRepositoryMockRecorder) DeleteUser(ctx, username interface{}) *gomock.Call {
+// LockLogin indicates an expected call of LockLogin.
+func (mr *MockAuthRepositoryMockRecorder) LockLogin(ctx, key, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepository)(nil).DeleteUser), ctx, username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLogin", reflect.TypeOf((*MockAuthRepository)(nil).LockLogin), ctx, key, until)
 }
 
-// GetUserByUsername mocks base method.
-func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (entities1.User, error) {
+// RecordLoginFailure mocks base method.
+func (m *MockAuthRepository) RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (entities.LoginAttempts, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, username)
-	ret0, _ := ret[0].(entities1.User)
+	ret := m.ctrl.Call(m, "RecordLoginFailure", ctx, key, at, windowStart)
+	ret0, _ := ret[0].(entities.LoginAttempts)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByUsername indicates an expected call of GetUserByUsername.
-func (mr *MockUserRepositoryMockRecorder) GetUserByUsername(ctx, username interface{}) *gomock.Call {
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockAuthRepositoryMockRecorder) RecordLoginFailure(ctx, key, at, windowStart interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockUserRepository)(nil).GetUserByUsername), ctx, username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockAuthRepository)(nil).RecordLoginFailure), ctx, key, at, windowStart)
 }
 
-// UpdateUser mocks base method.
-func (m *MockUserRepository) UpdateUser(ctx context.Context, user entities1.User) error {
+// ResetLoginAttempts mocks base method.
+func (m *MockAuthRepository) ResetLoginAttempts(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", ctx, user)
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", ctx, key)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockUserRepositoryMockRecorder) UpdateUser(ctx, user interface{}) *gomock.Call {
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockAuthRepositoryMockRecorder) ResetLoginAttempts(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserRepository)(nil).UpdateUser), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockAuthRepository)(nil).ResetLoginAttempts), ctx, key)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAuthRepository) RevokeAPIKey(ctx context.Context, username string, keyId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, username, keyId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAuthRepositoryMockRecorder) RevokeAPIKey(ctx, username, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAuthRepository)(nil).RevokeAPIKey), ctx, username, keyId)
+}
+
+// RevokeAccessTokens mocks base method.
+func (m *MockAuthRepository) RevokeAccessTokens(ctx context.Context, tokens []entities.RevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessTokens", ctx, tokens)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessTokens indicates an expected call of RevokeAccessTokens.
+func (mr *MockAuthRepositoryMockRecorder) RevokeAccessTokens(ctx, tokens interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessTokens", reflect.TypeOf((*MockAuthRepository)(nil).RevokeAccessTokens), ctx, tokens)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId string) ([]entities.RevokedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", ctx, familyId)
+	ret0, _ := ret[0].([]entities.RevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockAuthRepositoryMockRecorder) RevokeRefreshTokenFamily(ctx, familyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockAuthRepository)(nil).RevokeRefreshTokenFamily), ctx, familyId)
+}
+
+// RevokeUserRefreshTokens mocks base method.
+func (m *MockAuthRepository) RevokeUserRefreshTokens(ctx context.Context, username string) ([]entities.RevokedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserRefreshTokens", ctx, username)
+	ret0, _ := ret[0].([]entities.RevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserRefreshTokens indicates an expected call of RevokeUserRefreshTokens.
+func (mr *MockAuthRepositoryMockRecorder) RevokeUserRefreshTokens(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserRefreshTokens", reflect.TypeOf((*MockAuthRepository)(nil).RevokeUserRefreshTokens), ctx, username)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockAuthRepository) RevokeUserTokens(ctx context.Context, userId int, purpose entities.TokenPurpose) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userId, purpose)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockAuthRepositoryMockRecorder) RevokeUserTokens(ctx, userId, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockAuthRepository)(nil).RevokeUserTokens), ctx, userId, purpose)
+}
+
+// SaveTwoFactor mocks base method.
+func (m *MockAuthRepository) SaveTwoFactor(ctx context.Context, twoFactor entities.TwoFactor, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTwoFactor", ctx, twoFactor, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTwoFactor indicates an expected call of SaveTwoFactor.
+func (mr *MockAuthRepositoryMockRecorder) SaveTwoFactor(ctx, twoFactor, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTwoFactor", reflect.TypeOf((*MockAuthRepository)(nil).SaveTwoFactor), ctx, twoFactor, recoveryCodeHashes)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockAuthRepository) TouchAPIKey(ctx context.Context, keyId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, keyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockAuthRepositoryMockRecorder) TouchAPIKey(ctx, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockAuthRepository)(nil).TouchAPIKey), ctx, keyId)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockAuthRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockAuthRepositoryMockRecorder) UseRecoveryCode(ctx, userId, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockAuthRepository)(nil).UseRecoveryCode), ctx, userId, codeHash)
+}
+
+// UseRefreshToken mocks base method.
+func (m *MockAuthRepository) UseRefreshToken(ctx context.Context, tokenId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRefreshToken", ctx, tokenId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRefreshToken indicates an expected call of UseRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) UseRefreshToken(ctx, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).UseRefreshToken), ctx, tokenId)
+}
+
+// UseTwoFactorStep mocks base method.
+func (m *MockAuthRepository) UseTwoFactorStep(ctx context.Context, userId int, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTwoFactorStep", ctx, userId, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTwoFactorStep indicates an expected call of UseTwoFactorStep.
+func (mr *MockAuthRepositoryMockRecorder) UseTwoFactorStep(ctx, userId, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTwoFactorStep", reflect.TypeOf((*MockAuthRepository)(nil).UseTwoFactorStep), ctx, userId, step)
+}
+
+// UseUserToken mocks base method.
+func (m *MockAuthRepository) UseUserToken(ctx context.Context, tokenId string, purpose entities.TokenPurpose) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserToken", ctx, tokenId, purpose)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseUserToken indicates an expected call of UseUserToken.
+func (mr *MockAuthRepositoryMockRecorder) UseUserToken(ctx, tokenId, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserToken", reflect.TypeOf((*MockAuthRepository)(nil).UseUserToken), ctx, tokenId, purpose)
 }
@@ -64,6 +64,36 @@ func (mr *MockStoreServicerMockRecorder) DeleteOrder(ctx, orderId interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrder", reflect.TypeOf((*MockStoreServicer)(nil).DeleteOrder), ctx, orderId)
 }
 
+// GetInventory mocks base method.
+func (m *MockStoreServicer) GetInventory(ctx context.Context) (entities.Inventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInventory", ctx)
+	ret0, _ := ret[0].(entities.Inventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInventory indicates an expected call of GetInventory.
+func (mr *MockStoreServicerMockRecorder) GetInventory(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventory", reflect.TypeOf((*MockStoreServicer)(nil).GetInventory), ctx)
+}
+
+// GetInventoryByCategory mocks base method.
+func (m *MockStoreServicer) GetInventoryByCategory(ctx context.Context) (entities.CategoryInventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInventoryByCategory", ctx)
+	ret0, _ := ret[0].(entities.CategoryInventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInventoryByCategory indicates an expected call of GetInventoryByCategory.
+func (mr *MockStoreServicerMockRecorder) GetInventoryByCategory(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventoryByCategory", reflect.TypeOf((*MockStoreServicer)(nil).GetInventoryByCategory), ctx)
+}
+
 // GetOrderById mocks base method.
 func (m *MockStoreServicer) GetOrderById(ctx context.Context, orderId int) (entities.Order, error) {
 	m.ctrl.T.Helper()
@@ -5,7 +5,8 @@
 package mock_service
 
 import (
-	entities "backend/internal/modules/user/entities"
+	entities "backend/internal/modules/auth/entities"
+	entities0 "backend/internal/modules/user/entities"
 	context "context"
 	http "net/http"
 	reflect "reflect"
@@ -36,24 +37,99 @@ func (m *MockUserServicer) EXPECT() *MockUserServicerMockRecorder {
 	return m.recorder
 }
 
+// APIKeys mocks base method.
+func (m *MockUserServicer) APIKeys(ctx context.Context, username string) ([]entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "APIKeys", ctx, username)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// APIKeys indicates an expected call of APIKeys.
+func (mr *MockUserServicerMockRecorder) APIKeys(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIKeys", reflect.TypeOf((*MockUserServicer)(nil).APIKeys), ctx, username)
+}
+
 // Authorize mocks base method.
-func (m *MockUserServicer) Authorize(ctx context.Context, username, password string) (string, *http.Cookie, error) {
+func (m *MockUserServicer) Authorize(ctx context.Context, username, password, clientIP string) (entities.TokensPair, *http.Cookie, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Authorize", ctx, username, password)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Authorize", ctx, username, password, clientIP)
+	ret0, _ := ret[0].(entities.TokensPair)
 	ret1, _ := ret[1].(*http.Cookie)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
 
 // Authorize indicates an expected call of Authorize.
-func (mr *MockUserServicerMockRecorder) Authorize(ctx, username, password interface{}) *gomock.Call {
+func (mr *MockUserServicerMockRecorder) Authorize(ctx, username, password, clientIP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockUserServicer)(nil).Authorize), ctx, username, password, clientIP)
+}
+
+// AuthorizeExternal mocks base method.
+func (m *MockUserServicer) AuthorizeExternal(ctx context.Context, stateToken, state, code string) (entities.TokensPair, *http.Cookie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeExternal", ctx, stateToken, state, code)
+	ret0, _ := ret[0].(entities.TokensPair)
+	ret1, _ := ret[1].(*http.Cookie)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AuthorizeExternal indicates an expected call of AuthorizeExternal.
+func (mr *MockUserServicerMockRecorder) AuthorizeExternal(ctx, stateToken, state, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeExternal", reflect.TypeOf((*MockUserServicer)(nil).AuthorizeExternal), ctx, stateToken, state, code)
+}
+
+// AuthorizeTwoFactor mocks base method.
+func (m *MockUserServicer) AuthorizeTwoFactor(ctx context.Context, challengeToken, code, clientIP string) (entities.TokensPair, *http.Cookie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeTwoFactor", ctx, challengeToken, code, clientIP)
+	ret0, _ := ret[0].(entities.TokensPair)
+	ret1, _ := ret[1].(*http.Cookie)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AuthorizeTwoFactor indicates an expected call of AuthorizeTwoFactor.
+func (mr *MockUserServicerMockRecorder) AuthorizeTwoFactor(ctx, challengeToken, code, clientIP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeTwoFactor", reflect.TypeOf((*MockUserServicer)(nil).AuthorizeTwoFactor), ctx, challengeToken, code, clientIP)
+}
+
+// BootstrapAdmin mocks base method.
+func (m *MockUserServicer) BootstrapAdmin(ctx context.Context, username, password, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BootstrapAdmin", ctx, username, password, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BootstrapAdmin indicates an expected call of BootstrapAdmin.
+func (mr *MockUserServicerMockRecorder) BootstrapAdmin(ctx, username, password, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockUserServicer)(nil).Authorize), ctx, username, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BootstrapAdmin", reflect.TypeOf((*MockUserServicer)(nil).BootstrapAdmin), ctx, username, password, email)
+}
+
+// ConfirmTwoFactor mocks base method.
+func (m *MockUserServicer) ConfirmTwoFactor(ctx context.Context, username, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTwoFactor", ctx, username, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTwoFactor indicates an expected call of ConfirmTwoFactor.
+func (mr *MockUserServicerMockRecorder) ConfirmTwoFactor(ctx, username, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTwoFactor", reflect.TypeOf((*MockUserServicer)(nil).ConfirmTwoFactor), ctx, username, code)
 }
 
 // Create mocks base method.
-func (m *MockUserServicer) Create(ctx context.Context, user entities.User) (int, error) {
+func (m *MockUserServicer) Create(ctx context.Context, user entities0.User) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, user)
 	ret0, _ := ret[0].(int)
@@ -67,6 +143,21 @@ func (mr *MockUserServicerMockRecorder) Create(ctx, user interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserServicer)(nil).Create), ctx, user)
 }
 
+// CreateAPIKey mocks base method.
+func (m *MockUserServicer) CreateAPIKey(ctx context.Context, username string, request entities.CreateAPIKeyRequest) (entities.NewAPIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, username, request)
+	ret0, _ := ret[0].(entities.NewAPIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockUserServicerMockRecorder) CreateAPIKey(ctx, username, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockUserServicer)(nil).CreateAPIKey), ctx, username, request)
+}
+
 // Delete mocks base method.
 func (m *MockUserServicer) Delete(ctx context.Context, username string) error {
 	m.ctrl.T.Helper()
@@ -81,11 +172,54 @@ func (mr *MockUserServicerMockRecorder) Delete(ctx, username interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserServicer)(nil).Delete), ctx, username)
 }
 
+// DisableTwoFactor mocks base method.
+func (m *MockUserServicer) DisableTwoFactor(ctx context.Context, username, code, clientIP string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactor", ctx, username, code, clientIP)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactor indicates an expected call of DisableTwoFactor.
+func (mr *MockUserServicerMockRecorder) DisableTwoFactor(ctx, username, code, clientIP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactor", reflect.TypeOf((*MockUserServicer)(nil).DisableTwoFactor), ctx, username, code, clientIP)
+}
+
+// EnrollTwoFactor mocks base method.
+func (m *MockUserServicer) EnrollTwoFactor(ctx context.Context, username string) (entities.TwoFactorEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTwoFactor", ctx, username)
+	ret0, _ := ret[0].(entities.TwoFactorEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTwoFactor indicates an expected call of EnrollTwoFactor.
+func (mr *MockUserServicerMockRecorder) EnrollTwoFactor(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTwoFactor", reflect.TypeOf((*MockUserServicer)(nil).EnrollTwoFactor), ctx, username)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockUserServicer) ForgotPassword(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockUserServicerMockRecorder) ForgotPassword(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockUserServicer)(nil).ForgotPassword), ctx, email)
+}
+
 // GetByName mocks base method.
-func (m *MockUserServicer) GetByName(ctx context.Context, name string) (entities.User, error) {
+func (m *MockUserServicer) GetByName(ctx context.Context, name string) (entities0.UserProfile, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetByName", ctx, name)
-	ret0, _ := ret[0].(entities.User)
+	ret0, _ := ret[0].(entities0.UserProfile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -96,6 +230,78 @@ func (mr *MockUserServicerMockRecorder) GetByName(ctx, name interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockUserServicer)(nil).GetByName), ctx, name)
 }
 
+// LoginStateFromCookie mocks base method.
+func (m *MockUserServicer) LoginStateFromCookie(r *http.Request) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginStateFromCookie", r)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// LoginStateFromCookie indicates an expected call of LoginStateFromCookie.
+func (mr *MockUserServicerMockRecorder) LoginStateFromCookie(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginStateFromCookie", reflect.TypeOf((*MockUserServicer)(nil).LoginStateFromCookie), r)
+}
+
+// Logout mocks base method.
+func (m *MockUserServicer) Logout(ctx context.Context, refreshToken string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, refreshToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUserServicerMockRecorder) Logout(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUserServicer)(nil).Logout), ctx, refreshToken)
+}
+
+// LogoutAll mocks base method.
+func (m *MockUserServicer) LogoutAll(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockUserServicerMockRecorder) LogoutAll(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockUserServicer)(nil).LogoutAll), ctx, username)
+}
+
+// Refresh mocks base method.
+func (m *MockUserServicer) Refresh(ctx context.Context, refreshToken string) (entities.TokensPair, *http.Cookie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(entities.TokensPair)
+	ret1, _ := ret[1].(*http.Cookie)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockUserServicerMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockUserServicer)(nil).Refresh), ctx, refreshToken)
+}
+
+// RefreshTokenFromCookie mocks base method.
+func (m *MockUserServicer) RefreshTokenFromCookie(r *http.Request) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokenFromCookie", r)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RefreshTokenFromCookie indicates an expected call of RefreshTokenFromCookie.
+func (mr *MockUserServicerMockRecorder) RefreshTokenFromCookie(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokenFromCookie", reflect.TypeOf((*MockUserServicer)(nil).RefreshTokenFromCookie), r)
+}
+
 // ResetCookie mocks base method.
 func (m *MockUserServicer) ResetCookie() *http.Cookie {
 	m.ctrl.T.Helper()
@@ -110,8 +316,110 @@ func (mr *MockUserServicerMockRecorder) ResetCookie() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetCookie", reflect.TypeOf((*MockUserServicer)(nil).ResetCookie))
 }
 
+// ResetLoginStateCookie mocks base method.
+func (m *MockUserServicer) ResetLoginStateCookie() *http.Cookie {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginStateCookie")
+	ret0, _ := ret[0].(*http.Cookie)
+	return ret0
+}
+
+// ResetLoginStateCookie indicates an expected call of ResetLoginStateCookie.
+func (mr *MockUserServicerMockRecorder) ResetLoginStateCookie() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginStateCookie", reflect.TypeOf((*MockUserServicer)(nil).ResetLoginStateCookie))
+}
+
+// ResetPassword mocks base method.
+func (m *MockUserServicer) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserServicerMockRecorder) ResetPassword(ctx, token, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserServicer)(nil).ResetPassword), ctx, token, password)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockUserServicer) RevokeAPIKey(ctx context.Context, username string, keyId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, username, keyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockUserServicerMockRecorder) RevokeAPIKey(ctx, username, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockUserServicer)(nil).RevokeAPIKey), ctx, username, keyId)
+}
+
+// Sessions mocks base method.
+func (m *MockUserServicer) Sessions(ctx context.Context, username, currentTokenId string) ([]entities.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sessions", ctx, username, currentTokenId)
+	ret0, _ := ret[0].([]entities.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sessions indicates an expected call of Sessions.
+func (mr *MockUserServicerMockRecorder) Sessions(ctx, username, currentTokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sessions", reflect.TypeOf((*MockUserServicer)(nil).Sessions), ctx, username, currentTokenId)
+}
+
+// StartExternalLogin mocks base method.
+func (m *MockUserServicer) StartExternalLogin(ctx context.Context) (string, *http.Cookie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartExternalLogin", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*http.Cookie)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// StartExternalLogin indicates an expected call of StartExternalLogin.
+func (mr *MockUserServicerMockRecorder) StartExternalLogin(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartExternalLogin", reflect.TypeOf((*MockUserServicer)(nil).StartExternalLogin), ctx)
+}
+
+// TwoFactorEnabled mocks base method.
+func (m *MockUserServicer) TwoFactorEnabled(ctx context.Context, userId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TwoFactorEnabled", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TwoFactorEnabled indicates an expected call of TwoFactorEnabled.
+func (mr *MockUserServicerMockRecorder) TwoFactorEnabled(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TwoFactorEnabled", reflect.TypeOf((*MockUserServicer)(nil).TwoFactorEnabled), ctx, userId)
+}
+
+// Unlock mocks base method.
+func (m *MockUserServicer) Unlock(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockUserServicerMockRecorder) Unlock(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockUserServicer)(nil).Unlock), ctx, username)
+}
+
 // Update mocks base method.
-func (m *MockUserServicer) Update(ctx context.Context, user entities.User) error {
+func (m *MockUserServicer) Update(ctx context.Context, user entities0.User) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Update", ctx, user)
 	ret0, _ := ret[0].(error)
@@ -123,3 +431,17 @@ func (mr *MockUserServicerMockRecorder) Update(ctx, user interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserServicer)(nil).Update), ctx, user)
 }
+
+// VerifyEmail mocks base method.
+func (m *MockUserServicer) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserServicerMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUserServicer)(nil).VerifyEmail), ctx, token)
+}
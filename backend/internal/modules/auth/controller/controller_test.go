@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/jwk"
+	"backend/internal/lib/rr"
+	mock_service "backend/internal/mocks/mock_auth_service"
+	"backend/internal/modules/auth/entities"
+	"context"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthControl_Token(t *testing.T) {
+	testCases := []struct {
+		name       string
+		form       url.Values
+		basicAuth  bool
+		wantStatus int
+		wantError  string
+	}{
+		{"basic auth", url.Values{"grant_type": {"client_credentials"}}, true, http.StatusOK, ""},
+		{"form credentials", url.Values{"grant_type": {"client_credentials"}, "client_id": {"inventory-sync"}, "client_secret": {"secret"}}, false, http.StatusOK, ""},
+		{"unsupported grant", url.Values{"grant_type": {"password"}}, true, http.StatusBadRequest, "unsupported_grant_type"},
+		{"invalid client", url.Values{"grant_type": {"client_credentials"}, "client_id": {"inventory-sync"}, "client_secret": {"password"}}, false, http.StatusUnauthorized, "invalid_client"},
+		{"invalid scope", url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}, true, http.StatusBadRequest, "invalid_scope"},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockAuthService(controller)
+	ac := NewAuthControl(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				req.SetBasicAuth("inventory-sync", "secret")
+			}
+			wr := httptest.NewRecorder()
+
+			ac.Token(wr, req)
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+			if r.Header.Get("Cache-Control") != "no-store" {
+				t.Errorf("want Cache-Control no-store, got %q", r.Header.Get("Cache-Control"))
+			}
+
+			if tc.wantError != "" {
+				var oauthErr entities.OAuthError
+				_ = json.NewDecoder(r.Body).Decode(&oauthErr)
+				if oauthErr.Error != tc.wantError {
+					t.Errorf("want error %s, got %s", tc.wantError, oauthErr.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthControl_JWKS(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockAuthService(controller)
+	ac := NewAuthControl(mockService, rr.NewReadRespond())
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	wr := httptest.NewRecorder()
+
+	ac.JWKS(wr, req)
+	r := wr.Result()
+
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("want status %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	if !strings.HasPrefix(r.Header.Get("Cache-Control"), "public") {
+		t.Errorf("want a cacheable response, got Cache-Control %q", r.Header.Get("Cache-Control"))
+	}
+
+	// the set is served as is, not wrapped in a response envelope
+	var set jwk.Set
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil || len(set.Keys) != 1 || set.Keys[0].KeyId != "2026-10" {
+		t.Errorf("want the key set, got %+v, %v", set, err)
+	}
+}
+
+func NewMockAuthService(controller *gomock.Controller) *mock_service.MockAuthServicer {
+	mockService := mock_service.NewMockAuthServicer(controller)
+
+	mockService.EXPECT().ClientCredentials(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, clientId, clientSecret, scope string) (entities.ClientToken, error) {
+		if clientId != "inventory-sync" || clientSecret != "secret" {
+			return entities.ClientToken{}, e.Unauthorized("invalid client")
+		}
+		if scope == "admin" {
+			return entities.ClientToken{}, e.Forbidden("scope admin is not allowed for the client")
+		}
+		return entities.ClientToken{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900, Scope: entities.ScopeReadPets}, nil
+	}).AnyTimes()
+
+	mockService.EXPECT().JWKS().Return(jwk.Set{Keys: []jwk.Key{{KeyType: "OKP", KeyId: "2026-10", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}}}).AnyTimes()
+
+	return mockService
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/jwk"
+	"backend/internal/lib/rr"
+	"backend/internal/modules/auth/entities"
+	"backend/internal/modules/auth/service"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// maxFormSize limits the token request body, it only carries a handful of short fields
+const maxFormSize = 1 << 10
+
+type AuthControl struct {
+	service service.AuthServicer
+	rr      rr.ReadResponder
+}
+
+func NewAuthControl(service service.AuthServicer, readResponder rr.ReadResponder) *AuthControl {
+	return &AuthControl{service: service, rr: readResponder}
+}
+
+// Token godoc
+// @Summary issue client token
+// @Description OAuth2 token endpoint for machine clients, only the client credentials grant is supported.
+// @Description The client authenticates with HTTP Basic or with the client_id and client_secret form fields.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Grant type" Enums(client_credentials)
+// @Param scope formData string false "Space separated scopes, every scope the client is allowed by default"
+// @Param client_id formData string false "Client id, if not sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, if not sent with HTTP Basic"
+// @Success 200 {object} entities.ClientToken
+// @Failure 400,401,500 {object} entities.OAuthError
+// @Router /oauth/token [post]
+func (c *AuthControl) Token(w http.ResponseWriter, r *http.Request) {
+	// tokens must not be cached, RFC 6749 section 5.1
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		c.writeError(w, http.StatusBadRequest, "invalid_request", "couldn't parse form")
+		return
+	}
+
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		c.writeError(w, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	token, err := c.service.ClientCredentials(r.Context(), clientId, clientSecret, r.PostForm.Get("scope"))
+	switch {
+	case errors.Is(err, e.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", `Basic realm="petstore"`)
+		c.writeError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	case errors.Is(err, e.ErrForbidden):
+		c.writeError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	case err != nil:
+		log.Println(err.Error())
+		c.writeError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	_ = c.rr.WriteJSON(w, 200, token)
+}
+
+// JWKS godoc
+// @Summary signing keys
+// @Description Public keys access tokens are signed with, as a JWK Set (RFC 7517). Keys are published
+// @Description before they start signing and until the tokens they signed have expired.
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} jwk.Set
+// @Router /.well-known/jwks.json [get]
+func (c *AuthControl) JWKS(w http.ResponseWriter, r *http.Request) {
+	// verifiers refetch the set on an unknown kid, a short max-age keeps rotation quick
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	var set jwk.Set = c.service.JWKS()
+	_ = c.rr.WriteJSON(w, 200, set)
+}
+
+func (c *AuthControl) writeError(w http.ResponseWriter, status int, code, description string) {
+	_ = c.rr.WriteJSON(w, status, entities.OAuthError{Error: code, ErrorDescription: description})
+}
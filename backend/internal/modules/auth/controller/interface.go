@@ -0,0 +1,8 @@
+package controller
+
+import "net/http"
+
+type AuthController interface {
+	Token(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
+}
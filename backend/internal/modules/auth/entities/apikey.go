@@ -0,0 +1,35 @@
+package entities
+
+import "time"
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot in logs and code
+const APIKeyPrefix = "psk_"
+
+// APIKey is a long-lived credential a user creates for scripts and jobs, sent in the
+// X-API-Key header. The key itself is only shown once, the server keeps its hash; the
+// prefix tells keys apart. A key grants its scopes only as long as the role of the owner does.
+type APIKey struct {
+	Id         int        `json:"id" example:"1"`
+	UserId     int        `json:"-"`
+	Username   string     `json:"-"` // of the owner, set when looked up by hash
+	Role       Role       `json:"-"` // current role of the owner, set when looked up by hash
+	Name       string     `json:"name" example:"inventory sync"`
+	Prefix     string     `json:"prefix" example:"psk_Xk3v9QbT"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" example:"read:pets"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" example:"inventory sync" binding:"required,max=64"`
+	Scopes        []string `json:"scopes" example:"read:pets" binding:"required,max=8"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty" example:"90" binding:"min=1,max=365"` // 90 by default
+}
+
+// NewAPIKey is shown once, when the key is created
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"psk_Xk3v9QbTz2Lq8WnR4yHc1MdF7sJpA0eGvUoK5tIbN6w"`
+}
@@ -1,12 +1,93 @@
 package entities
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
 
+// TokensPair is the result of a login. Users with two-factor authentication get a
+// ChallengeToken instead of the tokens, to be exchanged along with their code.
 type TokensPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken    string `json:"access_token,omitempty"`
+	RefreshToken   string `json:"refresh_token,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
 }
 
 type Claims struct {
 	jwt.RegisteredClaims
+	Type     string `json:"typ,omitempty"` // "JWT" for access tokens
+	UserId   int    `json:"uid,omitempty"`
+	Role     Role   `json:"role,omitempty"`
+	Scope    string `json:"scope,omitempty"`     // space separated OAuth2 scopes
+	ClientId string `json:"client_id,omitempty"` // set for tokens issued to machine clients
+	Email    string `json:"email,omitempty"`     // set for the tokens mailed to users
+	LoginState
+}
+
+// LoginState binds a login at an external OpenID provider to the browser it was started
+// in: the state and nonce sent to the provider and the PKCE verifier of the code
+type LoginState struct {
+	State    string `json:"state,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	Verifier string `json:"verifier,omitempty"`
+}
+
+// TokenPurpose is what a token mailed to a user can be used for, tokens of one purpose
+// are rejected for any other
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify-email"
+	PurposeResetPassword TokenPurpose = "reset-password"
+)
+
+// UserToken is the server side record of a token mailed to a user, it makes the token single-use
+type UserToken struct {
+	TokenId   string // jti of the token
+	UserId    int
+	Purpose   TokenPurpose
+	ExpiresAt time.Time
+}
+
+// RefreshToken is the server side record of an issued refresh token. Only the hash of
+// the token is stored; tokens rotated from the same login share a FamilyId.
+type RefreshToken struct {
+	Id            int
+	FamilyId      string
+	UserId        int
+	Username      string
+	Role          Role // current role of the owner, picked up by the tokens issued on refresh
+	TokenHash     string
+	AccessTokenId string // jti of the access token issued along with the refresh token
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+	UsedAt        time.Time // zero until the token is exchanged for a new pair
+	RevokedAt     time.Time // zero unless the token family was revoked
+}
+
+// RevokedToken is an access token that must be rejected until it expires
+type RevokedToken struct {
+	TokenId   string
+	ExpiresAt time.Time
+}
+
+// Session is a login along with every token rotated from it, i.e. a refresh token family
+type Session struct {
+	Id         string    `json:"id" example:"6f1c0a7e2b9d4e58a3c1f0b2d7e9a4c6"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"` // the session the request was made with
+}
+
+// LoginAttempts counts the recent failed logins of a username or of a client IP
+type LoginAttempts struct {
+	Key           string // "user:<username>" or "ip:<address>"
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time // zero unless the account is locked out
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"bXktcmVmcmVzaC10b2tlbg"`
 }
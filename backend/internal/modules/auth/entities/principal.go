@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"slices"
+	"strings"
+)
+
+// Principal is the authenticated caller of a request, built from its verified access token
+type Principal struct {
+	UserId   int    // zero for machine clients
+	Username string // the client id for machine clients
+	Roles    []Role
+	Scopes   []string
+	TokenId  string
+	ClientId string // set for machine clients only
+	APIKeyId int    // set when authenticated with an API key
+}
+
+// Principal returns the caller the claims were issued to
+func (c *Claims) Principal() Principal {
+	principal := Principal{
+		UserId:   c.UserId,
+		Username: c.Subject,
+		Scopes:   strings.Fields(c.Scope),
+		TokenId:  c.ID,
+		ClientId: c.ClientId,
+	}
+	if c.Role != "" {
+		principal.Roles = []Role{c.Role}
+	}
+	return principal
+}
+
+// IsClient reports whether the caller is a machine client rather than a user
+func (p Principal) IsClient() bool {
+	return p.ClientId != ""
+}
+
+// IsAPIKey reports whether the caller authenticated with an API key rather than a login
+func (p Principal) IsAPIKey() bool {
+	return p.APIKeyId != 0
+}
+
+// HasRole reports whether any role of the caller includes role
+func (p Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if r.Includes(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the caller was granted the OAuth2 scope
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Actor is the name the caller is recorded under in audit fields,
+// machine clients are prefixed so that they can't be mistaken for users
+func (p Principal) Actor() string {
+	if p.IsClient() {
+		return "client:" + p.ClientId
+	}
+	return p.Username
+}
@@ -0,0 +1,40 @@
+package entities
+
+// Role is the set of permissions a user has. Roles are ordered, every role includes
+// the permissions of the roles below it: customer < staff < admin.
+type Role string
+
+const (
+	RoleCustomer Role = "customer" // places orders and manages their own account
+	RoleStaff    Role = "staff"    // manages pets
+	RoleAdmin    Role = "admin"    // manages users
+)
+
+var roleRanks = map[Role]int{
+	RoleCustomer: 1,
+	RoleStaff:    2,
+	RoleAdmin:    3,
+}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r grants every permission of other. Unknown roles grant nothing.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+// Scopes returns the OAuth2 scopes granted to tokens of users with the role
+func (r Role) Scopes() []string {
+	switch {
+	case r.Includes(RoleStaff):
+		return []string{ScopeReadPets, ScopeWritePets}
+	case r.Includes(RoleCustomer):
+		return []string{ScopeReadPets}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package entities
+
+// OAuth2 scopes of the petstore_auth scheme of the reference Petstore API
+const (
+	ScopeReadPets  = "read:pets"
+	ScopeWritePets = "write:pets"
+)
+
+// Client is a machine client registered for the client credentials grant
+type Client struct {
+	Id         int
+	ClientId   string
+	Name       string
+	SecretHash string
+	Scopes     []string // scopes the client may request
+	Disabled   bool
+}
+
+// ClientToken is the access token response of the client credentials grant, RFC 6749 section 5.1
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"900"`
+	Scope       string `json:"scope" example:"read:pets write:pets"`
+}
+
+// OAuthError is the error response of the token endpoint, RFC 6749 section 5.2
+type OAuthError struct {
+	Error            string `json:"error" example:"invalid_client"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+// TwoFactor is the TOTP second factor of a user. It takes effect once the user confirms
+// the enrollment with a valid code, proving the authenticator app was set up.
+type TwoFactor struct {
+	UserId       int
+	Secret       string    // base32 encoded TOTP secret
+	EnabledAt    time.Time // zero while the enrollment isn't confirmed
+	LastUsedStep int64     // time step of the last accepted code, older codes can't be replayed
+}
+
+func (t TwoFactor) Enabled() bool {
+	return !t.EnabledAt.IsZero()
+}
+
+// TwoFactorEnrollment is shown once, when a user sets up two-factor authentication
+type TwoFactorEnrollment struct {
+	Secret        string   `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI           string   `json:"otpauth_uri" example:"otpauth://totp/Petstore:johndoe001?algorithm=SHA1&digits=6&issuer=Petstore&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	RecoveryCodes []string `json:"recovery_codes" example:"k7gq-2mxd-p4ta"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" example:"287082" binding:"required,max=32"`
+}
+
+// TwoFactorLogin is the second step of the login of users with two-factor authentication,
+// the code is either the current TOTP code or one of the recovery codes
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" example:"287082" binding:"required,max=32"`
+}
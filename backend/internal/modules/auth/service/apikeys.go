@@ -0,0 +1,85 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/modules/auth/entities"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+// apiKeyPrefixLength is the length of the prefix kept in clear to tell keys apart,
+// "psk_" and the first 8 random characters
+const apiKeyPrefixLength = len(entities.APIKeyPrefix) + 8
+
+// APIKeyRepository looks up the API keys of users, it is implemented by repository.Repository
+type APIKeyRepository interface {
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (entities.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyId int) error
+}
+
+// WithAPIKeyRepository enables authentication with the API keys in repo
+func WithAPIKeyRepository(repo APIKeyRepository) AuthServiceOption {
+	return func(a *AuthService) {
+		a.apiKeys = repo
+	}
+}
+
+// GenerateAPIKey creates a random API key. The returned record holds its prefix and hash;
+// the owner, name, scopes and expiry are up to the caller.
+func (a *AuthService) GenerateAPIKey() (string, entities.APIKey, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", entities.APIKey{}, err
+	}
+	key := entities.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	return key, entities.APIKey{
+		Prefix:  key[:apiKeyPrefixLength],
+		KeyHash: hashToken(key),
+	}, nil
+}
+
+// VerifyAPIKey returns the caller an API key was issued to. The key grants the scopes it
+// was created with that the role of the owner still grants, so a demoted user's keys lose
+// the scopes of the former role.
+func (a *AuthService) VerifyAPIKey(ctx context.Context, key string) (entities.Principal, error) {
+	if a.apiKeys == nil || len(key) <= apiKeyPrefixLength || key[:len(entities.APIKeyPrefix)] != entities.APIKeyPrefix {
+		return entities.Principal{}, e.Unauthorized("invalid API key")
+	}
+
+	apiKey, err := a.apiKeys.GetAPIKeyByHash(ctx, hashToken(key))
+	if errors.Is(err, e.ErrNotFound) {
+		return entities.Principal{}, e.Unauthorized("invalid API key")
+	} else if err != nil {
+		return entities.Principal{}, e.Internal("couldn't get API key", err)
+	}
+
+	if !time.Now().Before(apiKey.ExpiresAt) {
+		return entities.Principal{}, e.Unauthorized("API key is expired")
+	}
+
+	// last use is only informational, a failure to record it mustn't fail the request
+	if err = a.apiKeys.TouchAPIKey(ctx, apiKey.Id); err != nil {
+		log.Println("failed to record API key use:", err)
+	}
+
+	scopes := make([]string, 0, len(apiKey.Scopes))
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(apiKey.Role.Scopes(), scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return entities.Principal{
+		UserId:   apiKey.UserId,
+		Username: apiKey.Username,
+		Roles:    []entities.Role{apiKey.Role},
+		Scopes:   scopes,
+		APIKeyId: apiKey.Id,
+	}, nil
+}
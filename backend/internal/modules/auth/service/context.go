@@ -0,0 +1,20 @@
+package service
+
+import (
+	"backend/internal/modules/auth/entities"
+	"context"
+)
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated caller
+func NewContext(ctx context.Context, principal entities.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller stored by NewContext. It reports false for
+// requests that didn't go through the authentication middleware.
+func PrincipalFromContext(ctx context.Context) (entities.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(entities.Principal)
+	return principal, ok
+}
@@ -1,91 +1,347 @@
 package service
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/passwords"
 	"backend/internal/modules/auth/entities"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
 
+const (
+	accessTokenType    = "JWT"
+	challengeTokenType = "2fa-challenge"
+)
+
 type AuthService struct {
-	Issuer       string
-	Audience     string
-	Secret       string
-	TokenExpiry  time.Duration
-	CookieDomain string
-	CookiePath   string
-	CookieName   string
+	Issuer             string
+	Audience           string
+	Secret             string
+	TokenExpiry        time.Duration
+	RefreshTokenExpiry time.Duration
+	ChallengeExpiry    time.Duration
+	VerifyEmailExpiry  time.Duration
+	ResetExpiry        time.Duration
+	LoginStateExpiry   time.Duration // how long users have to log in at an external provider
+	Leeway             time.Duration // clock skew tolerated on exp, nbf and iat
+	CookiePath         string
+	CookieName         string
+	revocations        RevocationStore
+	clients            ClientRepository
+	apiKeys            APIKeyRepository
+	hasher             *passwords.Hasher
+	keys               []SigningKey
+}
+
+// ClientRepository looks up the machine clients of the client credentials grant,
+// it is implemented by repository.Repository
+type ClientRepository interface {
+	GetClientById(ctx context.Context, clientId string) (entities.Client, error)
+}
+
+type AuthServiceOption func(*AuthService)
+
+// WithRevocationStore sets the store revoked tokens are kept in, by default they are
+// only kept in memory
+func WithRevocationStore(store RevocationStore) AuthServiceOption {
+	return func(a *AuthService) {
+		a.revocations = store
+	}
 }
 
-func NewAuthService(issuer, audience, secret, cookieDomain string) *AuthService {
-	return &AuthService{
-		Issuer:       issuer,
-		Audience:     audience,
-		Secret:       secret,
-		TokenExpiry:  15 * time.Minute,
-		CookieDomain: cookieDomain,
-		CookiePath:   "/",
-		CookieName:   "__Host-refresh_token",
+// WithPasswordHasher sets the algorithm and parameters passwords are hashed with,
+// by default passwords.NewHasher
+func WithPasswordHasher(hasher *passwords.Hasher) AuthServiceOption {
+	return func(a *AuthService) {
+		a.hasher = hasher
 	}
 }
 
+// WithClientRepository enables the client credentials grant for the clients in repo
+func WithClientRepository(repo ClientRepository) AuthServiceOption {
+	return func(a *AuthService) {
+		a.clients = repo
+	}
+}
+
+func NewAuthService(issuer, audience, secret string, options ...AuthServiceOption) *AuthService {
+	a := &AuthService{
+		Issuer:             issuer,
+		Audience:           audience,
+		Secret:             secret,
+		TokenExpiry:        15 * time.Minute,
+		RefreshTokenExpiry: 7 * 24 * time.Hour,
+		ChallengeExpiry:    5 * time.Minute,
+		VerifyEmailExpiry:  24 * time.Hour,
+		ResetExpiry:        time.Hour,
+		LoginStateExpiry:   10 * time.Minute,
+		Leeway:             30 * time.Second,
+		CookiePath:         "/",
+		CookieName:         "__Host-refresh_token",
+		revocations:        NewRevocationStore(nil, 0),
+		hasher:             passwords.NewHasher(),
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	return a
+}
+
+// EncryptPassword hashes the password with the current algorithm and parameters,
+// the result is a PHC string carrying both
 func (a *AuthService) EncryptPassword(password string) (string, error) {
-	encrypted, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return a.hasher.Hash(password)
+}
+
+// VerifyPassword checks the password against a hash made with any supported algorithm and parameters
+func (a *AuthService) VerifyPassword(password string, encryptedPassword string) (bool, error) {
+	ok, _, err := a.hasher.Verify(password, encryptedPassword)
+	return ok, err
+}
+
+// NeedsRehash tells whether the hash was made with outdated parameters, in which case it
+// should be replaced once the password is known, i.e. on the next successful login
+func (a *AuthService) NeedsRehash(encryptedPassword string) bool {
+	return a.hasher.NeedsRehash(encryptedPassword)
+}
+
+// GenerateToken returns a signed access token along with its id, which is needed to revoke it.
+// The role of the user and the scopes it grants are carried in the token, so a role change
+// applies from the next refresh.
+func (a *AuthService) GenerateToken(userId int, username string, role entities.Role) (string, string, error) {
+	claims := jwt.MapClaims{
+		"name":  username,
+		"sub":   username,
+		"uid":   userId,
+		"role":  role,
+		"scope": strings.Join(role.Scopes(), " "),
+	}
+
+	return a.signToken(claims, accessTokenType, a.TokenExpiry)
+}
+
+// GenerateChallengeToken returns a short-lived token proving that the user passed the
+// password check, it is exchanged for the access token along with the second factor.
+// Challenge tokens grant no access by themselves, VerifyRequest rejects them.
+func (a *AuthService) GenerateChallengeToken(userId int, username string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": username,
+		"uid": userId,
+	}
+
+	token, _, err := a.signToken(claims, challengeTokenType, a.ChallengeExpiry)
+	return token, err
+}
+
+// VerifyChallengeToken returns the claims of a valid challenge token
+func (a *AuthService) VerifyChallengeToken(token string) (*entities.Claims, error) {
+	claims, err := a.parseToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(encrypted), nil
+
+	if claims.Type != challengeTokenType {
+		return nil, e.Unauthorized("invalid challenge token")
+	}
+
+	return claims, nil
 }
 
-func (a *AuthService) VerifyPassword(password string, encryptedPassword string) (bool, error) {
-	if err := bcrypt.CompareHashAndPassword([]byte(encryptedPassword), []byte(password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return false, nil
-		}
-		return false, err
+// GenerateUserToken returns a signed token to be mailed to the user along with its record,
+// which the caller has to store to make the token single-use. The token is bound to the
+// email it is sent to, so it can't be used once the user changes the email.
+func (a *AuthService) GenerateUserToken(purpose entities.TokenPurpose, userId int, username, email string) (string, entities.UserToken, error) {
+	expiry := a.VerifyEmailExpiry
+	if purpose == entities.PurposeResetPassword {
+		expiry = a.ResetExpiry
+	}
+
+	claims := jwt.MapClaims{
+		"sub":   username,
+		"uid":   userId,
+		"email": email,
 	}
-	return true, nil
+
+	token, tokenId, err := a.signToken(claims, string(purpose), expiry)
+	if err != nil {
+		return "", entities.UserToken{}, err
+	}
+
+	return token, entities.UserToken{
+		TokenId:   tokenId,
+		UserId:    userId,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().UTC().Add(expiry),
+	}, nil
 }
 
-func (a *AuthService) GenerateToken(username string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+// VerifyUserToken returns the claims of a valid token mailed to a user for purpose.
+// Whether the token was already used is up to the caller.
+func (a *AuthService) VerifyUserToken(purpose entities.TokenPurpose, token string) (*entities.Claims, error) {
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != string(purpose) {
+		return nil, e.Unauthorized("invalid token")
+	}
+
+	return claims, nil
+}
+
+// ClientCredentials implements the client credentials grant: it authenticates a machine
+// client and issues it an access token with the requested scopes, or with every scope the
+// client is allowed if scope is empty. No refresh token is issued, clients simply ask again.
+func (a *AuthService) ClientCredentials(ctx context.Context, clientId, clientSecret, scope string) (entities.ClientToken, error) {
+	if a.clients == nil || clientId == "" {
+		return entities.ClientToken{}, e.Unauthorized("invalid client")
+	}
+
+	client, err := a.clients.GetClientById(ctx, clientId)
+	if errors.Is(err, e.ErrNotFound) {
+		return entities.ClientToken{}, e.Unauthorized("invalid client")
+	} else if err != nil {
+		return entities.ClientToken{}, e.Wrap("couldn't get client", err)
+	}
+
+	if ok, _ := a.VerifyPassword(clientSecret, client.SecretHash); !ok || client.Disabled {
+		return entities.ClientToken{}, e.Unauthorized("invalid client")
+	}
+
+	scopes := client.Scopes
+	if scope != "" {
+		scopes = strings.Fields(scope)
+		for _, requested := range scopes {
+			if !slices.Contains(client.Scopes, requested) {
+				return entities.ClientToken{}, e.Forbidden(fmt.Sprintf("scope %s is not allowed for the client", requested))
+			}
+		}
+	}
+
+	claims := jwt.MapClaims{
+		"sub":       client.ClientId,
+		"client_id": client.ClientId,
+		"scope":     strings.Join(scopes, " "),
+	}
+
+	token, _, err := a.signToken(claims, accessTokenType, a.TokenExpiry)
+	if err != nil {
+		return entities.ClientToken{}, e.Internal("failed to generate token", err)
+	}
 
-	// set token claims
-	claims := token.Claims.(jwt.MapClaims)
+	return entities.ClientToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(a.TokenExpiry.Seconds()),
+		Scope:       claims["scope"].(string),
+	}, nil
+}
 
-	claims["name"] = username
-	claims["sub"] = username
+// signToken adds the claims every token has to claims and signs the token with the current
+// signing key, or with the secret if there is none
+func (a *AuthService) signToken(claims jwt.MapClaims, tokenType string, expiry time.Duration) (string, string, error) {
+	tokenId, err := randomString(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC()
+	claims["jti"] = tokenId
 	claims["aud"] = a.Audience
 	claims["iss"] = a.Issuer
-	claims["iat"] = time.Now().UTC().Unix()
-	claims["typ"] = "JWT"
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["typ"] = tokenType
 
 	// set the expiry time
-	claims["exp"] = time.Now().UTC().Add(a.TokenExpiry).Unix()
+	claims["exp"] = now.Add(expiry).Unix()
 
 	// create signed token
-	signedToken, err := token.SignedString([]byte(a.Secret))
+	var signedToken string
+	if key, ok := a.signingKey(now); ok {
+		token := jwt.NewWithClaims(key.method, claims)
+		token.Header["kid"] = key.Id
+		signedToken, err = token.SignedString(key.Signer)
+	} else if a.Secret != "" {
+		signedToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.Secret))
+	} else {
+		err = errors.New("no signing key is active")
+	}
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return signedToken, nil
+	return signedToken, tokenId, nil
+}
+
+// RevokeTokens makes VerifyRequest reject the given access tokens until they expire
+func (a *AuthService) RevokeTokens(ctx context.Context, tokens ...entities.RevokedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
 
+	if err := a.revocations.Revoke(ctx, tokens...); err != nil {
+		return e.Internal("failed to revoke tokens", err)
+	}
+
+	return nil
+}
+
+// GenerateRefreshToken creates a random opaque refresh token. The returned record holds
+// its hash and expiry time; the owner and token family are up to the caller.
+func (a *AuthService) GenerateRefreshToken() (string, entities.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", entities.RefreshToken{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	refreshToken := entities.RefreshToken{
+		TokenHash: a.HashRefreshToken(token),
+		ExpiresAt: time.Now().UTC().Add(a.RefreshTokenExpiry),
+	}
+
+	return token, refreshToken, nil
+}
+
+// HashRefreshToken returns the form a refresh token is stored in. Refresh tokens are long
+// random strings, so a fast hash is enough to keep them useless if the table leaks.
+func (a *AuthService) HashRefreshToken(token string) string {
+	return hashToken(token)
+}
+
+// RefreshTokenFromCookie returns the refresh token sent in the cookie, if any
+func (a *AuthService) RefreshTokenFromCookie(r *http.Request) string {
+	token, err := a.getCookie(r)
+	if err != nil {
+		return ""
+	}
+	return token
 }
 
-func (a *AuthService) CreateCookie(token string) *http.Cookie {
+// CreateCookie returns the cookie holding the refresh token. Its name has the __Host- prefix,
+// so browsers only take it host-only, without a Domain, and for every path.
+func (a *AuthService) CreateCookie(refreshToken string) *http.Cookie {
 	return &http.Cookie{
 		Name:     a.CookieName,
 		Path:     a.CookiePath,
-		Value:    token,
-		Expires:  time.Now().UTC().Add(a.TokenExpiry),
-		MaxAge:   int(a.TokenExpiry.Seconds()),
+		Value:    refreshToken,
+		Expires:  time.Now().UTC().Add(a.RefreshTokenExpiry),
+		MaxAge:   int(a.RefreshTokenExpiry.Seconds()),
 		SameSite: http.SameSiteLaxMode,
-		Domain:   a.CookieDomain,
 		HttpOnly: true,
 		Secure:   true,
 	}
@@ -99,52 +355,92 @@ func (a *AuthService) CreateExpiredCookie() *http.Cookie {
 		Expires:  time.Unix(0, 0),
 		MaxAge:   -1,
 		SameSite: http.SameSiteStrictMode,
-		Domain:   a.CookieDomain,
 		HttpOnly: true,
 		Secure:   true,
 	}
 }
 
 func (a *AuthService) VerifyRequest(w http.ResponseWriter, r *http.Request) (string, *entities.Claims, error) {
-	// try to get token from cookie
-	token, err := a.getCookie(r)
+	// access tokens are only accepted from the header, the cookie holds the refresh token
+	token, err := a.getTokenFromHeader(w, r)
 	if err != nil {
-		// check header if no cookie found
-		if token, err = a.getTokenFromHeader(w, r); err != nil {
-			return "", nil, err
-		}
+		return "", nil, err
 	}
 
-	// prepare function for token parsing
-	parseClaims := func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(a.Secret), nil
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// challenge tokens only prove the password, they must not grant access
+	if claims.Type != accessTokenType {
+		return "", nil, e.Unauthorized("invalid token type")
+	}
+
+	// check revocation, every token issued by the service has an id
+	if claims.ID == "" {
+		return "", nil, e.Unauthorized("missing token id")
+	}
+
+	revoked, err := a.revocations.IsRevoked(r.Context(), claims.ID)
+	if err != nil {
+		return "", nil, e.Internal("failed to check token revocation", err)
+	}
+	if revoked {
+		return "", nil, e.Unauthorized("token is revoked")
+	}
+
+	return token, claims, nil
+}
+
+// parseToken checks the signature, expiry, not before time, issuer and audience of token
+// and returns its claims
+func (a *AuthService) parseToken(token string) (*entities.Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(a.validMethods()),
+		jwt.WithLeeway(a.Leeway),
+		jwt.WithIssuedAt(),
+	}
+	if a.Audience != "" {
+		options = append(options, jwt.WithAudience(a.Audience))
 	}
 
 	// parse token into claims
 	claims := new(entities.Claims)
 
-	if _, err = jwt.ParseWithClaims(token, claims, parseClaims); err != nil {
+	if _, err := jwt.ParseWithClaims(token, claims, a.verificationKey, options...); err != nil {
 		if strings.HasPrefix(err.Error(), "token is expired by") {
-			return "", nil, errors.New("token is expired by")
+			return nil, e.Unauthorized("token is expired by")
 		}
-		return "", nil, err
+		return nil, &e.Error{Kind: e.ErrUnauthorized, Msg: "invalid token", Err: err}
 	}
 
 	// check issuer
 	if claims.Issuer != a.Issuer {
-		return "", nil, errors.New("invalid token issuer")
+		return nil, e.Unauthorized("invalid token issuer")
 	}
 
-	return token, claims, nil
+	return claims, nil
+}
+
+// hashToken returns the SHA-256 hash of a random token like a refresh token or an API key
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 func (a *AuthService) getCookie(r *http.Request) (string, error) {
 	cookie, err := r.Cookie(a.CookieName)
 	if err != nil || cookie.Value == "" {
-		return "", errors.New("invalid cookie")
+		return "", e.Unauthorized("invalid cookie")
 	}
 	return cookie.Value, nil
 }
@@ -155,18 +451,18 @@ func (a *AuthService) getTokenFromHeader(w http.ResponseWriter, r *http.Request)
 	// get authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return "", errors.New("missing authorization header")
+		return "", e.Unauthorized("missing authorization header")
 	}
 
 	// split the header on spaces
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 {
-		return "", errors.New("invalid authorization header")
+		return "", e.Unauthorized("invalid authorization header")
 	}
 
 	// check to see if we have the word "Bearer"
 	if parts[0] != "Bearer" {
-		return "", errors.New("invalid authorization header")
+		return "", e.Unauthorized("invalid authorization header")
 	}
 
 	token := parts[1]
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"backend/internal/lib/jwk"
 	"backend/internal/modules/auth/entities"
+	"context"
 	"net/http"
 )
 
@@ -10,8 +12,26 @@ import (
 type AuthServicer interface {
 	EncryptPassword(password string) (string, error)
 	VerifyPassword(password string, encryptedPassword string) (ok bool, err error)
-	GenerateToken(subject string) (string, error)
+	NeedsRehash(encryptedPassword string) bool
+	GenerateToken(userId int, subject string, role entities.Role) (token string, tokenId string, err error)
+	GenerateChallengeToken(userId int, subject string) (string, error)
+	VerifyChallengeToken(token string) (*entities.Claims, error)
+	GenerateUserToken(purpose entities.TokenPurpose, userId int, subject, email string) (string, entities.UserToken, error)
+	VerifyUserToken(purpose entities.TokenPurpose, token string) (*entities.Claims, error)
+	ClientCredentials(ctx context.Context, clientId, clientSecret, scope string) (entities.ClientToken, error)
+	RevokeTokens(ctx context.Context, tokens ...entities.RevokedToken) error
+	GenerateRefreshToken() (string, entities.RefreshToken, error)
+	HashRefreshToken(token string) string
+	GenerateAPIKey() (string, entities.APIKey, error)
+	VerifyAPIKey(ctx context.Context, key string) (entities.Principal, error)
+	GenerateLoginState(state entities.LoginState) (string, error)
+	VerifyLoginState(token string) (entities.LoginState, error)
+	LoginStateFromCookie(r *http.Request) string
+	CreateLoginStateCookie(token string) *http.Cookie
+	CreateExpiredLoginStateCookie() *http.Cookie
+	RefreshTokenFromCookie(r *http.Request) string
 	CreateCookie(refreshToken string) *http.Cookie
 	CreateExpiredCookie() *http.Cookie
 	VerifyRequest(w http.ResponseWriter, r *http.Request) (string, *entities.Claims, error)
+	JWKS() jwk.Set
 }
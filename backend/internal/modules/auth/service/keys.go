@@ -0,0 +1,206 @@
+package service
+
+import (
+	"backend/internal/lib/jwk"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SigningKey is an asymmetric key tokens are signed with, identified by the kid header.
+// Keys rotate by time: a key is published in the JWKS and accepted as soon as it is loaded,
+// signs from SignFrom until a key with a later SignFrom takes over, and is dropped at RetireAt.
+// Loading the next key well before its SignFrom gives verifiers caching the JWKS time to
+// pick it up, and keeping the previous key until every token it signed has expired keeps
+// those tokens valid, so RetireAt should be at least the longest token lifetime after the
+// next key's SignFrom.
+type SigningKey struct {
+	Id       string
+	Signer   crypto.Signer
+	SignFrom time.Time
+	RetireAt time.Time // zero if the key is never retired
+	method   jwt.SigningMethod
+}
+
+func NewSigningKey(id string, signer crypto.Signer, signFrom, retireAt time.Time) (SigningKey, error) {
+	if id == "" {
+		return SigningKey{}, errors.New("key id is required")
+	}
+
+	alg, err := jwk.Algorithm(signer.Public())
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	if !retireAt.IsZero() && !retireAt.After(signFrom) {
+		return SigningKey{}, fmt.Errorf("key %s is retired before it signs", id)
+	}
+
+	return SigningKey{
+		Id:       id,
+		Signer:   signer,
+		SignFrom: signFrom,
+		RetireAt: retireAt,
+		method:   jwt.GetSigningMethod(alg),
+	}, nil
+}
+
+// Algorithm returns the JWS algorithm of the key
+func (k SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k SigningKey) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// keyManifest lists the signing keys in a JSON file, e.g.
+//
+//	{"keys": [
+//	  {"kid": "2026-10", "file": "2026-10.pem", "signFrom": "2026-10-01T00:00:00Z", "retireAt": "2027-01-02T00:00:00Z"},
+//	  {"kid": "2027-01", "file": "2027-01.pem", "signFrom": "2027-01-01T00:00:00Z"}
+//	]}
+//
+// Relative paths are resolved against the directory of the manifest.
+type keyManifest struct {
+	Keys []struct {
+		Id       string    `json:"kid"`
+		File     string    `json:"file"`
+		SignFrom time.Time `json:"signFrom"`
+		RetireAt time.Time `json:"retireAt"`
+	} `json:"keys"`
+}
+
+// LoadSigningKeys reads the manifest at path and the PEM encoded private keys it lists
+func LoadSigningKeys(path string) ([]SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest keyManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid key manifest: %w", err)
+	}
+
+	keys := make([]SigningKey, 0, len(manifest.Keys))
+	seen := make(map[string]bool)
+	for _, entry := range manifest.Keys {
+		if seen[entry.Id] {
+			return nil, fmt.Errorf("duplicate key id %s", entry.Id)
+		}
+		seen[entry.Id] = true
+
+		file := entry.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", entry.Id, err)
+		}
+
+		signer, err := jwk.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", entry.Id, err)
+		}
+
+		key, err := NewSigningKey(entry.Id, signer, entry.SignFrom, entry.RetireAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// WithSigningKeys signs tokens with the asymmetric keys instead of the secret. Tokens signed
+// with the secret are still accepted as long as it is set, which allows moving over from it.
+func WithSigningKeys(keys ...SigningKey) AuthServiceOption {
+	return func(a *AuthService) {
+		a.keys = keys
+	}
+}
+
+// JWKS returns the public keys tokens are verified with, including keys that haven't
+// started signing yet, for other services to verify our tokens
+func (a *AuthService) JWKS() jwk.Set {
+	set := jwk.Set{Keys: []jwk.Key{}}
+
+	now := time.Now()
+	for _, key := range a.keys {
+		if key.retired(now) {
+			continue
+		}
+		public, err := jwk.FromPublicKey(key.Id, key.Signer.Public())
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, public)
+	}
+
+	return set
+}
+
+// signingKey returns the key with the latest SignFrom that has been reached
+func (a *AuthService) signingKey(now time.Time) (SigningKey, bool) {
+	var current SigningKey
+	var found bool
+
+	for _, key := range a.keys {
+		if key.retired(now) || now.Before(key.SignFrom) {
+			continue
+		}
+		if !found || key.SignFrom.After(current.SignFrom) {
+			current, found = key, true
+		}
+	}
+
+	return current, found
+}
+
+// verificationKey returns the key the signature of token is checked with. The key is chosen
+// by kid and must match the algorithm in the header, so a token can't pass an RSA public key
+// off as an HMAC secret.
+func (a *AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if a.Secret == "" {
+			return nil, errors.New("symmetric tokens are not accepted")
+		}
+		return []byte(a.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	now := time.Now()
+	for _, key := range a.keys {
+		if key.Id != kid || key.retired(now) {
+			continue
+		}
+		if key.method.Alg() != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.Signer.Public(), nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// validMethods returns the algorithms of the configured keys
+func (a *AuthService) validMethods() []string {
+	var methods []string
+	if a.Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range a.keys {
+		methods = append(methods, key.method.Alg())
+	}
+	return methods
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/modules/auth/entities"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"time"
+)
+
+const (
+	loginStateTokenType = "oidc-login"
+	// loginStateCookieName is host-only and on every path, the callback is under /user
+	loginStateCookieName = "__Host-oidc_login"
+)
+
+// GenerateLoginState returns a signed token carrying the state of a login at an external
+// provider, to be kept in the browser until the provider redirects back. The token is only
+// sent in an HttpOnly cookie, which keeps the PKCE verifier from scripts and other sites.
+func (a *AuthService) GenerateLoginState(state entities.LoginState) (string, error) {
+	claims := jwt.MapClaims{
+		"state":    state.State,
+		"nonce":    state.Nonce,
+		"verifier": state.Verifier,
+	}
+
+	token, _, err := a.signToken(claims, loginStateTokenType, a.LoginStateExpiry)
+	return token, err
+}
+
+// VerifyLoginState returns the login state in a valid token made by GenerateLoginState
+func (a *AuthService) VerifyLoginState(token string) (entities.LoginState, error) {
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return entities.LoginState{}, err
+	}
+
+	if claims.Type != loginStateTokenType || claims.State == "" || claims.Nonce == "" || claims.Verifier == "" {
+		return entities.LoginState{}, e.Unauthorized("invalid login state")
+	}
+
+	return claims.LoginState, nil
+}
+
+// LoginStateFromCookie returns the login state token sent in the cookie, if any
+func (a *AuthService) LoginStateFromCookie(r *http.Request) string {
+	cookie, err := r.Cookie(loginStateCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// CreateLoginStateCookie returns the cookie holding the login state token. It is sent along
+// with the redirect back from the provider, a cross-site top level navigation, so it has to
+// be SameSite=Lax.
+func (a *AuthService) CreateLoginStateCookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     loginStateCookieName,
+		Path:     "/",
+		Value:    token,
+		Expires:  time.Now().UTC().Add(a.LoginStateExpiry),
+		MaxAge:   int(a.LoginStateExpiry.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+		HttpOnly: true,
+		Secure:   true,
+	}
+}
+
+// CreateExpiredLoginStateCookie returns the cookie removing the login state once it is used
+func (a *AuthService) CreateExpiredLoginStateCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     loginStateCookieName,
+		Path:     "/",
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		SameSite: http.SameSiteLaxMode,
+		HttpOnly: true,
+		Secure:   true,
+	}
+}
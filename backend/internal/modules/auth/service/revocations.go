@@ -0,0 +1,176 @@
+package service
+
+import (
+	"backend/internal/modules/auth/entities"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// RevocationStore keeps the ids of access tokens revoked before their expiry
+type RevocationStore interface {
+	Revoke(ctx context.Context, tokens ...entities.RevokedToken) error
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
+}
+
+// RevocationRepository persists revoked tokens, it is implemented by repository.Repository
+type RevocationRepository interface {
+	RevokeAccessTokens(ctx context.Context, tokens []entities.RevokedToken) error
+	GetRevokedAccessTokens(ctx context.Context, since time.Time) ([]entities.RevokedToken, error)
+}
+
+const (
+	// syncOverlap covers revocations committed by other instances while the last sync was running
+	syncOverlap = 5 * time.Second
+	// syncTimeout bounds a sync, which doesn't run on the context of the request that started it
+	syncTimeout = 5 * time.Second
+	// maxStaleIntervals is how many sync intervals the cache may fall behind, e.g. while the
+	// database is unreachable, before requests wait for the sync and fail with it
+	maxStaleIntervals = 3
+)
+
+// CachedRevocations answers IsRevoked from memory. Revocations are written through to the
+// repository, and the cache pulls the ones made by other instances every syncInterval, so
+// a token revoked elsewhere is rejected soon after syncInterval. The sync runs in the
+// background, requests keep being answered from the cache meanwhile; only a cache that never
+// synced or is maxStaleIntervals behind makes them wait for it.
+// Without a repository the store is memory only.
+type CachedRevocations struct {
+	repo         RevocationRepository
+	syncInterval time.Duration
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time // token id to the time the token expires
+	syncedAt time.Time
+	syncing  chan struct{} // closed when the running sync is done, nil if none is running
+	syncErr  error         // of the last sync
+}
+
+func NewRevocationStore(repo RevocationRepository, syncInterval time.Duration) *CachedRevocations {
+	return &CachedRevocations{
+		repo:         repo,
+		syncInterval: syncInterval,
+		revoked:      make(map[string]time.Time),
+	}
+}
+
+func (c *CachedRevocations) Revoke(ctx context.Context, tokens ...entities.RevokedToken) error {
+	if c.repo != nil {
+		if err := c.repo.RevokeAccessTokens(ctx, tokens); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, token := range tokens {
+		c.revoked[token.TokenId] = token.ExpiresAt
+	}
+
+	return nil
+}
+
+func (c *CachedRevocations) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	if c.repo != nil {
+		if err := c.refresh(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	expiresAt, ok := c.revoked[tokenId]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// refresh starts a sync once syncInterval has passed and waits for it if the cache is too
+// stale to be trusted. Giving up the wait because ctx is done only fails this request.
+func (c *CachedRevocations) refresh(ctx context.Context) error {
+	c.mu.RLock()
+	age := time.Since(c.syncedAt)
+	synced := !c.syncedAt.IsZero()
+	c.mu.RUnlock()
+
+	if age < c.syncInterval {
+		return nil
+	}
+
+	done := c.startSync()
+	if synced && age < maxStaleIntervals*c.syncInterval {
+		return nil
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncErr
+}
+
+// startSync runs a sync in the background unless one is running already and returns the
+// channel closed once it is done
+func (c *CachedRevocations) startSync() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.syncing != nil {
+		return c.syncing
+	}
+
+	done := make(chan struct{})
+	c.syncing = done
+
+	go func() {
+		defer close(done)
+		c.sync()
+	}()
+
+	return done
+}
+
+// sync loads the tokens revoked since the previous sync and drops the expired ones. The
+// query runs without holding the lock, so requests aren't held up by it.
+func (c *CachedRevocations) sync() {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	now := time.Now()
+
+	c.mu.RLock()
+	since := c.syncedAt
+	c.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-syncOverlap)
+	}
+
+	tokens, err := c.repo.GetRevokedAccessTokens(ctx, since)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.syncing = nil
+	c.syncErr = err
+	if err != nil {
+		log.Println("failed to sync revoked tokens:", err)
+		return
+	}
+
+	for _, token := range tokens {
+		c.revoked[token.TokenId] = token.ExpiresAt
+	}
+
+	for tokenId, expiresAt := range c.revoked {
+		if now.After(expiresAt) {
+			delete(c.revoked, tokenId)
+		}
+	}
+
+	c.syncedAt = now
+}
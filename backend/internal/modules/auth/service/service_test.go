@@ -1,15 +1,32 @@
 package service
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/modules/auth/entities"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 var as *AuthService
 
 func init() {
-	as = NewAuthService("localhost", "localhost", "very-secret", "localhost")
+	as = NewAuthService("localhost", "localhost", "very-secret")
 }
 
 func TestAuthService_VerifyPassword(t *testing.T) {
@@ -25,11 +42,22 @@ func TestAuthService_VerifyPassword(t *testing.T) {
 			t.Errorf("VerifyPassword() error = %v, want %v", err, true)
 		}
 	})
+	// hashes made before argon2id became the default still verify, and are due for a rehash
+	t.Run("bcrypt hash", func(t *testing.T) {
+		legacy, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+
+		if ok, err := as.VerifyPassword(password, string(legacy)); !ok || err != nil {
+			t.Errorf("VerifyPassword() = %v, %v, want %v", ok, err, true)
+		}
+		if !as.NeedsRehash(string(legacy)) || as.NeedsRehash(encrypted) {
+			t.Errorf("NeedsRehash() is wrong for bcrypt or the current hash")
+		}
+	})
 }
 
 func TestAuthService_VerifyRequest(t *testing.T) {
 	// valid case with header
-	token, _ := as.GenerateToken("wanomir")
+	token, _, _ := as.GenerateToken(1, "wanomir", entities.RoleCustomer)
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	wr := httptest.NewRecorder()
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -58,15 +86,15 @@ func TestAuthService_VerifyRequest(t *testing.T) {
 		}
 	})
 
-	// valid case with cookie
+	// the cookie carries the refresh token and is not an access credential
 	cookie := as.CreateCookie(token)
 	req = httptest.NewRequest(http.MethodGet, "/", nil)
 	wr = httptest.NewRecorder()
 	req.AddCookie(cookie)
 
 	t.Run("with cookie", func(t *testing.T) {
-		if _, _, err := as.VerifyRequest(wr, req); err != nil {
-			t.Errorf("VerifyRequest() error = %v, want nil", err)
+		if _, _, err := as.VerifyRequest(wr, req); err == nil {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, true)
 		}
 	})
 
@@ -82,3 +110,690 @@ func TestAuthService_VerifyRequest(t *testing.T) {
 		}
 	})
 }
+
+// browsers reject __Host- cookies with a Domain attribute
+func TestAuthService_CreateCookie(t *testing.T) {
+	for _, cookie := range []*http.Cookie{as.CreateCookie("refresh-token"), as.CreateExpiredCookie()} {
+		wr := httptest.NewRecorder()
+		http.SetCookie(wr, cookie)
+
+		header := wr.Header().Get("Set-Cookie")
+		if !strings.HasPrefix(header, "__Host-") || strings.Contains(strings.ToLower(header), "domain=") ||
+			!strings.Contains(header, "Path=/;") || !strings.Contains(header, "Secure") {
+			t.Errorf("Set-Cookie = %q, want a __Host- cookie without Domain, on every path and secure", header)
+		}
+	}
+}
+
+func TestAuthService_ChallengeToken(t *testing.T) {
+	challenge, err := as.GenerateChallengeToken(1, "wanomir")
+	if err != nil {
+		t.Fatalf("GenerateChallengeToken() error = %v, want nil", err)
+	}
+
+	t.Run("verified as challenge", func(t *testing.T) {
+		claims, err := as.VerifyChallengeToken(challenge)
+		if err != nil || claims.Subject != "wanomir" || claims.UserId != 1 {
+			t.Errorf("VerifyChallengeToken() = %+v, %v, want the claims of wanomir", claims, err)
+		}
+	})
+
+	// the challenge only proves the password, it must not be usable as an access token
+	t.Run("rejected as access token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+challenge)
+
+		if _, _, err := as.VerifyRequest(httptest.NewRecorder(), req); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+
+	t.Run("access token is not a challenge", func(t *testing.T) {
+		token, _, _ := as.GenerateToken(1, "wanomir", entities.RoleCustomer)
+		if _, err := as.VerifyChallengeToken(token); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyChallengeToken() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+}
+
+func TestAuthService_LoginState(t *testing.T) {
+	state := entities.LoginState{State: "state", Nonce: "nonce", Verifier: "verifier"}
+
+	token, err := as.GenerateLoginState(state)
+	if err != nil {
+		t.Fatalf("GenerateLoginState() error = %v, want nil", err)
+	}
+
+	t.Run("from cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(as.CreateLoginStateCookie(token))
+
+		got, err := as.VerifyLoginState(as.LoginStateFromCookie(req))
+		if err != nil || got != state {
+			t.Errorf("VerifyLoginState() = %+v, %v, want %+v", got, err, state)
+		}
+	})
+
+	// the login state must not be usable as an access token and the other way round
+	t.Run("rejected as access token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		if _, _, err := as.VerifyRequest(httptest.NewRecorder(), req); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+
+	t.Run("access token is not a login state", func(t *testing.T) {
+		access, _, _ := as.GenerateToken(1, "wanomir", entities.RoleCustomer)
+		if _, err := as.VerifyLoginState(access); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyLoginState() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+}
+
+func TestAuthService_UserToken(t *testing.T) {
+	token, record, err := as.GenerateUserToken(entities.PurposeResetPassword, 1, "wanomir", "wanomir@example.com")
+	if err != nil {
+		t.Fatalf("GenerateUserToken() error = %v, want nil", err)
+	}
+
+	t.Run("verified for its purpose", func(t *testing.T) {
+		claims, err := as.VerifyUserToken(entities.PurposeResetPassword, token)
+		if err != nil || claims.ID != record.TokenId || claims.Email != "wanomir@example.com" {
+			t.Errorf("VerifyUserToken() = %+v, %v, want the claims of the record %+v", claims, err, record)
+		}
+	})
+
+	t.Run("expires with reset expiry", func(t *testing.T) {
+		if until := time.Until(record.ExpiresAt); until <= 0 || until > as.ResetExpiry {
+			t.Errorf("GenerateUserToken() expires in %v, want %v", until, as.ResetExpiry)
+		}
+	})
+
+	t.Run("rejected for another purpose", func(t *testing.T) {
+		if _, err := as.VerifyUserToken(entities.PurposeVerifyEmail, token); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyUserToken() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+
+	t.Run("rejected as access token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		if _, _, err := as.VerifyRequest(httptest.NewRecorder(), req); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+}
+
+func TestAuthService_SigningKeys(t *testing.T) {
+	now := time.Now()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	// the EC key signs until the Ed25519 key takes over, the RSA key is only published so far
+	previous, _ := NewSigningKey("2026-09", ecKey, now.Add(-48*time.Hour), now.Add(24*time.Hour))
+	current, _ := NewSigningKey("2026-10", edKey, now.Add(-time.Hour), time.Time{})
+	next, _ := NewSigningKey("2026-11", rsaKey, now.Add(24*time.Hour), time.Time{})
+
+	ks := NewAuthService("localhost", "localhost", "", WithSigningKeys(previous, current, next))
+
+	verify := func(as *AuthService, token string) error {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, _, err := as.VerifyRequest(httptest.NewRecorder(), req)
+		return err
+	}
+
+	token, _, err := ks.GenerateToken(1, "wanomir", entities.RoleCustomer)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	t.Run("signed with the current key", func(t *testing.T) {
+		parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if parsed.Header["kid"] != "2026-10" || parsed.Method.Alg() != "EdDSA" {
+			t.Errorf("token header = %v, want kid 2026-10 and alg EdDSA", parsed.Header)
+		}
+		if err := verify(ks, token); err != nil {
+			t.Errorf("VerifyRequest() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("verifiable with the JWKS", func(t *testing.T) {
+		set := ks.JWKS()
+		if len(set.Keys) != 3 {
+			t.Fatalf("JWKS() has %d keys, want 3", len(set.Keys))
+		}
+
+		_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+			key, _ := set.Find(token.Header["kid"].(string))
+			return key.PublicKey()
+		})
+		if err != nil {
+			t.Errorf("token doesn't verify with the JWKS: %v", err)
+		}
+	})
+
+	t.Run("tokens of the previous key stay valid", func(t *testing.T) {
+		old := NewAuthService("localhost", "localhost", "", WithSigningKeys(previous))
+		token, _, _ := old.GenerateToken(1, "wanomir", entities.RoleCustomer)
+
+		if err := verify(ks, token); err != nil {
+			t.Errorf("VerifyRequest() error = %v, want nil", err)
+		}
+
+		// until the key is retired
+		retired, _ := NewSigningKey("2026-09", ecKey, now.Add(-48*time.Hour), now.Add(-time.Minute))
+		rs := NewAuthService("localhost", "localhost", "", WithSigningKeys(retired, current))
+		if err := verify(rs, token); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+		if len(rs.JWKS().Keys) != 1 {
+			t.Errorf("JWKS() publishes a retired key")
+		}
+	})
+
+	t.Run("secret tokens rejected without the secret", func(t *testing.T) {
+		token, _, _ := as.GenerateToken(1, "wanomir", entities.RoleCustomer)
+		if err := verify(ks, token); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+
+		// but accepted while moving over from the secret
+		ms := NewAuthService("localhost", "localhost", "very-secret", WithSigningKeys(current))
+		if err := verify(ms, token); err != nil {
+			t.Errorf("VerifyRequest() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("public key as HMAC secret", func(t *testing.T) {
+		public, _ := x509.MarshalPKIXPublicKey(ecKey.Public())
+		claims := jwt.MapClaims{"iss": "localhost", "aud": "localhost", "typ": "JWT", "jti": "1", "exp": now.Add(time.Hour).Unix()}
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = "2026-09"
+		token, _ := forged.SignedString(public)
+
+		if err := verify(ks, token); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+}
+
+func TestAuthService_VerifyRequestClaims(t *testing.T) {
+	sign := func(claims jwt.MapClaims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("very-secret"))
+		return token
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"iss": "localhost", "aud": "localhost", "typ": "JWT", "jti": "1", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", sign(claims(nil)), false},
+		{"other audience", sign(claims(jwt.MapClaims{"aud": "billing"})), true},
+		{"audience list", sign(claims(jwt.MapClaims{"aud": []string{"billing", "localhost"}})), false},
+		{"missing audience", sign(claims(jwt.MapClaims{"aud": nil})), true},
+		{"not valid yet", sign(claims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})), true},
+		{"within leeway", sign(claims(jwt.MapClaims{"nbf": time.Now().Add(10 * time.Second).Unix()})), false},
+		{"issued in the future", sign(claims(jwt.MapClaims{"iat": time.Now().Add(time.Hour).Unix()})), true},
+		{"expired", sign(claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+
+			if _, _, err := as.VerifyRequest(httptest.NewRecorder(), req); (err != nil) != tc.wantErr {
+				t.Errorf("VerifyRequest() error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	_ = os.WriteFile(filepath.Join(dir, "2026-10.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	write := func(manifest string) string {
+		path := filepath.Join(dir, "keys.json")
+		_ = os.WriteFile(path, []byte(manifest), 0600)
+		return path
+	}
+
+	t.Run("relative path", func(t *testing.T) {
+		keys, err := LoadSigningKeys(write(`{"keys": [{"kid": "2026-10", "file": "2026-10.pem", "signFrom": "2026-10-01T00:00:00Z"}]}`))
+		if err != nil {
+			t.Fatalf("LoadSigningKeys() error = %v", err)
+		}
+		if len(keys) != 1 || keys[0].Id != "2026-10" || keys[0].Algorithm() != "ES256" || !keys[0].RetireAt.IsZero() {
+			t.Errorf("LoadSigningKeys() = %+v, want the ES256 key 2026-10", keys)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		manifest string
+	}{
+		{"duplicate kid", `{"keys": [{"kid": "a", "file": "2026-10.pem"}, {"kid": "a", "file": "2026-10.pem"}]}`},
+		{"missing kid", `{"keys": [{"file": "2026-10.pem"}]}`},
+		{"missing file", `{"keys": [{"kid": "a", "file": "2026-11.pem"}]}`},
+		{"retired before signing", `{"keys": [{"kid": "a", "file": "2026-10.pem", "signFrom": "2026-10-01T00:00:00Z", "retireAt": "2026-09-01T00:00:00Z"}]}`},
+		{"invalid json", `{"keys": [`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := LoadSigningKeys(write(tc.manifest)); err == nil {
+				t.Errorf("LoadSigningKeys() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestAuthService_GenerateRefreshToken(t *testing.T) {
+	token, record, err := as.GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v, want nil", err)
+	}
+
+	t.Run("stores only the hash", func(t *testing.T) {
+		if record.TokenHash == token || record.TokenHash != as.HashRefreshToken(token) {
+			t.Errorf("GenerateRefreshToken() hash = %s, want hash of the token", record.TokenHash)
+		}
+	})
+
+	t.Run("expires with refresh token expiry", func(t *testing.T) {
+		if until := time.Until(record.ExpiresAt); until <= as.TokenExpiry || until > as.RefreshTokenExpiry {
+			t.Errorf("GenerateRefreshToken() expires in %v, want %v", until, as.RefreshTokenExpiry)
+		}
+	})
+
+	t.Run("tokens are unique", func(t *testing.T) {
+		if other, _, _ := as.GenerateRefreshToken(); other == token {
+			t.Errorf("GenerateRefreshToken() returned %s twice", token)
+		}
+	})
+
+	t.Run("read from cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/user/refresh", nil)
+		req.AddCookie(as.CreateCookie(token))
+
+		if got := as.RefreshTokenFromCookie(req); got != token {
+			t.Errorf("RefreshTokenFromCookie() = %s, want %s", got, token)
+		}
+	})
+}
+
+func TestAuthService_RevokeTokens(t *testing.T) {
+	token, tokenId, err := as.GenerateToken(1, "wanomir", entities.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	_, claims, err := as.VerifyRequest(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatalf("VerifyRequest() error = %v, want nil", err)
+	}
+	if claims.ID != tokenId {
+		t.Fatalf("VerifyRequest() jti = %s, want %s", claims.ID, tokenId)
+	}
+	principal := claims.Principal()
+	if principal.UserId != 1 || !principal.HasRole(entities.RoleCustomer) || principal.HasRole(entities.RoleStaff) {
+		t.Fatalf("VerifyRequest() principal = %+v, want customer 1", principal)
+	}
+	if !principal.HasScope(entities.ScopeReadPets) || principal.HasScope(entities.ScopeWritePets) {
+		t.Fatalf("VerifyRequest() scopes = %v, want customer scopes", principal.Scopes)
+	}
+
+	if err = as.RevokeTokens(context.Background(), entities.RevokedToken{TokenId: tokenId, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("RevokeTokens() error = %v, want nil", err)
+	}
+
+	if _, _, err = as.VerifyRequest(httptest.NewRecorder(), req); !errors.Is(err, e.ErrUnauthorized) {
+		t.Errorf("VerifyRequest() error = %v, want unauthorized", err)
+	}
+}
+
+// revocationRepository is a RevocationRepository shared by several instances of the store
+type revocationRepository struct {
+	tokens []entities.RevokedToken
+	syncs  int
+	block  chan struct{} // holds syncs until closed, if set
+	ctxErr error         // of the context of the last sync
+}
+
+func (r *revocationRepository) RevokeAccessTokens(_ context.Context, tokens []entities.RevokedToken) error {
+	r.tokens = append(r.tokens, tokens...)
+	return nil
+}
+
+func (r *revocationRepository) GetRevokedAccessTokens(ctx context.Context, _ time.Time) ([]entities.RevokedToken, error) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.syncs++
+	r.ctxErr = ctx.Err()
+	return r.tokens, nil
+}
+
+func TestCachedRevocations(t *testing.T) {
+	ctx := context.Background()
+	repo := &revocationRepository{}
+
+	instance := NewRevocationStore(repo, time.Hour)
+	other := NewRevocationStore(repo, 0)
+
+	t.Run("revoked locally", func(t *testing.T) {
+		if err := instance.Revoke(ctx, entities.RevokedToken{TokenId: "a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		if revoked, _ := instance.IsRevoked(ctx, "a"); !revoked {
+			t.Errorf("IsRevoked() = %v, want %v", revoked, true)
+		}
+	})
+
+	t.Run("revoked by another instance", func(t *testing.T) {
+		if revoked, _ := other.IsRevoked(ctx, "a"); !revoked {
+			t.Errorf("IsRevoked() = %v, want %v", revoked, true)
+		}
+	})
+
+	t.Run("synced once per interval", func(t *testing.T) {
+		syncs := repo.syncs
+		for i := 0; i < 3; i++ {
+			_, _ = instance.IsRevoked(ctx, "b")
+		}
+		if repo.syncs != syncs {
+			t.Errorf("IsRevoked() synced %d times, want 0", repo.syncs-syncs)
+		}
+	})
+
+	t.Run("expired tokens are dropped", func(t *testing.T) {
+		_ = other.Revoke(ctx, entities.RevokedToken{TokenId: "c", ExpiresAt: time.Now().Add(-time.Minute)})
+		if revoked, _ := other.IsRevoked(ctx, "c"); revoked {
+			t.Errorf("IsRevoked() = %v, want %v", revoked, false)
+		}
+	})
+}
+
+func TestCachedRevocations_Sync(t *testing.T) {
+	repo := &revocationRepository{tokens: []entities.RevokedToken{{TokenId: "a", ExpiresAt: time.Now().Add(time.Hour)}}}
+	store := NewRevocationStore(repo, 50*time.Millisecond)
+
+	// a caller giving up doesn't cancel the sync the others wait for
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.IsRevoked(cancelled, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("IsRevoked() error = %v, want %v", err, context.Canceled)
+	}
+	if revoked, err := store.IsRevoked(context.Background(), "a"); !revoked || err != nil {
+		t.Fatalf("IsRevoked() = %v, %v, want true", revoked, err)
+	}
+	if repo.ctxErr != nil {
+		t.Errorf("sync ran with a done context: %v", repo.ctxErr)
+	}
+
+	// once the interval passed, requests are answered from the cache while the sync runs
+	repo.block = make(chan struct{})
+	time.Sleep(60 * time.Millisecond)
+
+	answered := make(chan bool)
+	go func() {
+		revoked, _ := store.IsRevoked(context.Background(), "a")
+		answered <- revoked
+	}()
+
+	select {
+	case revoked := <-answered:
+		if !revoked {
+			t.Errorf("IsRevoked() = false, want true")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("IsRevoked() waited for the sync")
+	}
+	close(repo.block)
+}
+
+type clientRepository map[string]entities.Client
+
+func (r clientRepository) GetClientById(_ context.Context, clientId string) (entities.Client, error) {
+	client, ok := r[clientId]
+	if !ok {
+		return entities.Client{}, e.NotFound("client not found")
+	}
+	return client, nil
+}
+
+// apiKeyRepository holds API keys by hash and counts their uses
+type apiKeyRepository struct {
+	keys    map[string]entities.APIKey
+	touched map[int]int
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(_ context.Context, keyHash string) (entities.APIKey, error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return entities.APIKey{}, e.NotFound("API key not found")
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) TouchAPIKey(_ context.Context, keyId int) error {
+	r.touched[keyId]++
+	return nil
+}
+
+func TestAuthService_VerifyAPIKey(t *testing.T) {
+	repo := &apiKeyRepository{keys: make(map[string]entities.APIKey), touched: make(map[int]int)}
+	ks := NewAuthService("localhost", "localhost", "very-secret", WithAPIKeyRepository(repo))
+
+	// newKey stores a key of a user with role
+	newKey := func(id int, role entities.Role, expiresAt time.Time, scopes ...string) string {
+		key, record, err := ks.GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(key, entities.APIKeyPrefix) || !strings.HasPrefix(key, record.Prefix) || record.KeyHash == key {
+			t.Fatalf("GenerateAPIKey() = %s, %+v, want a prefixed key and its hash", key, record)
+		}
+		record.Id, record.UserId, record.Username, record.Role = id, id, "user", role
+		record.Scopes, record.ExpiresAt = scopes, expiresAt
+		repo.keys[record.KeyHash] = record
+		return key
+	}
+	// the demoted user created the key while on staff
+	staff := newKey(1, entities.RoleStaff, time.Now().Add(time.Hour), entities.ScopeReadPets, entities.ScopeWritePets)
+	demoted := newKey(2, entities.RoleCustomer, time.Now().Add(time.Hour), entities.ScopeReadPets, entities.ScopeWritePets)
+	expired := newKey(3, entities.RoleStaff, time.Now().Add(-time.Minute), entities.ScopeReadPets)
+
+	testCases := []struct {
+		name       string
+		key        string
+		wantScopes []string
+		wantErr    bool
+	}{
+		{"normal case", staff, []string{entities.ScopeReadPets, entities.ScopeWritePets}, false},
+		{"scopes of a former role", demoted, []string{entities.ScopeReadPets}, false},
+		{"expired", expired, nil, true},
+		{"unknown", entities.APIKeyPrefix + "Xk3v9QbTz2Lq8WnR4yHc1MdF7sJpA0eGvUoK5tIbN6w", nil, true},
+		{"without prefix", strings.TrimPrefix(staff, entities.APIKeyPrefix), nil, true},
+		{"prefix only", entities.APIKeyPrefix, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := ks.VerifyAPIKey(context.Background(), tc.key)
+			if tc.wantErr {
+				if !errors.Is(err, e.ErrUnauthorized) {
+					t.Errorf("VerifyAPIKey() error = %v, want %v", err, e.ErrUnauthorized)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAPIKey() error = %v, want nil", err)
+			}
+			if !principal.IsAPIKey() || principal.IsClient() || strings.Join(principal.Scopes, " ") != strings.Join(tc.wantScopes, " ") {
+				t.Errorf("VerifyAPIKey() = %+v, want an API key principal with scopes %v", principal, tc.wantScopes)
+			}
+			if repo.touched[principal.APIKeyId] == 0 {
+				t.Errorf("VerifyAPIKey() didn't record the use of the key")
+			}
+		})
+	}
+
+	t.Run("without repository", func(t *testing.T) {
+		if _, err := as.VerifyAPIKey(context.Background(), staff); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyAPIKey() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+}
+
+func TestAuthService_ClientCredentials(t *testing.T) {
+	secretHash, _ := as.EncryptPassword("secret")
+	clients := clientRepository{
+		"inventory-sync": {ClientId: "inventory-sync", SecretHash: secretHash, Scopes: []string{entities.ScopeReadPets, entities.ScopeWritePets}},
+		"disabled":       {ClientId: "disabled", SecretHash: secretHash, Scopes: []string{entities.ScopeReadPets}, Disabled: true},
+	}
+
+	cs := NewAuthService("localhost", "localhost", "very-secret", WithClientRepository(clients))
+
+	testCases := []struct {
+		name      string
+		clientId  string
+		secret    string
+		scope     string
+		wantScope string
+		wantErr   error
+	}{
+		{"all allowed scopes", "inventory-sync", "secret", "", "read:pets write:pets", nil},
+		{"requested scope", "inventory-sync", "secret", "read:pets", "read:pets", nil},
+		{"scope not allowed", "inventory-sync", "secret", "read:pets admin", "", e.ErrForbidden},
+		{"wrong secret", "inventory-sync", "password", "", "", e.ErrUnauthorized},
+		{"unknown client", "john", "secret", "", "", e.ErrUnauthorized},
+		{"disabled client", "disabled", "secret", "", "", e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := cs.ClientCredentials(context.Background(), tc.clientId, tc.secret, tc.scope)
+			if !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Fatalf("ClientCredentials() error = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if token.Scope != tc.wantScope || token.TokenType != "Bearer" {
+				t.Errorf("ClientCredentials() token = %+v, want scope %s", token, tc.wantScope)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+			_, claims, err := cs.VerifyRequest(httptest.NewRecorder(), req)
+			if err != nil {
+				t.Fatalf("VerifyRequest() error = %v, want nil", err)
+			}
+			if principal := claims.Principal(); !principal.IsClient() || principal.Username != tc.clientId || principal.Roles != nil {
+				t.Errorf("VerifyRequest() claims = %+v, want claims of client %s", claims, tc.clientId)
+			}
+		})
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+	throttle := NewLoginThrottle(NewMemoryAttempts(),
+		WithBackoff(2, 4, time.Second, 4*time.Second),
+		WithLockout(6, 15*time.Minute),
+		WithFailureWindow(time.Hour),
+	)
+	throttle.now = func() time.Time { return now }
+
+	fail := func(username, ip string, times int) {
+		for i := 0; i < times; i++ {
+			if err := throttle.Fail(ctx, username, ip); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	retryAfter := func(username, ip string) time.Duration {
+		err := throttle.Check(ctx, username, ip)
+		if err != nil && !errors.Is(err, e.ErrRateLimited) {
+			t.Fatalf("Check() error = %v", err)
+		}
+		return e.RetryAfterOf(err)
+	}
+
+	t.Run("free failures", func(t *testing.T) {
+		fail("wanomir", "192.0.2.1", 2)
+		if wait := retryAfter("wanomir", "192.0.2.1"); wait != 0 {
+			t.Errorf("Check() retry after = %v, want %v", wait, 0)
+		}
+	})
+
+	t.Run("exponential backoff", func(t *testing.T) {
+		for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			fail("wanomir", "192.0.2.1", 1)
+			if wait := retryAfter("wanomir", "192.0.2.1"); wait != want {
+				t.Errorf("Check() retry after = %v, want %v", wait, want)
+			}
+		}
+	})
+
+	t.Run("account lockout", func(t *testing.T) {
+		fail("wanomir", "192.0.2.1", 1)
+		err := throttle.Check(ctx, "wanomir", "198.51.100.7")
+		if wait := e.RetryAfterOf(err); !errors.Is(err, e.ErrRateLimited) || wait != 15*time.Minute {
+			t.Errorf("Check() error = %v, retry after = %v, want lockout for %v", err, wait, 15*time.Minute)
+		}
+	})
+
+	t.Run("client ip shared by usernames", func(t *testing.T) {
+		// the ip failed 6 times for wanomir, 2 past its free failures
+		if wait := retryAfter("jenstar", "192.0.2.1"); wait != 2*time.Second {
+			t.Errorf("Check() retry after = %v, want %v", wait, 2*time.Second)
+		}
+		if wait := retryAfter("jenstar", "198.51.100.7"); wait != 0 {
+			t.Errorf("Check() retry after = %v, want %v", wait, 0)
+		}
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		if err := throttle.Unlock(ctx, "wanomir"); err != nil {
+			t.Fatal(err)
+		}
+		if wait := retryAfter("wanomir", "198.51.100.7"); wait != 0 {
+			t.Errorf("Check() retry after = %v, want %v", wait, 0)
+		}
+	})
+
+	t.Run("failures are forgotten", func(t *testing.T) {
+		fail("jenstar", "203.0.113.5", 5)
+		now = now.Add(time.Hour + time.Second)
+		fail("jenstar", "203.0.113.5", 1)
+		if wait := retryAfter("jenstar", "203.0.113.5"); wait != 0 {
+			t.Errorf("Check() retry after = %v, want %v", wait, 0)
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/modules/auth/entities"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AttemptStore counts failed logins per key, it is implemented by repository.Repository
+type AttemptStore interface {
+	// GetLoginAttempts returns the attempts recorded for keys, keys without any are left out
+	GetLoginAttempts(ctx context.Context, keys []string) ([]entities.LoginAttempts, error)
+	// RecordLoginFailure counts a failure of key at the given time. Failures that happened
+	// before windowStart are forgotten, so the count starts over.
+	RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (entities.LoginAttempts, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+// LoginThrottle slows down password guessing. Every username and every client IP may fail
+// a few logins freely, after that each failure doubles the time to wait before the next
+// attempt. Usernames that keep failing are locked out for a while, IPs are only slowed down
+// since many users may share one.
+type LoginThrottle struct {
+	store        AttemptStore
+	freeFailures int
+	ipFailures   int // free failures of a client IP, that covers every username tried from it
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	lockoutAfter int
+	lockoutFor   time.Duration
+	window       time.Duration
+	now          func() time.Time
+}
+
+type LoginThrottleOption func(*LoginThrottle)
+
+// WithBackoff sets the failures allowed before the backoff starts, for usernames and
+// for client IPs, and the delay after the first extra failure, doubled up to maxDelay.
+func WithBackoff(freeFailures, ipFailures int, baseDelay, maxDelay time.Duration) LoginThrottleOption {
+	return func(t *LoginThrottle) {
+		t.freeFailures = freeFailures
+		t.ipFailures = ipFailures
+		t.baseDelay = baseDelay
+		t.maxDelay = maxDelay
+	}
+}
+
+// WithLockout locks an account for duration once its username failed after logins in a row
+func WithLockout(after int, duration time.Duration) LoginThrottleOption {
+	return func(t *LoginThrottle) {
+		t.lockoutAfter = after
+		t.lockoutFor = duration
+	}
+}
+
+// WithFailureWindow sets how long failures are remembered after the last one
+func WithFailureWindow(window time.Duration) LoginThrottleOption {
+	return func(t *LoginThrottle) {
+		t.window = window
+	}
+}
+
+func NewLoginThrottle(store AttemptStore, options ...LoginThrottleOption) *LoginThrottle {
+	t := &LoginThrottle{
+		store:        store,
+		freeFailures: 3,
+		ipFailures:   20,
+		baseDelay:    time.Second,
+		maxDelay:     5 * time.Minute,
+		lockoutAfter: 10,
+		lockoutFor:   15 * time.Minute,
+		window:       time.Hour,
+		now:          time.Now,
+	}
+
+	for _, option := range options {
+		option(t)
+	}
+
+	return t
+}
+
+// Check returns a rate limit error while username, or the client IP, has to wait before
+// trying to log in again. It must be called before the password is verified.
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) error {
+	attempts, err := t.store.GetLoginAttempts(ctx, []string{userKey(username), ipKey(ip)})
+	if err != nil {
+		return e.Internal("failed to get login attempts", err)
+	}
+
+	now := t.now()
+
+	var wait time.Duration
+	locked := false
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.After(now) {
+			locked = true
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+			continue
+		}
+		wait = max(wait, t.retryAt(attempt, now).Sub(now))
+	}
+
+	switch {
+	case locked:
+		return e.RateLimited("account is temporarily locked", wait)
+	case wait > 0:
+		return e.RateLimited("too many failed logins, try again later", wait)
+	}
+
+	return nil
+}
+
+// Fail records a failed login of username from ip, locking the account if it failed too often
+func (t *LoginThrottle) Fail(ctx context.Context, username, ip string) error {
+	now := t.now()
+	windowStart := now.Add(-t.window)
+
+	if _, err := t.store.RecordLoginFailure(ctx, ipKey(ip), now, windowStart); err != nil {
+		return e.Internal("failed to record login failure", err)
+	}
+
+	attempt, err := t.store.RecordLoginFailure(ctx, userKey(username), now, windowStart)
+	if err != nil {
+		return e.Internal("failed to record login failure", err)
+	}
+
+	if t.lockoutAfter > 0 && attempt.Failures >= t.lockoutAfter {
+		if err = t.store.LockLogin(ctx, attempt.Key, now.Add(t.lockoutFor)); err != nil {
+			return e.Internal("failed to lock account", err)
+		}
+	}
+
+	return nil
+}
+
+// Succeed forgets the failures of username. The failures of the client IP are kept,
+// otherwise logging into an own account would reset the count of guesses at others.
+func (t *LoginThrottle) Succeed(ctx context.Context, username string) error {
+	return t.Unlock(ctx, username)
+}
+
+// Unlock lifts the lockout of username and forgets its failures
+func (t *LoginThrottle) Unlock(ctx context.Context, username string) error {
+	if err := t.store.ResetLoginAttempts(ctx, userKey(username)); err != nil {
+		return e.Internal("failed to reset login attempts", err)
+	}
+	return nil
+}
+
+// retryAt returns when the next login attempt of the key is allowed
+func (t *LoginThrottle) retryAt(attempt entities.LoginAttempts, now time.Time) time.Time {
+	if now.Sub(attempt.LastFailureAt) > t.window {
+		return time.Time{}
+	}
+
+	free := t.freeFailures
+	if strings.HasPrefix(attempt.Key, "ip:") {
+		free = t.ipFailures
+	}
+
+	extra := attempt.Failures - free
+	if extra <= 0 {
+		return time.Time{}
+	}
+
+	delay := t.maxDelay
+	// stop doubling before the shift overflows
+	if extra < 32 {
+		delay = min(t.baseDelay<<(extra-1), t.maxDelay)
+	}
+
+	return attempt.LastFailureAt.Add(delay)
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MemoryAttempts is an AttemptStore for a single instance, attempts are lost on restart
+type MemoryAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]entities.LoginAttempts
+	prunedAt time.Time
+}
+
+func NewMemoryAttempts() *MemoryAttempts {
+	return &MemoryAttempts{attempts: make(map[string]entities.LoginAttempts)}
+}
+
+func (m *MemoryAttempts) GetLoginAttempts(_ context.Context, keys []string) ([]entities.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts := make([]entities.LoginAttempts, 0, len(keys))
+	for _, key := range keys {
+		if attempt, ok := m.attempts[key]; ok {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts, nil
+}
+
+func (m *MemoryAttempts) RecordLoginFailure(_ context.Context, key string, at, windowStart time.Time) (entities.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(at, windowStart)
+
+	attempt, ok := m.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(windowStart) {
+		attempt = entities.LoginAttempts{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+
+	m.attempts[key] = attempt
+
+	return attempt, nil
+}
+
+func (m *MemoryAttempts) LockLogin(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt := m.attempts[key]
+	attempt.Key = key
+	attempt.LockedUntil = until
+	m.attempts[key] = attempt
+
+	return nil
+}
+
+func (m *MemoryAttempts) ResetLoginAttempts(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+
+	return nil
+}
+
+// prune drops the attempts that are forgotten anyway, at most once a minute
+func (m *MemoryAttempts) prune(now, windowStart time.Time) {
+	if now.Sub(m.prunedAt) < time.Minute {
+		return
+	}
+
+	for key, attempt := range m.attempts {
+		if attempt.LastFailureAt.Before(windowStart) && !attempt.LockedUntil.After(now) {
+			delete(m.attempts, key)
+		}
+	}
+
+	m.prunedAt = now
+}
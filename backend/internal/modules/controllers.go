@@ -2,6 +2,7 @@ package modules
 
 import (
 	"backend/internal/lib/rr"
+	ac "backend/internal/modules/auth/controller"
 	pc "backend/internal/modules/pet/controller"
 	sc "backend/internal/modules/store/controller"
 	uc "backend/internal/modules/user/controller"
@@ -11,6 +12,7 @@ type Controllers struct {
 	Pet   pc.PetController
 	User  uc.UserController
 	Store sc.StoreController
+	Auth  ac.AuthController
 }
 
 func NewControllers(services *Services, readResponder rr.ReadResponder) *Controllers {
@@ -18,5 +20,6 @@ func NewControllers(services *Services, readResponder rr.ReadResponder) *Control
 		Pet:   pc.NewPetControl(services.Pet, readResponder),
 		User:  uc.NewUserController(services.User, readResponder),
 		Store: sc.NewStoreControl(services.Store, readResponder),
+		Auth:  ac.NewAuthControl(services.Auth, readResponder),
 	}
 }
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"backend/internal/lib/e"
 	"backend/internal/lib/rr"
 	mock_service "backend/internal/mocks/mock_pet_service"
 	"backend/internal/modules/pet/entities"
@@ -71,7 +72,7 @@ func TestPetControl_UpdateWithForm(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", Payload{"1", "garfield", "available"}, http.StatusOK},
-		{"unknown pet", Payload{"10", "garfield", "available"}, http.StatusBadRequest},
+		{"unknown pet", Payload{"10", "garfield", "available"}, http.StatusNotFound},
 		{"invalid id", Payload{"agkla", "garfield", "available"}, http.StatusBadRequest},
 	}
 
@@ -183,6 +184,62 @@ func TestPetControl_UploadImage(t *testing.T) {
 			}
 		})
 	}
+
+	// bodies over the limit are cut off while the form is read
+	t.Run("body too large", func(t *testing.T) {
+		payload := new(bytes.Buffer)
+		writer := multipart.NewWriter(payload)
+		part, _ := writer.CreateFormFile("file", "cat.png")
+		_, _ = part.Write(make([]byte, maxUploadSize+1))
+		_ = writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/pet/1/uploadImage", payload)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		wr := httptest.NewRecorder()
+
+		pc.UploadImage(wr, req)
+
+		if wr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Want status %d, got %d", http.StatusRequestEntityTooLarge, wr.Code)
+		}
+	})
+}
+
+func TestPetControl_GetImage(t *testing.T) {
+	testCases := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"normal case", "/pet/1/images/image.jpg", http.StatusOK},
+		{"unknown image", "/pet/1/images/unknown.jpg", http.StatusNotFound},
+		{"invalid id", "/pet/agjla/images/image.jpg", http.StatusBadRequest},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockPetService(controller)
+	pc := NewPetControl(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			wr := httptest.NewRecorder()
+
+			pc.GetImage(wr, req)
+
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Errorf("Want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+
+			if tc.wantStatus == http.StatusOK && r.Header.Get("Content-Type") != "image/jpeg" {
+				t.Errorf("Want content type %s, got %s", "image/jpeg", r.Header.Get("Content-Type"))
+			}
+		})
+	}
 }
 
 func TestPetControl_Create(t *testing.T) {
@@ -192,7 +249,7 @@ func TestPetControl_Create(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", entities.Pet{Name: "garfield", Category: entities.Category{Name: "cat"}, Status: "available"}, http.StatusOK},
-		{"invalid payload", entities.Pet{}, http.StatusBadRequest},
+		{"invalid payload", entities.Pet{}, http.StatusUnprocessableEntity},
 	}
 
 	controller := gomock.NewController(t)
@@ -228,7 +285,7 @@ func TestPetControl_Update(t *testing.T) {
 	}{
 		{"normal case", entities.Pet{Id: 1, Name: "garfield", Category: entities.Category{Name: "cat"}, Status: "available"}, http.StatusOK},
 		{"unknown pet", entities.Pet{Id: 10, Name: "garfield", Category: entities.Category{Name: "cat"}, Status: "available"}, http.StatusNotFound},
-		{"insufficient parameters", entities.Pet{Id: 1}, http.StatusBadRequest},
+		{"insufficient parameters", entities.Pet{Id: 1}, http.StatusUnprocessableEntity},
 	}
 
 	controller := gomock.NewController(t)
@@ -263,7 +320,7 @@ func TestPetControl_GetByStatus(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", []string{"available", "pending"}, http.StatusOK},
-		{"invalid status", []string{"unknown"}, http.StatusBadRequest},
+		{"invalid status", []string{"unknown"}, http.StatusUnprocessableEntity},
 		{"no status at all", []string{""}, http.StatusBadRequest},
 	}
 
@@ -290,19 +347,101 @@ func TestPetControl_GetByStatus(t *testing.T) {
 	}
 }
 
+func TestPetControl_GetByTags(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"normal case", "?tags=fluffy,calm", http.StatusOK},
+		{"match all", "?tags=fluffy&tags=calm&mode=all", http.StatusOK},
+		{"no tags at all", "?tags=", http.StatusBadRequest},
+		{"invalid mode", "?tags=fluffy&mode=some", http.StatusBadRequest},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockPetService(controller)
+	pc := NewPetControl(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/pet/findByTags"+tc.query, nil)
+			wr := httptest.NewRecorder()
+
+			pc.GetByTags(wr, req)
+
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Errorf("Want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPetControl_List(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNext   string
+		wantPrev   string
+	}{
+		{"normal case", "", http.StatusOK, "/pet?limit=20&offset=20", ""},
+		{"filters are kept in links", "?status=available,pending&tags=fluffy&limit=10&offset=10", http.StatusOK, "/pet?limit=10&offset=20&status=available%2Cpending&tags=fluffy", "/pet?limit=10&offset=0&status=available%2Cpending&tags=fluffy"},
+		{"last page", "?offset=40", http.StatusOK, "", "/pet?limit=20&offset=20"},
+		{"invalid limit", "?limit=many", http.StatusBadRequest, "", ""},
+		{"invalid status", "?status=unknown", http.StatusUnprocessableEntity, "", ""},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockPetService(controller)
+	pc := NewPetControl(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/pet"+tc.query, nil)
+			wr := httptest.NewRecorder()
+
+			pc.List(wr, req)
+
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Fatalf("Want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+
+			if r.StatusCode != http.StatusOK {
+				return
+			}
+
+			var page entities.PetPage
+			_ = json.NewDecoder(r.Body).Decode(&page)
+
+			if page.Next != tc.wantNext || page.Prev != tc.wantPrev {
+				t.Errorf("Want links %q %q, got %q %q", tc.wantNext, tc.wantPrev, page.Next, page.Prev)
+			}
+		})
+	}
+}
+
 func NewMockPetService(controller *gomock.Controller) *mock_service.MockPetServicer {
 	mockService := mock_service.NewMockPetServicer(controller)
 
 	mockService.EXPECT().GetById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, petId int) (entities.Pet, error) {
 		if petId < 1 || petId > 9 {
-			return entities.Pet{}, errors.New("pet not found")
+			return entities.Pet{}, e.NotFound("pet not found")
 		}
 		return entities.Pet{}, nil
 	}).AnyTimes()
 
 	mockService.EXPECT().UpdateWithForm(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int, name, status string) error {
 		if id < 1 || id > 9 {
-			return errors.New("pet not found")
+			return e.NotFound("pet not found")
 		}
 		return nil
 	}).AnyTimes()
@@ -311,24 +450,55 @@ func NewMockPetService(controller *gomock.Controller) *mock_service.MockPetServi
 
 	mockService.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, pet entities.Pet) (int, error) {
 		if pet.Name == "" || pet.Status == "" || pet.Category.Name == "" {
-			return 0, errors.New("fill in mandatory fields")
+			return 0, e.Validation("fill in mandatory fields")
 		}
 		return 1, nil
 	}).AnyTimes()
 
 	mockService.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, pet entities.Pet) error {
 		if pet.Name == "" || pet.Status == "" || pet.Category.Name == "" {
-			return errors.New("fill in mandatory fields")
+			return e.Validation("fill in mandatory fields")
 		}
 		return nil
 	}).AnyTimes()
 
 	mockService.EXPECT().GetByStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, status string) ([]entities.Pet, error) {
 		if !(status == "available" || status == "pending" || status == "sold") {
-			return nil, errors.New("invalid status")
+			return nil, e.Validation("invalid status")
 		}
 		return []entities.Pet{{Name: "garfield", Status: "available"}}, nil
 	}).AnyTimes()
 
+	mockService.EXPECT().GetByTags(gomock.Any(), gomock.Any(), gomock.Any()).Return([]entities.Pet{{Name: "garfield", Status: "available"}}, nil).AnyTimes()
+
+	mockService.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, filter entities.PetFilter) (entities.PetPage, error) {
+		for _, status := range filter.Statuses {
+			if !(status == "available" || status == "pending" || status == "sold") {
+				return entities.PetPage{}, e.Validation("invalid status")
+			}
+		}
+		if filter.Limit == 0 {
+			filter.Limit = 20
+		}
+
+		// pretend there are 50 pets in total
+		items := make(entities.Pets, max(min(filter.Limit, 50-filter.Offset), 0))
+		return entities.PetPage{Items: items, Total: 50, Limit: filter.Limit, Offset: filter.Offset}, nil
+	}).AnyTimes()
+
+	mockService.EXPECT().UploadImage(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, petId int, upload entities.ImageUpload) (entities.PetImage, error) {
+		if upload.File == nil {
+			return entities.PetImage{}, errors.New("no file")
+		}
+		return entities.PetImage{PetId: petId, Url: "http://localhost/pet/1/images/image.jpg", AdditionalMetadata: upload.AdditionalMetadata}, nil
+	}).AnyTimes()
+
+	mockService.EXPECT().GetImage(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, petId int, imageName string) (io.ReadCloser, entities.PetImage, error) {
+		if petId != 1 || imageName != "image.jpg" {
+			return nil, entities.PetImage{}, e.NotFound("image not found")
+		}
+		return io.NopCloser(strings.NewReader("image")), entities.PetImage{ContentType: "image/jpeg", Size: 5}, nil
+	}).AnyTimes()
+
 	return mockService
 }
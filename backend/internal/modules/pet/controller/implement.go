@@ -2,17 +2,22 @@ package controller
 
 import (
 	"backend/internal/lib/e"
+	"backend/internal/lib/imaging"
 	"backend/internal/lib/rr"
 	"backend/internal/lib/u"
 	"backend/internal/modules/pet/entities"
 	"backend/internal/modules/pet/service"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 )
 
+// maxUploadSize caps the whole multipart body, the image itself is checked by the service
+const maxUploadSize = 32 << 20
+
 type PetControl struct {
 	service service.PetServicer
 	rr      rr.ReadResponder
@@ -28,25 +33,27 @@ func NewPetControl(service service.PetServicer, readResponder rr.ReadResponder)
 // GetById godoc
 // @Summary get pet by id
 // @Security ApiKeyAuth
+// @Security petstore_auth[read:pets]
+// @Security UserApiKey
 // @Description Find pet by ID
 // @Tags pet
 // @Produce json
 // @Param petId path int true "Pet ID"
 // @Success 200 {object} entities.Pet
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,404,500 {object} rr.JSONResponse
 // @Router /pet/{petId} [get]
 func (c *PetControl) GetById(w http.ResponseWriter, r *http.Request) {
 	id := u.ParamFromPath(r.URL.Path)
 
 	petId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid id supplied"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid id supplied"))
 		return
 	}
 
 	pet, err := c.service.GetById(r.Context(), petId)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, err, 404)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
@@ -56,6 +63,8 @@ func (c *PetControl) GetById(w http.ResponseWriter, r *http.Request) {
 // UpdateWithForm godoc
 // @Summary update pet
 // @Security ApiKeyAuth
+// @Security petstore_auth[write:pets]
+// @Security UserApiKey
 // @Description Updates a pet in the store with form data
 // @Tags pet
 // @Produce json
@@ -63,14 +72,14 @@ func (c *PetControl) GetById(w http.ResponseWriter, r *http.Request) {
 // @Param name formData string false "Pet name"
 // @Param status formData string false "Pet status"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,403,404,422,500 {object} rr.JSONResponse
 // @Router /pet/{petId} [post]
 func (c *PetControl) UpdateWithForm(w http.ResponseWriter, r *http.Request) {
 	id := u.ParamFromPath(r.URL.Path)
 
 	petId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid id supplied"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid id supplied"))
 		return
 	}
 
@@ -79,7 +88,7 @@ func (c *PetControl) UpdateWithForm(w http.ResponseWriter, r *http.Request) {
 	status := r.FormValue("status")
 
 	if err = c.service.UpdateWithForm(r.Context(), petId, name, status); err != nil {
-		_ = c.rr.WriteJSONError(w, err)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
@@ -91,29 +100,31 @@ func (c *PetControl) UpdateWithForm(w http.ResponseWriter, r *http.Request) {
 // Delete godoc
 // @Summary delete pet
 // @Security ApiKeyAuth
+// @Security petstore_auth[write:pets]
+// @Security UserApiKey
 // @Description Deletes a pet
 // @Tags pet
 // @Produce json
 // @Param petId path int true "Pet ID"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,403,404,500 {object} rr.JSONResponse
 // @Router /pet/{petId} [delete]
 func (c *PetControl) Delete(w http.ResponseWriter, r *http.Request) {
 	id := u.ParamFromPath(r.URL.Path)
 
 	petId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid id supplied"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid id supplied"))
 		return
 	}
 
 	if _, err = c.service.GetById(r.Context(), petId); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't get pet", err), 404)
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't get pet", err))
 		return
 	}
 
 	if err = c.service.Delete(r.Context(), petId); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't delete pet", err))
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't delete pet", err))
 		return
 	}
 
@@ -124,14 +135,16 @@ func (c *PetControl) Delete(w http.ResponseWriter, r *http.Request) {
 // UploadImage godoc
 // @Summary upload image
 // @Security ApiKeyAuth
+// @Security petstore_auth[write:pets]
+// @Security UserApiKey
 // @Description Uploads an image
 // @Tags pet
 // @Produce json
 // @Param petId path int true "Pet ID"
 // @Param additionalMetadata formData string false "Additional data to pass to server"
 // @Param file formData file true "File to upload"
-// @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Success 200 {object} rr.JSONResponse{data=entities.PetImage}
+// @Failure 400,403,404,413,415,500 {object} rr.JSONResponse
 // @Router /pet/{petId}/uploadImage [post]
 func (c *PetControl) UploadImage(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
@@ -139,44 +152,110 @@ func (c *PetControl) UploadImage(w http.ResponseWriter, r *http.Request) {
 
 	petId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid id supplied"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid id supplied"))
 		return
 	}
 
 	if _, err = c.service.GetById(r.Context(), petId); err != nil {
-		_ = c.rr.WriteJSONError(w, err, 404)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
-	_ = r.ParseForm()
-	file, _, err := r.FormFile("file")
-	if err != nil || file == nil {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid file supplied"))
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	file, header, err := r.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't upload image", imaging.ErrTooLarge), 413)
+		return
+	} else if err != nil || file == nil {
+		_ = c.rr.WriteError(w, r, errors.New("invalid file supplied"))
 		return
 	}
+	defer file.Close()
 
-	resp := rr.JSONResponse{Error: false, Message: "pet image uploaded"}
+	upload := entities.ImageUpload{
+		FileName:           header.Filename,
+		ContentType:        header.Header.Get("Content-Type"),
+		AdditionalMetadata: r.FormValue("additionalMetadata"),
+		File:               file,
+	}
+
+	image, err := c.service.UploadImage(r.Context(), petId, upload)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't upload image", err), 413)
+		return
+	} else if errors.Is(err, imaging.ErrNotImage) {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't upload image", err), 415)
+		return
+	} else if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't upload image", err), 500)
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "pet image uploaded", Data: image}
 	_ = c.rr.WriteJSON(w, 200, resp)
 }
 
+// GetImage godoc
+// @Summary get image
+// @Description Returns an image previously uploaded for a pet
+// @Tags pet
+// @Produce octet-stream
+// @Param petId path int true "Pet ID"
+// @Param imageName path string true "Image name"
+// @Success 200 {file} binary
+// @Failure 400,404,500 {object} rr.JSONResponse
+// @Router /pet/{petId}/images/{imageName} [get]
+func (c *PetControl) GetImage(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, imageName := parts[len(parts)-3], parts[len(parts)-1]
+
+	petId, err := strconv.Atoi(id)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, errors.New("invalid id supplied"))
+		return
+	}
+
+	file, image, err := c.service.GetImage(r.Context(), petId, imageName)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(image.Size, 10))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+
+	_, _ = io.Copy(w, file)
+}
+
 // Create godoc
 // @Summary create pet
 // @Security ApiKeyAuth
+// @Security petstore_auth[write:pets]
+// @Security UserApiKey
 // @Description Add a new pet to the store
 // @Tags pet
 // @Accept json
 // @Produce json
 // @Param body body entities.Pet true "Pet object that needs to be added to the store"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,403,409,422,500 {object} rr.JSONResponse
 // @Router /pet [post]
 func (c *PetControl) Create(w http.ResponseWriter, r *http.Request) {
 	var pet entities.Pet
-	_ = c.rr.ReadJSON(w, r, &pet)
+	if err := c.rr.ReadJSON(w, r, &pet); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
 
 	petId, err := c.service.Create(r.Context(), pet)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, err)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
@@ -188,25 +267,30 @@ func (c *PetControl) Create(w http.ResponseWriter, r *http.Request) {
 // Update godoc
 // @Summary update pet
 // @Security ApiKeyAuth
+// @Security petstore_auth[write:pets]
+// @Security UserApiKey
 // @Description Update an existing pet
 // @Tags pet
 // @Accept json
 // @Produce json
 // @Param body body entities.Pet true "Pet object that needs to be added to the store"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,403,404,422,500 {object} rr.JSONResponse
 // @Router /pet [put]
 func (c *PetControl) Update(w http.ResponseWriter, r *http.Request) {
 	var pet entities.Pet
-	_ = c.rr.ReadJSON(w, r, &pet)
+	if err := c.rr.ReadPartialJSON(w, r, &pet); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
 
 	if _, err := c.service.GetById(r.Context(), pet.Id); err != nil {
-		_ = c.rr.WriteJSONError(w, err, 404)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
 	if err := c.service.Update(r.Context(), pet); err != nil {
-		_ = c.rr.WriteJSONError(w, err)
+		_ = c.rr.WriteError(w, r, err)
 		return
 	}
 
@@ -217,18 +301,20 @@ func (c *PetControl) Update(w http.ResponseWriter, r *http.Request) {
 // GetByStatus godoc
 // @Summary get pets by status
 // @Security ApiKeyAuth
+// @Security petstore_auth[read:pets]
+// @Security UserApiKey
 // @Description Finds pets by status
 // @Tags pet
 // @Produce json
 // @Param status query []string true "Status values that need to be considered for filter<br>Available values : <i>available, pending, sold</i>"
 // @Success 200 {object} entities.Pets
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,422,500 {object} rr.JSONResponse
 // @Router /pet/findByStatus [get]
 func (c *PetControl) GetByStatus(w http.ResponseWriter, r *http.Request) {
 	statuses := strings.Split(r.URL.Query()["status"][0], ",")
 
 	if len(statuses) == 0 || statuses[0] == "" {
-		_ = c.rr.WriteJSONError(w, errors.New("at least one status must be specified"))
+		_ = c.rr.WriteError(w, r, errors.New("at least one status must be specified"))
 		return
 	}
 
@@ -238,7 +324,7 @@ func (c *PetControl) GetByStatus(w http.ResponseWriter, r *http.Request) {
 	for _, status := range statuses {
 		pets, err := c.service.GetByStatus(ctx, status)
 		if err != nil {
-			_ = c.rr.WriteJSONError(w, e.Wrap("couldn't get pets by status "+status, err))
+			_ = c.rr.WriteError(w, r, e.Wrap("couldn't get pets by status "+status, err))
 			return
 		}
 		result = append(result, pets...)
@@ -246,3 +332,124 @@ func (c *PetControl) GetByStatus(w http.ResponseWriter, r *http.Request) {
 
 	_ = c.rr.WriteJSON(w, 200, result)
 }
+
+// GetByTags godoc
+// @Summary get pets by tags
+// @Security ApiKeyAuth
+// @Security petstore_auth[read:pets]
+// @Security UserApiKey
+// @Description Finds pets having any of the given tags, or all of them with mode=all
+// @Tags pet
+// @Produce json
+// @Param tags query []string true "Tags to filter by"
+// @Param mode query string false "Match mode<br>Available values : <i>any, all</i>"
+// @Success 200 {object} entities.Pets
+// @Failure 400,422,500 {object} rr.JSONResponse
+// @Router /pet/findByTags [get]
+func (c *PetControl) GetByTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	tags := splitParam(query["tags"])
+	if len(tags) == 0 {
+		_ = c.rr.WriteError(w, r, errors.New("at least one tag must be specified"))
+		return
+	}
+
+	var matchAll bool
+	switch query.Get("mode") {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		_ = c.rr.WriteError(w, r, errors.New("invalid mode supplied"))
+		return
+	}
+
+	pets, err := c.service.GetByTags(r.Context(), tags, matchAll)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't get pets by tags", err))
+		return
+	}
+
+	_ = c.rr.WriteJSON(w, 200, entities.Pets(pets))
+}
+
+// List godoc
+// @Summary list pets
+// @Security ApiKeyAuth
+// @Security petstore_auth[read:pets]
+// @Security UserApiKey
+// @Description Lists pets page by page, filters can be combined
+// @Tags pet
+// @Produce json
+// @Param status query []string false "Status values<br>Available values : <i>available, pending, sold</i>"
+// @Param category query string false "Category name"
+// @Param tags query []string false "Tag names, pets must have all of them"
+// @Param name query string false "Name prefix"
+// @Param sort query string false "Sort key, prefix with - for descending order<br>Available values : <i>id, name, createdAt</i>"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Param offset query int false "Number of pets to skip"
+// @Success 200 {object} entities.PetPage
+// @Failure 400,422,500 {object} rr.JSONResponse
+// @Router /pet [get]
+func (c *PetControl) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := entities.PetFilter{
+		Statuses:   splitParam(query["status"]),
+		Category:   query.Get("category"),
+		Tags:       splitParam(query["tags"]),
+		NamePrefix: query.Get("name"),
+		SortBy:     strings.TrimPrefix(query.Get("sort"), "-"),
+		Desc:       strings.HasPrefix(query.Get("sort"), "-"),
+	}
+
+	var err error
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			_ = c.rr.WriteError(w, r, errors.New("invalid limit supplied"))
+			return
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil {
+			_ = c.rr.WriteError(w, r, errors.New("invalid offset supplied"))
+			return
+		}
+	}
+
+	page, err := c.service.List(r.Context(), filter)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't list pets", err))
+		return
+	}
+
+	// links keep every filter of the current request and only move the offset
+	pageLink := func(offset int) string {
+		query.Set("limit", strconv.Itoa(page.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	if page.Offset+len(page.Items) < page.Total {
+		page.Next = pageLink(page.Offset + page.Limit)
+	}
+	if page.Offset > 0 {
+		page.Prev = pageLink(max(page.Offset-page.Limit, 0))
+	}
+
+	_ = c.rr.WriteJSON(w, 200, page)
+}
+
+// splitParam accepts both repeated (?a=1&a=2) and comma separated (?a=1,2) query values
+func splitParam(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
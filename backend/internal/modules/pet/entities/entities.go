@@ -1,32 +1,85 @@
 package entities
 
+import "io"
+
 type Category struct {
 	Id   int    `json:"id,int"`
-	Name string `json:"name" example:"cat"`
+	Name string `json:"name" binding:"required,max=255" example:"cat"`
 }
 
 type Tag struct {
 	Id   int    `json:"id,int"`
-	Name string `json:"name" example:"fluffy"`
+	Name string `json:"name" binding:"required,max=255" example:"fluffy"`
 }
 
 type PhotoUrl struct {
-	Id    int    `json:"id,int"`
-	PetId int    `json:"petId,int"`
-	Url   string `json:"url"`
+	Id         int         `json:"id,int"`
+	PetId      int         `json:"petId,int"`
+	Url        string      `json:"url"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type Thumbnail struct {
+	Id         int    `json:"id,int"`
+	PhotoUrlId int    `json:"photoUrlId,int"`
+	Name       string `json:"name" example:"128"`
+	Width      int    `json:"width,int" example:"128"`
+	Height     int    `json:"height,int" example:"96"`
+	Url        string `json:"url"`
 }
 
 type Pet struct {
-	Id        int      `json:"id,int"`
-	Category  Category `json:"category" binding:"required"`
-	Name      string   `json:"name" binding:"required" example:"doggy"`
-	PhotoUrls []string `json:"photoUrls"`
-	Tags      []Tag    `json:"tags"`
-	Status    string   `json:"status" binding:"required" example:"available"` // available | pending | sold
+	Id        int        `json:"id,int"`
+	Category  Category   `json:"category" binding:"required"`
+	Name      string     `json:"name" binding:"required,max=255" example:"doggy"`
+	PhotoUrls []string   `json:"photoUrls"`
+	Photos    []PhotoUrl `json:"photos,omitempty"`
+	Tags      []Tag      `json:"tags"`
+	Status    string     `json:"status" binding:"required,oneof=available pending sold" example:"available"` // available | pending | sold
+	CreatedBy string     `json:"createdBy,omitempty" example:"jenstar"`                                      // set by the server from the caller
+	UpdatedBy string     `json:"updatedBy,omitempty" example:"client:inventory-sync"`                        // set by the server from the caller
 }
 
 type Pets []Pet
 
+type PetFilter struct {
+	Statuses   []string
+	Category   string
+	Tags       []string
+	NamePrefix string
+	SortBy     string // id | name | createdAt
+	Desc       bool
+	Limit      int
+	Offset     int
+}
+
+type PetPage struct {
+	Items  Pets   `json:"items"`
+	Total  int    `json:"total,int" example:"42"`
+	Limit  int    `json:"limit,int" example:"20"`
+	Offset int    `json:"offset,int" example:"0"`
+	Next   string `json:"next,omitempty" example:"/pet?limit=20&offset=20"`
+	Prev   string `json:"prev,omitempty"`
+}
+
+type PetImage struct {
+	PetId              int         `json:"petId,int"`
+	Url                string      `json:"url"`
+	ContentType        string      `json:"contentType" example:"image/jpeg"`
+	Size               int64       `json:"size,int"`
+	Width              int         `json:"width,int"`
+	Height             int         `json:"height,int"`
+	AdditionalMetadata string      `json:"additionalMetadata,omitempty"`
+	Thumbnails         []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type ImageUpload struct {
+	FileName           string
+	ContentType        string
+	AdditionalMetadata string
+	File               io.Reader
+}
+
 type PetTag struct {
 	Id    int `db:"id,int"`
 	PetId int `db:"pet_id,int"`
@@ -2,18 +2,60 @@ package service
 
 import (
 	"backend/internal/lib/e"
+	"backend/internal/lib/imaging"
+	"backend/internal/lib/validate"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/pet/entities"
 	"backend/internal/repository"
+	"backend/internal/storage"
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
 )
 
 type PetService struct {
-	DB repository.Repository
+	DB           repository.Repository
+	storage      storage.Storage
+	images       *imaging.Processor
+	imageBaseUrl string
+}
+
+type PetServiceOption func(*PetService)
+
+// WithImageStorage enables pet image uploads; baseUrl is the public address
+// of this server used to build photo urls, e.g. "http://localhost:8888".
+func WithImageStorage(storage storage.Storage, baseUrl string) PetServiceOption {
+	return func(s *PetService) {
+		s.storage = storage
+		s.imageBaseUrl = strings.TrimSuffix(baseUrl, "/")
+	}
 }
 
-func NewPetService(db repository.Repository) *PetService {
-	return &PetService{DB: db}
+// WithImageProcessor sets the pipeline used to validate uploaded images and render their thumbnails.
+func WithImageProcessor(processor *imaging.Processor) PetServiceOption {
+	return func(s *PetService) {
+		s.images = processor
+	}
+}
+
+func NewPetService(db repository.Repository, options ...PetServiceOption) *PetService {
+	s := &PetService{DB: db, images: imaging.NewProcessor()}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
 func (s *PetService) GetById(ctx context.Context, id int) (pet entities.Pet, err error) {
@@ -21,49 +63,78 @@ func (s *PetService) GetById(ctx context.Context, id int) (pet entities.Pet, err
 		return entities.Pet{}, err
 	}
 
-	// get pet category
-	if pet.Category, err = s.DB.GetPetCategoryById(ctx, pet.Category.Id); err != nil {
-		return pet, e.Wrap("failed to get pet category", err)
+	pets := []entities.Pet{pet}
+	if err = s.hydrate(ctx, pets); err != nil {
+		return entities.Pet{}, err
 	}
 
-	// get pet photo urls
-	photoUrls, err := s.DB.GetPhotoUrlsByPetId(ctx, pet.Id)
-	if err != nil {
-		return pet, e.Wrap("failed to get photo urls", err)
+	return pets[0], nil
+}
+
+// hydrate fills photo urls, photos and tags of the given pets in place. It takes
+// a fixed number of queries regardless of how many pets, photos or tags there are.
+func (s *PetService) hydrate(ctx context.Context, pets []entities.Pet) error {
+	if len(pets) == 0 {
+		return nil
 	}
 
-	pet.PhotoUrls = make([]string, 0, len(photoUrls))
-	for _, photoUrl := range photoUrls {
-		pet.PhotoUrls = append(pet.PhotoUrls, photoUrl.Url)
+	petIds := make([]int, 0, len(pets))
+	for _, pet := range pets {
+		petIds = append(petIds, pet.Id)
 	}
 
-	// get pet tag pairs
-	petTagPairs, err := s.DB.GetPetTagPairsByPetId(ctx, pet.Id)
+	// get photo urls along with their thumbnails
+	photoUrls, err := s.DB.GetPhotoUrlsByPetIds(ctx, petIds)
 	if err != nil {
-		return entities.Pet{}, e.Wrap("failed to get pet tag pairs", err)
+		return e.Wrap("failed to get photo urls", err)
 	}
 
-	// get tags for current pet
-	pet.Tags = make([]entities.Tag, 0)
-	for _, pt := range petTagPairs {
-		var tag entities.Tag
-		if tag, err = s.DB.GetTagById(ctx, pt.TagId); err != nil {
-			return entities.Pet{}, e.Wrap("failed to get tag", err)
+	// get tags
+	tags, err := s.DB.GetTagsByPetIds(ctx, petIds)
+	if err != nil {
+		return e.Wrap("failed to get pet tags", err)
+	}
+
+	for i := range pets {
+		photos := photoUrls[pets[i].Id]
+
+		pets[i].PhotoUrls = make([]string, 0, len(photos))
+		pets[i].Photos = make([]entities.PhotoUrl, 0, len(photos))
+		for _, photo := range photos {
+			pets[i].PhotoUrls = append(pets[i].PhotoUrls, photo.Url)
+			pets[i].Photos = append(pets[i].Photos, photo)
 		}
-		pet.Tags = append(pet.Tags, tag)
+
+		pets[i].Tags = make([]entities.Tag, 0, len(tags[pets[i].Id]))
+		pets[i].Tags = append(pets[i].Tags, tags[pets[i].Id]...)
 	}
 
-	return pet, nil
+	return nil
+}
+
+// withTx runs fn with a copy of the service whose repository is bound to a single transaction
+func (s *PetService) withTx(ctx context.Context, fn func(tx *PetService) error) error {
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		tx := *s
+		tx.DB = repo
+		return fn(&tx)
+	})
 }
 
 func (s *PetService) UpdateWithForm(ctx context.Context, id int, name string, status string) error {
+	return s.withTx(ctx, func(tx *PetService) error {
+		return tx.updateWithForm(ctx, id, name, status)
+	})
+}
+
+func (s *PetService) updateWithForm(ctx context.Context, id int, name string, status string) error {
 	pet, err := s.GetById(ctx, id)
 	if err != nil {
 		return e.Wrap("couldn't get pet", err)
 	}
 
 	if name == "" && status == "" {
-		return errors.New("name and status can not be both empty")
+		return e.Validation("name and status can not be both empty")
 	}
 
 	if name != "" {
@@ -72,6 +143,7 @@ func (s *PetService) UpdateWithForm(ctx context.Context, id int, name string, st
 	if status != "" {
 		pet.Status = status
 	}
+	pet.UpdatedBy = actor(ctx)
 
 	if err = s.DB.UpdatePet(ctx, pet); err != nil {
 		return e.Wrap("couldn't update pet", err)
@@ -81,6 +153,12 @@ func (s *PetService) UpdateWithForm(ctx context.Context, id int, name string, st
 }
 
 func (s *PetService) Delete(ctx context.Context, id int) error {
+	return s.withTx(ctx, func(tx *PetService) error {
+		return tx.delete(ctx, id)
+	})
+}
+
+func (s *PetService) delete(ctx context.Context, id int) error {
 	if _, err := s.GetById(ctx, id); err != nil {
 		return e.Wrap("couldn't get pet", err)
 	}
@@ -100,14 +178,21 @@ func (s *PetService) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *PetService) Create(ctx context.Context, pet entities.Pet) (int, error) {
-	// check required fields
-	if pet.Name == "" || pet.Status == "" || pet.Category.Name == "" {
-		return 0, errors.New("pet name or pet status or category name is empty")
+func (s *PetService) Create(ctx context.Context, pet entities.Pet) (petId int, err error) {
+	err = s.withTx(ctx, func(tx *PetService) error {
+		petId, err = tx.create(ctx, pet)
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	if !s.statusIsValid(pet.Status) {
-		return 0, errors.New("invalid status")
+	return petId, nil
+}
+
+func (s *PetService) create(ctx context.Context, pet entities.Pet) (int, error) {
+	if err := validate.Struct(pet); err != nil {
+		return 0, err
 	}
 
 	// handle pet category
@@ -124,7 +209,7 @@ func (s *PetService) Create(ctx context.Context, pet entities.Pet) (int, error)
 	}
 
 	// create pet and update pet id
-	petId, err := s.DB.CreatePet(ctx, pet.Category.Id, pet.Name, pet.Status)
+	petId, err := s.DB.CreatePet(ctx, pet.Category.Id, pet.Name, pet.Status, actor(ctx))
 	if err != nil {
 		return 0, e.Wrap("couldn't create pet", err)
 	}
@@ -132,7 +217,7 @@ func (s *PetService) Create(ctx context.Context, pet entities.Pet) (int, error)
 
 	// save photo urls
 	for _, url := range pet.PhotoUrls {
-		if err = s.DB.CreatePetPhotoUrl(ctx, pet.Id, url); err != nil {
+		if _, err = s.DB.CreatePetPhotoUrl(ctx, pet.Id, url); err != nil {
 			return 0, e.Wrap("couldn't create pet photo url", err)
 		}
 	}
@@ -163,6 +248,12 @@ func (s *PetService) Create(ctx context.Context, pet entities.Pet) (int, error)
 }
 
 func (s *PetService) Update(ctx context.Context, petUpdate entities.Pet) error {
+	return s.withTx(ctx, func(tx *PetService) error {
+		return tx.update(ctx, petUpdate)
+	})
+}
+
+func (s *PetService) update(ctx context.Context, petUpdate entities.Pet) error {
 	pet, err := s.GetById(ctx, petUpdate.Id)
 	if err != nil {
 		return e.Wrap("couldn't get pet", err)
@@ -177,7 +268,7 @@ func (s *PetService) Update(ctx context.Context, petUpdate entities.Pet) error {
 	}
 
 	if !s.statusIsValid(pet.Status) {
-		return errors.New("invalid status")
+		return e.Validation("invalid status")
 	}
 
 	if petUpdate.Category.Name != "" {
@@ -197,6 +288,7 @@ func (s *PetService) Update(ctx context.Context, petUpdate entities.Pet) error {
 	}
 
 	// update pet
+	pet.UpdatedBy = actor(ctx)
 	if err = s.DB.UpdatePet(ctx, pet); err != nil {
 		return e.Wrap("couldn't update pet", err)
 	}
@@ -215,7 +307,7 @@ func (s *PetService) Update(ctx context.Context, petUpdate entities.Pet) error {
 			if url == "" || url == "string" {
 				continue
 			}
-			if err = s.DB.CreatePetPhotoUrl(ctx, pet.Id, url); err != nil {
+			if _, err = s.DB.CreatePetPhotoUrl(ctx, pet.Id, url); err != nil {
 				return e.Wrap("couldn't create pet photo url", err)
 			}
 		}
@@ -254,7 +346,7 @@ func (s *PetService) Update(ctx context.Context, petUpdate entities.Pet) error {
 
 func (s *PetService) GetByStatus(ctx context.Context, status string) ([]entities.Pet, error) {
 	if !s.statusIsValid(status) {
-		return nil, errors.New("invalid status")
+		return nil, e.Validation("invalid status")
 	}
 
 	pets, err := s.DB.GetPetsByStatus(ctx, status)
@@ -262,18 +354,218 @@ func (s *PetService) GetByStatus(ctx context.Context, status string) ([]entities
 		return nil, err
 	}
 
-	result := make([]entities.Pet, 0, len(pets))
-	for _, p := range pets {
-		var pet entities.Pet
-		if pet, err = s.GetById(ctx, p.Id); err != nil {
-			return nil, e.Wrap("couldn't get pet by id", err)
+	if err = s.hydrate(ctx, pets); err != nil {
+		return nil, err
+	}
+
+	return pets, nil
+}
+
+func (s *PetService) GetByTags(ctx context.Context, tags []string, matchAll bool) ([]entities.Pet, error) {
+	if len(tags) == 0 {
+		return nil, e.Validation("at least one tag must be specified")
+	}
+
+	pets, err := s.DB.GetPetsByTags(ctx, tags, matchAll)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.hydrate(ctx, pets); err != nil {
+		return nil, err
+	}
+
+	return pets, nil
+}
+
+func (s *PetService) List(ctx context.Context, filter entities.PetFilter) (entities.PetPage, error) {
+	for _, status := range filter.Statuses {
+		if !s.statusIsValid(status) {
+			return entities.PetPage{}, e.Validation("invalid status " + status)
+		}
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = "id"
+	case "id", "name", "createdAt":
+	default:
+		return entities.PetPage{}, e.Validation("invalid sort key " + filter.SortBy)
+	}
+
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return entities.PetPage{}, e.Validation("limit and offset can not be negative")
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+	filter.Limit = min(filter.Limit, maxPageSize)
+
+	pets, total, err := s.DB.ListPets(ctx, filter)
+	if err != nil {
+		return entities.PetPage{}, e.Wrap("couldn't list pets", err)
+	}
+
+	if err = s.hydrate(ctx, pets); err != nil {
+		return entities.PetPage{}, err
+	}
+
+	page := entities.PetPage{
+		Items:  pets,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	return page, nil
+}
+
+func (s *PetService) UploadImage(ctx context.Context, petId int, upload entities.ImageUpload) (entities.PetImage, error) {
+	if s.storage == nil {
+		return entities.PetImage{}, e.Internal("image storage is not configured", nil)
+	}
+
+	if _, err := s.DB.GetPetById(ctx, petId); err != nil {
+		return entities.PetImage{}, e.Wrap("couldn't get pet", err)
+	}
+
+	processed, err := s.images.Process(upload.File)
+	if err != nil {
+		return entities.PetImage{}, e.Wrap("invalid image", err)
+	}
+
+	baseName, err := s.newImageName()
+	if err != nil {
+		return entities.PetImage{}, e.Wrap("couldn't generate image name", err)
+	}
+
+	// remove everything saved so far if any of the steps below fails
+	var savedKeys []string
+	cleanup := func() {
+		for _, key := range savedKeys {
+			_ = s.storage.Delete(ctx, key)
 		}
-		result = append(result, pet)
 	}
 
-	return result, nil
+	save := func(imageName string, img imaging.Image, metadata map[string]string) (entities.Thumbnail, error) {
+		key := s.imageKey(petId, imageName)
+		info := storage.ObjectInfo{ContentType: img.ContentType, Metadata: metadata}
+		if _, err := s.storage.Put(ctx, key, bytes.NewReader(img.Data), info); err != nil {
+			return entities.Thumbnail{}, err
+		}
+		savedKeys = append(savedKeys, key)
+
+		return entities.Thumbnail{Width: img.Width, Height: img.Height, Url: s.imageUrl(petId, imageName)}, nil
+	}
+
+	var metadata map[string]string
+	if upload.AdditionalMetadata != "" {
+		metadata = map[string]string{"additionalMetadata": upload.AdditionalMetadata}
+	}
+
+	original, err := save(baseName+processed.Original.Ext, processed.Original, metadata)
+	if err != nil {
+		cleanup()
+		return entities.PetImage{}, e.Wrap("couldn't save image", err)
+	}
+
+	image := entities.PetImage{
+		PetId:              petId,
+		Url:                original.Url,
+		ContentType:        processed.Original.ContentType,
+		Size:               int64(len(processed.Original.Data)),
+		Width:              original.Width,
+		Height:             original.Height,
+		AdditionalMetadata: upload.AdditionalMetadata,
+		Thumbnails:         make([]entities.Thumbnail, 0, len(processed.Thumbnails)),
+	}
+
+	for _, t := range processed.Thumbnails {
+		thumbnail, err := save(baseName+"_"+t.Name+t.Ext, t.Image, map[string]string{"variant": t.Name})
+		if err != nil {
+			cleanup()
+			return entities.PetImage{}, e.Wrap("couldn't save thumbnail", err)
+		}
+		thumbnail.Name = t.Name
+		image.Thumbnails = append(image.Thumbnails, thumbnail)
+	}
+
+	// record the photo and its thumbnails together so that a failure leaves no partial rows behind
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		photoUrlId, err := repo.CreatePetPhotoUrl(ctx, petId, image.Url)
+		if err != nil {
+			return e.Wrap("couldn't create pet photo url", err)
+		}
+
+		for i := range image.Thumbnails {
+			image.Thumbnails[i].PhotoUrlId = photoUrlId
+			if image.Thumbnails[i].Id, err = repo.CreatePhotoThumbnail(ctx, image.Thumbnails[i]); err != nil {
+				return e.Wrap("couldn't create photo thumbnail", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		cleanup()
+		return entities.PetImage{}, err
+	}
+
+	return image, nil
+}
+
+func (s *PetService) GetImage(ctx context.Context, petId int, imageName string) (io.ReadCloser, entities.PetImage, error) {
+	if s.storage == nil {
+		return nil, entities.PetImage{}, e.Internal("image storage is not configured", nil)
+	}
+
+	if imageName == "" || strings.ContainsAny(imageName, "/\\") || strings.HasPrefix(imageName, ".") {
+		return nil, entities.PetImage{}, e.NotFound("image not found")
+	}
+
+	file, info, err := s.storage.Get(ctx, s.imageKey(petId, imageName))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, entities.PetImage{}, e.NotFound("image not found")
+	} else if err != nil {
+		return nil, entities.PetImage{}, e.Internal("couldn't get image", err)
+	}
+
+	image := entities.PetImage{
+		PetId:              petId,
+		Url:                s.imageUrl(petId, imageName),
+		ContentType:        info.ContentType,
+		Size:               info.Size,
+		AdditionalMetadata: info.Metadata["additionalMetadata"],
+	}
+
+	return file, image, nil
+}
+
+func (s *PetService) imageKey(petId int, imageName string) string {
+	return fmt.Sprintf("pets/%d/%s", petId, imageName)
+}
+
+func (s *PetService) imageUrl(petId int, imageName string) string {
+	return fmt.Sprintf("%s/pet/%d/images/%s", s.imageBaseUrl, petId, imageName)
+}
+
+// newImageName generates a random name for an uploaded image, without extension
+func (s *PetService) newImageName() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
 }
 
 func (s *PetService) statusIsValid(status string) bool {
 	return status == "available" || status == "pending" || status == "sold"
 }
+
+// actor returns who is making the call, as recorded in the pet audit fields
+func actor(ctx context.Context) string {
+	principal, _ := as.PrincipalFromContext(ctx)
+	return principal.Actor()
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"backend/internal/modules/pet/entities"
 	"context"
+	"io"
 )
 
 //go:generate mockgen -source=./interface.go -destination=../../../mocks/mock_pet_service/mock_pet_service.go
@@ -14,4 +15,8 @@ type PetServicer interface {
 	Create(ctx context.Context, pet entities.Pet) (int, error)
 	Update(ctx context.Context, pet entities.Pet) error
 	GetByStatus(ctx context.Context, status string) ([]entities.Pet, error)
+	GetByTags(ctx context.Context, tags []string, matchAll bool) ([]entities.Pet, error)
+	List(ctx context.Context, filter entities.PetFilter) (entities.PetPage, error)
+	UploadImage(ctx context.Context, petId int, upload entities.ImageUpload) (entities.PetImage, error)
+	GetImage(ctx context.Context, petId int, imageName string) (io.ReadCloser, entities.PetImage, error)
 }
@@ -1,11 +1,23 @@
 package service
 
 import (
+	"backend/internal/lib/imaging"
 	"backend/internal/mocks/mock_repository"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/pet/entities"
+	"backend/internal/repository"
+	"backend/internal/storage/filestore"
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
+	goimage "image"
+	"image/png"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -28,7 +40,7 @@ func TestPetService_GetById(t *testing.T) {
 		{"unknown category", 4, true},
 		{"no photos", 5, false},
 		{"no tag pairs", 6, false},
-		{"no tag ", 7, true},
+		{"unknown tag is skipped", 7, false},
 	}
 
 	controller := gomock.NewController(t)
@@ -232,28 +244,448 @@ func TestGetPetById(t *testing.T) {
 	}
 }
 
+func TestPetService_GetByTags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tags     []string
+		matchAll bool
+		wantErr  bool
+		wantIds  []int
+	}{
+		{"any tag", []string{"fluffy", "calm"}, false, false, []int{1, 2, 5}},
+		{"all tags", []string{"fluffy", "calm"}, true, false, []int{}},
+		{"single tag", []string{"calm"}, true, false, []int{2, 5}},
+		{"no tags", nil, false, true, nil},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	ps := NewPetService(mockRepo)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pets, err := ps.GetByTags(context.Background(), tc.tags, tc.matchAll)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetByTags() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			ids := make([]int, 0, len(pets))
+			for _, pet := range pets {
+				ids = append(ids, pet.Id)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIds) {
+				t.Errorf("GetByTags() ids = %v, want %v", ids, tc.wantIds)
+			}
+		})
+	}
+}
+
+func TestPetService_List(t *testing.T) {
+	testCases := []struct {
+		name      string
+		filter    entities.PetFilter
+		wantErr   bool
+		wantLimit int
+		wantIds   []int
+	}{
+		{"normal case", entities.PetFilter{}, false, 20, []int{1, 2, 3, 5, 6, 7}},
+		{"by status", entities.PetFilter{Statuses: []string{"available", "sold"}}, false, 20, []int{1, 3, 5}},
+		{"second page", entities.PetFilter{Limit: 2, Offset: 2}, false, 2, []int{3, 5}},
+		{"limit is capped", entities.PetFilter{Limit: 1000}, false, 100, []int{1, 2, 3, 5, 6, 7}},
+		{"invalid status", entities.PetFilter{Statuses: []string{"unknown"}}, true, 0, nil},
+		{"invalid sort key", entities.PetFilter{SortBy: "age"}, true, 0, nil},
+		{"negative offset", entities.PetFilter{Offset: -1}, true, 0, nil},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	ps := NewPetService(mockRepo)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := ps.List(context.Background(), tc.filter)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if page.Limit != tc.wantLimit {
+				t.Errorf("List() limit = %d, want %d", page.Limit, tc.wantLimit)
+			}
+
+			ids := make([]int, 0, len(page.Items))
+			for _, pet := range page.Items {
+				ids = append(ids, pet.Id)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIds) {
+				t.Errorf("List() ids = %v, want %v", ids, tc.wantIds)
+			}
+		})
+	}
+}
+
+func TestPetService_UploadImage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		petId          int
+		upload         entities.ImageUpload
+		content        []byte
+		wantErr        bool
+		wantThumbnails []string
+	}{
+		{"normal case", 1, entities.ImageUpload{FileName: "cat.png", AdditionalMetadata: "front view"}, testImage(t, 800, 600), false, []string{"128", "512"}},
+		{"small image", 1, entities.ImageUpload{FileName: "cat.png"}, testImage(t, 64, 64), false, []string{"128", "512"}},
+		{"unknown pet", 100, entities.ImageUpload{FileName: "cat.png"}, testImage(t, 64, 64), true, nil},
+		{"not an image", 1, entities.ImageUpload{FileName: "cat.png", ContentType: "image/png"}, []byte("definitely not an image"), true, nil},
+		{"too large", 1, entities.ImageUpload{FileName: "cat.png"}, make([]byte, 2<<20), true, nil},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	fs, err := filestore.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockRepo := NewMockRepository(controller)
+	ps := NewPetService(mockRepo,
+		WithImageStorage(fs, "http://localhost:8888"),
+		WithImageProcessor(imaging.NewProcessor(imaging.WithMaxBytes(1<<20))),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.upload.File = bytes.NewReader(tc.content)
+
+			image, err := ps.UploadImage(context.Background(), tc.petId, tc.upload)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("UploadImage() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if !strings.HasPrefix(image.Url, "http://localhost:8888/pet/1/images/") || image.ContentType != "image/png" {
+				t.Errorf("UploadImage() image = %+v", image)
+			}
+
+			if len(image.Thumbnails) != len(tc.wantThumbnails) {
+				t.Fatalf("UploadImage() thumbnails = %d, want %d", len(image.Thumbnails), len(tc.wantThumbnails))
+			}
+
+			for i, thumbnail := range image.Thumbnails {
+				if thumbnail.Name != tc.wantThumbnails[i] || thumbnail.PhotoUrlId != 1 {
+					t.Errorf("UploadImage() thumbnail = %+v", thumbnail)
+				}
+
+				imageName := thumbnail.Url[strings.LastIndex(thumbnail.Url, "/")+1:]
+				file, _, err := ps.GetImage(context.Background(), tc.petId, imageName)
+				if err != nil {
+					t.Fatalf("GetImage() error = %v", err)
+				}
+
+				config, _, err := goimage.DecodeConfig(file)
+				_ = file.Close()
+				if err != nil || config.Width != thumbnail.Width || config.Height != thumbnail.Height {
+					t.Errorf("GetImage() thumbnail %s is %dx%d, want %dx%d", thumbnail.Name, config.Width, config.Height, thumbnail.Width, thumbnail.Height)
+				}
+			}
+
+			imageName := image.Url[strings.LastIndex(image.Url, "/")+1:]
+			file, stored, err := ps.GetImage(context.Background(), tc.petId, imageName)
+			if err != nil {
+				t.Fatalf("GetImage() error = %v", err)
+			}
+			defer file.Close()
+
+			if stored.AdditionalMetadata != tc.upload.AdditionalMetadata || stored.Size != image.Size {
+				t.Errorf("GetImage() image = %+v", stored)
+			}
+		})
+	}
+
+	t.Run("invalid image name", func(t *testing.T) {
+		if _, _, err := ps.GetImage(context.Background(), 1, "../../etc/passwd"); err == nil {
+			t.Errorf("GetImage() error = %v, wantErr %v", err, true)
+		}
+	})
+
+	t.Run("no storage", func(t *testing.T) {
+		upload := entities.ImageUpload{FileName: "cat.png", File: bytes.NewReader(testImage(t, 64, 64))}
+		if _, err := NewPetService(mockRepo).UploadImage(context.Background(), 1, upload); err == nil {
+			t.Errorf("UploadImage() error = %v, wantErr %v", err, true)
+		}
+	})
+}
+
+func TestPetService_Transactions(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	// fail halfway through writing the pet so that the transaction has to be rolled back
+	mockRepo := NewMockRepository(controller)
+	failingRepo := mock_repository.NewMockRepository(controller)
+	failingRepo.EXPECT().GetPetCategoryByName(gomock.Any(), gomock.Any()).Return(entities.Category{Id: 1, Name: "cat"}, nil).AnyTimes()
+	failingRepo.EXPECT().CreatePet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+	failingRepo.EXPECT().GetTagByName(gomock.Any(), gomock.Any()).Return(entities.Tag{Id: 1, Name: "fluffy"}, nil).AnyTimes()
+	failingRepo.EXPECT().CreatePetTagPair(gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.PetTag{}, errors.New("connection lost")).AnyTimes()
+
+	var committed, rolledBack int
+	var txTarget repository.Repository = mockRepo
+	txRepo := mock_repository.NewMockRepository(controller)
+	txRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+		if err := fn(txTarget); err != nil {
+			rolledBack++
+			return err
+		}
+		committed++
+		return nil
+	}).AnyTimes()
+
+	ps := NewPetService(txRepo)
+	pet := entities.Pet{Name: "Pet", Category: entities.Category{Name: "cat"}, Tags: []entities.Tag{{Name: "fluffy"}}, Status: "available"}
+
+	t.Run("committed", func(t *testing.T) {
+		if _, err := ps.Create(context.Background(), pet); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := ps.Delete(context.Background(), 1); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if committed != 2 || rolledBack != 0 {
+			t.Errorf("committed = %d, rolled back = %d, want 2 and 0", committed, rolledBack)
+		}
+	})
+
+	t.Run("rolled back", func(t *testing.T) {
+		txTarget = failingRepo
+		if _, err := ps.Create(context.Background(), pet); err == nil {
+			t.Fatalf("Create() error = %v, wantErr %v", err, true)
+		}
+		if committed != 2 || rolledBack != 1 {
+			t.Errorf("committed = %d, rolled back = %d, want 2 and 1", committed, rolledBack)
+		}
+	})
+}
+
+func TestPetService_AuditFields(t *testing.T) {
+	testCases := []struct {
+		name      string
+		principal *ae.Principal
+		want      string
+	}{
+		{"user", &ae.Principal{UserId: 2, Username: "jenstar", Roles: []ae.Role{ae.RoleStaff}}, "jenstar"},
+		{"client", &ae.Principal{Username: "inventory-sync", ClientId: "inventory-sync"}, "client:inventory-sync"},
+		{"anonymous", nil, ""},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	var createdBy string
+	mockRepo := mock_repository.NewMockRepository(controller)
+	mockRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+		return fn(mockRepo)
+	}).AnyTimes()
+	mockRepo.EXPECT().GetPetCategoryByName(gomock.Any(), gomock.Any()).Return(entities.Category{Id: 1, Name: "cat"}, nil).AnyTimes()
+	mockRepo.EXPECT().CreatePet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ int, _ string, _ string, by string) (int, error) {
+			createdBy = by
+			return 1, nil
+		}).AnyTimes()
+
+	ps := NewPetService(mockRepo)
+	pet := entities.Pet{Name: "Pet", Category: entities.Category{Name: "cat"}, Status: "available"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.principal != nil {
+				ctx = as.NewContext(ctx, *tc.principal)
+			}
+
+			if _, err := ps.Create(ctx, pet); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if createdBy != tc.want {
+				t.Errorf("Create() created by = %q, want %q", createdBy, tc.want)
+			}
+		})
+	}
+}
+
+func TestPetService_QueryCount(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	// every read path issues the same number of queries however many pets, photos and tags there are
+	for _, size := range []struct{ pets, tags int }{{1, 1}, {10, 10}, {100, 50}} {
+		queries := 0
+		ps := NewPetService(newCountingRepository(controller, size.pets, size.tags, &queries))
+
+		t.Run(fmt.Sprintf("get by id/%d tags", size.tags), func(t *testing.T) {
+			queries = 0
+			pet, err := ps.GetById(context.Background(), 1)
+			if err != nil || len(pet.Tags) != size.tags || len(pet.Photos) != 1 {
+				t.Fatalf("GetById() pet = %+v, error = %v", pet, err)
+			}
+			if queries != 3 {
+				t.Errorf("GetById() queries = %d, want %d", queries, 3)
+			}
+		})
+
+		t.Run(fmt.Sprintf("get by status/%d pets", size.pets), func(t *testing.T) {
+			queries = 0
+			pets, err := ps.GetByStatus(context.Background(), "available")
+			if err != nil || len(pets) != size.pets {
+				t.Fatalf("GetByStatus() pets = %d, error = %v", len(pets), err)
+			}
+			if queries != 3 {
+				t.Errorf("GetByStatus() queries = %d, want %d", queries, 3)
+			}
+		})
+
+		t.Run(fmt.Sprintf("list/%d pets", size.pets), func(t *testing.T) {
+			queries = 0
+			page, err := ps.List(context.Background(), entities.PetFilter{Limit: maxPageSize})
+			if err != nil || len(page.Items) != size.pets {
+				t.Fatalf("List() items = %d, error = %v", len(page.Items), err)
+			}
+			if queries != 3 {
+				t.Errorf("List() queries = %d, want %d", queries, 3)
+			}
+		})
+	}
+}
+
+func BenchmarkPetService_List(b *testing.B) {
+	controller := gomock.NewController(b)
+	defer controller.Finish()
+
+	for _, size := range []struct{ pets, tags int }{{1, 1}, {10, 10}, {100, 10}, {100, 100}} {
+		b.Run(fmt.Sprintf("%d pets/%d tags", size.pets, size.tags), func(b *testing.B) {
+			queries := 0
+			ps := NewPetService(newCountingRepository(controller, size.pets, size.tags, &queries))
+			filter := entities.PetFilter{Limit: maxPageSize}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ps.List(context.Background(), filter); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			if perOp := float64(queries) / float64(b.N); perOp != 3 {
+				b.Errorf("List() queries per op = %v, want %v", perOp, 3)
+			}
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// newCountingRepository mocks the read path for the given number of available pets,
+// each with one photo and tagsPerPet tags, counting every repository call in queries
+func newCountingRepository(controller *gomock.Controller, pets, tagsPerPet int, queries *int) *mock_repository.MockRepository {
+	mockRepo := mock_repository.NewMockRepository(controller)
+
+	mockPets := make([]entities.Pet, 0, pets)
+	for i := 1; i <= pets; i++ {
+		mockPets = append(mockPets, entities.Pet{
+			Id:       i,
+			Category: entities.Category{Id: 1, Name: "cat"},
+			Name:     fmt.Sprintf("Pet %d", i),
+			Status:   "available",
+		})
+	}
+
+	mockRepo.EXPECT().GetPetById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (entities.Pet, error) {
+		*queries++
+		if id < 1 || id > len(mockPets) {
+			return entities.Pet{}, errors.New("pet not found")
+		}
+		return mockPets[id-1], nil
+	}).AnyTimes()
+
+	mockRepo.EXPECT().GetPetsByStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string) ([]entities.Pet, error) {
+		*queries++
+		return slices.Clone(mockPets), nil
+	}).AnyTimes()
+
+	mockRepo.EXPECT().ListPets(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, filter entities.PetFilter) ([]entities.Pet, int, error) {
+		*queries++
+		return slices.Clone(mockPets[:min(filter.Limit, len(mockPets))]), len(mockPets), nil
+	}).AnyTimes()
+
+	mockRepo.EXPECT().GetPhotoUrlsByPetIds(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, ids []int) (map[int][]entities.PhotoUrl, error) {
+		*queries++
+		result := make(map[int][]entities.PhotoUrl, len(ids))
+		for _, id := range ids {
+			result[id] = []entities.PhotoUrl{{Id: id, PetId: id, Url: fmt.Sprintf("https://example.com/%d.jpg", id)}}
+		}
+		return result, nil
+	}).AnyTimes()
+
+	mockRepo.EXPECT().GetTagsByPetIds(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, ids []int) (map[int][]entities.Tag, error) {
+		*queries++
+		result := make(map[int][]entities.Tag, len(ids))
+		for _, id := range ids {
+			for j := 1; j <= tagsPerPet; j++ {
+				result[id] = append(result[id], entities.Tag{Id: j, Name: fmt.Sprintf("tag%d", j)})
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+
+	return mockRepo
+}
+
+func testImage(t *testing.T, width, height int) []byte {
+	img := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
 func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepository {
 	mockRepo := mock_repository.NewMockRepository(controller)
 	mocks := generateMocks()
 
+	mockRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+		return fn(mockRepo)
+	}).AnyTimes()
+
+	// pets are joined with their categories, so a pet with an unknown category is never returned
+	hasCategory := func(pet entities.Pet) bool {
+		return slices.Contains(mocks.Categories, pet.Category)
+	}
+
 	mockRepo.EXPECT().GetPetById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (entities.Pet, error) {
 		for _, pet := range mocks.Pets {
-			if pet.Id == id {
+			if pet.Id == id && hasCategory(pet) {
 				return pet, nil
 			}
 		}
 		return entities.Pet{}, errors.New("pet not found")
 	}).AnyTimes()
 
-	mockRepo.EXPECT().GetPetCategoryById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (entities.Category, error) {
-		for _, cat := range mocks.Categories {
-			if cat.Id == id {
-				return cat, nil
-			}
-		}
-		return entities.Category{}, errors.New("category not found")
-	}).AnyTimes()
-
 	mockRepo.EXPECT().GetPetCategoryByName(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) (entities.Category, error) {
 		for _, cat := range mocks.Categories {
 			if cat.Name == name {
@@ -263,35 +695,28 @@ func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepos
 		return entities.Category{}, errors.New("category not found")
 	}).AnyTimes()
 
-	mockRepo.EXPECT().GetPhotoUrlsByPetId(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) ([]entities.PhotoUrl, error) {
-		result := make([]entities.PhotoUrl, 0)
+	mockRepo.EXPECT().GetPhotoUrlsByPetIds(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, ids []int) (map[int][]entities.PhotoUrl, error) {
+		result := make(map[int][]entities.PhotoUrl)
 		for _, photoUrl := range mocks.PhotoUrls {
-			if photoUrl.PetId == id {
-				result = append(result, photoUrl)
+			if slices.Contains(ids, photoUrl.PetId) {
+				result[photoUrl.PetId] = append(result[photoUrl.PetId], photoUrl)
 			}
 		}
 		return result, nil
 	}).AnyTimes()
 
-	mockRepo.EXPECT().GetPetTagPairsByPetId(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) ([]entities.PetTag, error) {
-		result := make([]entities.PetTag, 0)
+	mockRepo.EXPECT().GetTagsByPetIds(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, ids []int) (map[int][]entities.Tag, error) {
+		result := make(map[int][]entities.Tag)
 		for _, petTag := range mocks.PetTags {
-			if petTag.PetId == id {
-				result = append(result, petTag)
+			for _, tag := range mocks.Tags {
+				if tag.Id == petTag.TagId && slices.Contains(ids, petTag.PetId) {
+					result[petTag.PetId] = append(result[petTag.PetId], tag)
+				}
 			}
 		}
 		return result, nil
 	}).AnyTimes()
 
-	mockRepo.EXPECT().GetTagById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (entities.Tag, error) {
-		for _, tag := range mocks.Tags {
-			if tag.Id == id {
-				return tag, nil
-			}
-		}
-		return entities.Tag{}, errors.New("tag not found")
-	}).AnyTimes()
-
 	mockRepo.EXPECT().GetTagByName(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) (entities.Tag, error) {
 		for _, tag := range mocks.Tags {
 			if tag.Name == name {
@@ -313,16 +738,52 @@ func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepos
 
 	mockRepo.EXPECT().CreatePetCategory(gomock.Any(), gomock.Any()).Return(entities.Category{}, nil).AnyTimes()
 
-	mockRepo.EXPECT().CreatePet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+	mockRepo.EXPECT().CreatePet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+
+	mockRepo.EXPECT().CreatePetPhotoUrl(gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
 
-	mockRepo.EXPECT().CreatePetPhotoUrl(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockRepo.EXPECT().CreatePhotoThumbnail(gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
 
 	mockRepo.EXPECT().CreatePetTagPair(gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.PetTag{}, nil).AnyTimes()
 
+	mockRepo.EXPECT().ListPets(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, filter entities.PetFilter) ([]entities.Pet, int, error) {
+		matched := make([]entities.Pet, 0)
+		for _, pet := range mocks.Pets {
+			if !hasCategory(pet) {
+				continue
+			}
+			if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, pet.Status) {
+				continue
+			}
+			matched = append(matched, pet)
+		}
+
+		result := matched[min(filter.Offset, len(matched)):min(filter.Offset+filter.Limit, len(matched))]
+		return result, len(matched), nil
+	}).AnyTimes()
+
+	mockRepo.EXPECT().GetPetsByTags(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tagNames []string, matchAll bool) ([]entities.Pet, error) {
+		result := make([]entities.Pet, 0)
+		for _, pet := range mocks.Pets {
+			matched := 0
+			for _, pt := range mocks.PetTags {
+				for _, tag := range mocks.Tags {
+					if pt.PetId == pet.Id && pt.TagId == tag.Id && slices.Contains(tagNames, tag.Name) {
+						matched++
+					}
+				}
+			}
+			if hasCategory(pet) && matched > 0 && (!matchAll || matched == len(tagNames)) {
+				result = append(result, pet)
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+
 	mockRepo.EXPECT().GetPetsByStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, status string) ([]entities.Pet, error) {
 		result := make([]entities.Pet, 0)
 		for _, pet := range mocks.Pets {
-			if pet.Status == status {
+			if pet.Status == status && hasCategory(pet) {
 				result = append(result, pet)
 			}
 		}
@@ -345,60 +806,60 @@ func generateMocks() Mocks {
 func generateMockPets() []entities.Pet {
 	mockPets := []entities.Pet{
 		{
-			1,
-			entities.Category{Id: 1, Name: "cat"},
-			"Poppy",
-			[]string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
-			[]entities.Tag{{1, "fluffy"}},
-			"available",
+			Id:        1,
+			Category:  entities.Category{Id: 1, Name: "cat"},
+			Name:      "Poppy",
+			PhotoUrls: []string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
+			Tags:      []entities.Tag{{Id: 1, Name: "fluffy"}},
+			Status:    "available",
 		},
 		{
-			2,
-			entities.Category{Id: 2, Name: "dog"},
-			"Abby",
-			[]string{"https://wallpapers.com/images/hd/dog-pictures-os09dhwexb80d990.jpg"},
-			[]entities.Tag{{2, "calm"}},
-			"pending",
+			Id:        2,
+			Category:  entities.Category{Id: 2, Name: "dog"},
+			Name:      "Abby",
+			PhotoUrls: []string{"https://wallpapers.com/images/hd/dog-pictures-os09dhwexb80d990.jpg"},
+			Tags:      []entities.Tag{{Id: 2, Name: "calm"}},
+			Status:    "pending",
 		},
 		{
-			3,
-			entities.Category{Id: 3, Name: "rodent"},
-			"Basil",
-			[]string{"https://i.pinimg.com/originals/59/df/fb/59dffb52f7435ce31979f7e03ce02ab4.jpg"},
-			[]entities.Tag{{3, "kind"}},
-			"sold",
+			Id:        3,
+			Category:  entities.Category{Id: 3, Name: "rodent"},
+			Name:      "Basil",
+			PhotoUrls: []string{"https://i.pinimg.com/originals/59/df/fb/59dffb52f7435ce31979f7e03ce02ab4.jpg"},
+			Tags:      []entities.Tag{{Id: 3, Name: "kind"}},
+			Status:    "sold",
 		},
 		{
-			4,
-			entities.Category{Id: 4, Name: "unknown"},
-			"Tilly",
-			[]string{"https://i.pinimg.com/originals/59/df/fb/59dffb52f7435ce31979f7e03ce02ab4.jpg"},
-			[]entities.Tag{{3, "kind"}},
-			"pending",
+			Id:        4,
+			Category:  entities.Category{Id: 4, Name: "unknown"},
+			Name:      "Tilly",
+			PhotoUrls: []string{"https://i.pinimg.com/originals/59/df/fb/59dffb52f7435ce31979f7e03ce02ab4.jpg"},
+			Tags:      []entities.Tag{{Id: 3, Name: "kind"}},
+			Status:    "pending",
 		},
 		{
-			5,
-			entities.Category{Id: 3, Name: "rodent"},
-			"Tilly",
-			[]string{""},
-			[]entities.Tag{{2, "calm"}},
-			"sold",
+			Id:        5,
+			Category:  entities.Category{Id: 3, Name: "rodent"},
+			Name:      "Tilly",
+			PhotoUrls: []string{""},
+			Tags:      []entities.Tag{{Id: 2, Name: "calm"}},
+			Status:    "sold",
 		},
 		{
-			6,
-			entities.Category{Id: 2, Name: "dog"},
-			"Rex",
-			[]string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
-			[]entities.Tag{{3, "calm"}},
-			"pending",
+			Id:        6,
+			Category:  entities.Category{Id: 2, Name: "dog"},
+			Name:      "Rex",
+			PhotoUrls: []string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
+			Tags:      []entities.Tag{{Id: 3, Name: "calm"}},
+			Status:    "pending",
 		},
 		{
-			7,
-			entities.Category{Id: 1, Name: "cat"},
-			"Rex",
-			[]string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
-			[]entities.Tag{{4, "unknown"}},
-			"pending",
+			Id:        7,
+			Category:  entities.Category{Id: 1, Name: "cat"},
+			Name:      "Rex",
+			PhotoUrls: []string{"https://wallbox.ru/wallpapers/main/201152/koshki-392426de15fb.jpg"},
+			Tags:      []entities.Tag{{Id: 4, Name: "unknown"}},
+			Status:    "pending",
 		},
 	}
 
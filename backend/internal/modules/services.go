@@ -1,11 +1,18 @@
 package modules
 
 import (
+	"backend/internal/lib/imaging"
+	"backend/internal/lib/oidc"
+	"backend/internal/lib/passwords"
+	"backend/internal/mail"
+	ae "backend/internal/modules/auth/entities"
 	au "backend/internal/modules/auth/service"
 	ps "backend/internal/modules/pet/service"
 	ss "backend/internal/modules/store/service"
 	us "backend/internal/modules/user/service"
 	"backend/internal/repository"
+	"backend/internal/storage"
+	"time"
 )
 
 type Services struct {
@@ -15,12 +22,86 @@ type Services struct {
 	Auth  au.AuthServicer
 }
 
-func NewServices(db repository.Repository, issuer, audience, secret, cookieDomain string) *Services {
-	authService := au.NewAuthService(issuer, audience, secret, cookieDomain)
+// TokenConfig identifies the tokens issued by the services
+type TokenConfig struct {
+	Issuer   string
+	Audience string
+	Secret   string // signs the tokens, optional with signing keys, see WithSigningKeys
+}
+
+type ServicesOption func(*servicesOptions)
+
+// servicesOptions collects the options of the single services
+type servicesOptions struct {
+	auth []au.AuthServiceOption
+	user []us.UserServiceOption
+	pet  []ps.PetServiceOption
+}
+
+// WithSigningKeys signs tokens with asymmetric keys, see au.WithSigningKeys
+func WithSigningKeys(keys ...au.SigningKey) ServicesOption {
+	return func(o *servicesOptions) {
+		o.auth = append(o.auth, au.WithSigningKeys(keys...))
+	}
+}
+
+// WithPasswordHasher sets how passwords are hashed, see au.WithPasswordHasher
+func WithPasswordHasher(hasher *passwords.Hasher) ServicesOption {
+	return func(o *servicesOptions) {
+		o.auth = append(o.auth, au.WithPasswordHasher(hasher))
+	}
+}
+
+// WithPasswordPolicy sets the rules new passwords are checked against, see us.WithPasswordPolicy
+func WithPasswordPolicy(policy *passwords.Policy) ServicesOption {
+	return func(o *servicesOptions) {
+		o.user = append(o.user, us.WithPasswordPolicy(policy))
+	}
+}
+
+// WithMailer enables email verification and password reset, see us.WithMailer
+func WithMailer(mailer mail.Mailer, linkBaseUrl string) ServicesOption {
+	return func(o *servicesOptions) {
+		o.user = append(o.user, us.WithMailer(mailer, linkBaseUrl))
+	}
+}
+
+// WithExternalLogin enables logging in through the OpenID provider, see us.WithExternalLogin
+func WithExternalLogin(provider *oidc.Provider, defaultRole ae.Role) ServicesOption {
+	return func(o *servicesOptions) {
+		o.user = append(o.user, us.WithExternalLogin(provider, defaultRole))
+	}
+}
+
+// WithImageProcessor sets how uploaded pet images are checked and resized, see ps.WithImageProcessor
+func WithImageProcessor(images *imaging.Processor) ServicesOption {
+	return func(o *servicesOptions) {
+		o.pet = append(o.pet, ps.WithImageProcessor(images))
+	}
+}
+
+// NewServices wires the services to the database. Pet images are kept in storage and served
+// under baseUrl, the public address of the API.
+func NewServices(db repository.Repository, storage storage.Storage, baseUrl string, tokens TokenConfig, options ...ServicesOption) *Services {
+	o := servicesOptions{
+		auth: []au.AuthServiceOption{
+			au.WithRevocationStore(au.NewRevocationStore(db, 10*time.Second)),
+			au.WithClientRepository(db),
+			au.WithAPIKeyRepository(db),
+		},
+		user: []us.UserServiceOption{us.WithLoginThrottle(au.NewLoginThrottle(db))},
+		pet:  []ps.PetServiceOption{ps.WithImageStorage(storage, baseUrl)},
+	}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	authService := au.NewAuthService(tokens.Issuer, tokens.Audience, tokens.Secret, o.auth...)
 
 	return &Services{
-		Pet:   ps.NewPetService(db),
-		User:  us.NewUserService(db, authService),
+		Pet:   ps.NewPetService(db, o.pet...),
+		User:  us.NewUserService(db, authService, o.user...),
 		Store: ss.NewStoreService(db),
 		Auth:  authService,
 	}
@@ -1,13 +1,13 @@
 package controller
 
 import (
+	"backend/internal/lib/e"
 	"backend/internal/lib/rr"
 	"backend/internal/mocks/mock_store_service"
 	"backend/internal/modules/store/entities"
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"net/http/httptest"
@@ -17,22 +17,34 @@ import (
 //go:generate mockgen -source=../service/interface.go -destination=../../../mocks/mock_store_service/mock_store_service.go
 
 func TestStoreControl_GetInventory(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"normal case", "", http.StatusOK},
+		{"by category", "?byCategory=true", http.StatusOK},
+		{"invalid parameter", "?byCategory=maybe", http.StatusBadRequest},
+	}
+
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
 	mockService := NewMockService(controller)
 	sc := NewStoreControl(mockService, rr.NewReadRespond())
 
-	req := httptest.NewRequest(http.MethodGet, "/store/inventory", nil)
-	wr := httptest.NewRecorder()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/store/inventory"+tc.query, nil)
+			wr := httptest.NewRecorder()
 
-	sc.GetInventory(wr, req)
+			sc.GetInventory(wr, req)
 
-	r := wr.Result()
+			r := wr.Result()
 
-	if r.StatusCode != http.StatusOK {
-		t.Run("normal case", func(t *testing.T) {
-			t.Errorf("want status code %d, got %d", http.StatusOK, r.StatusCode)
+			if r.StatusCode != tc.wantStatus {
+				t.Errorf("want status code %d, got %d", tc.wantStatus, r.StatusCode)
+			}
 		})
 	}
 }
@@ -44,7 +56,8 @@ func TestStoreControl_CreateOrder(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", entities.Order{PetId: 1, Quantity: 2}, http.StatusOK},
-		{"invalid case", entities.Order{PetId: 0, Quantity: 0}, http.StatusBadRequest},
+		{"invalid case", entities.Order{PetId: 0, Quantity: 0}, http.StatusUnprocessableEntity},
+		{"duplicate order", entities.Order{Id: 7, PetId: 1, Quantity: 2}, http.StatusConflict},
 	}
 
 	controller := gomock.NewController(t)
@@ -72,6 +85,11 @@ func TestStoreControl_CreateOrder(t *testing.T) {
 			if r.StatusCode != tc.wantStatus {
 				t.Errorf("CreateOrder(), expected status %d, got %d", tc.wantStatus, r.StatusCode)
 			}
+
+			// the client learns about the conflict, not about the constraint behind it
+			if r.StatusCode == http.StatusConflict && resp.Message != "couldn't create order: record already exists" {
+				t.Errorf("CreateOrder(), expected the conflict without its cause, got %q", resp.Message)
+			}
 		})
 	}
 }
@@ -147,19 +165,27 @@ func NewMockService(controller *gomock.Controller) *mock_service.MockStoreServic
 
 	mockService.EXPECT().GetOrderById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (entities.Order, error) {
 		if id < 1 || id > 9 {
-			return entities.Order{}, errors.New("order not found")
+			return entities.Order{}, e.NotFound("order not found")
 		}
 		return entities.Order{}, nil
 	}).AnyTimes()
 
 	mockService.EXPECT().CreateOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order entities.Order) (int, error) {
 		if order.PetId == 0 || order.Quantity == 0 {
-			return 0, errors.New("invalid order")
+			return 0, e.Validation("invalid order")
+		}
+		// order 7 exists already, the repository reports the unique violation as a conflict
+		if order.Id == 7 {
+			return 0, e.Conflict("record already exists")
 		}
 		return 1, nil
 	}).AnyTimes()
 
 	mockService.EXPECT().DeleteOrder(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
+	mockService.EXPECT().GetInventory(gomock.Any()).Return(entities.Inventory{"available": 1, "pending": 0, "sold": 0}, nil).AnyTimes()
+
+	mockService.EXPECT().GetInventoryByCategory(gomock.Any()).Return(entities.CategoryInventory{"cat": {"available": 1, "pending": 0, "sold": 0}}, nil).AnyTimes()
+
 	return mockService
 }
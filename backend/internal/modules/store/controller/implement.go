@@ -23,37 +23,64 @@ func NewStoreControl(service service.StoreServicer, readResponder rr.ReadRespond
 // GetInventory godoc
 // @Summary get inventory
 // @Security ApiKeyAuth
+// @Security UserApiKey
 // @Description Returns pet inventories
 // @Tags store
 // @Produce json
+// @Param byCategory query bool false "Break the counts down by pet category"
 // @Success 200 {object} entities.Inventory
+// @Failure 400,500 {object} rr.JSONResponse
 // @Router /store/inventory [get]
 func (s *StoreControl) GetInventory(w http.ResponseWriter, r *http.Request) {
-	payload := entities.Inventory{
-		"additionalProp1": 0,
-		"additionalProp2": 0,
-		"additionalProp3": 0,
+	byCategory := false
+	if param := r.URL.Query().Get("byCategory"); param != "" {
+		var err error
+		if byCategory, err = strconv.ParseBool(param); err != nil {
+			_ = s.rr.WriteError(w, r, e.Wrap("invalid byCategory supplied", err))
+			return
+		}
 	}
-	_ = s.rr.WriteJSON(w, 200, payload)
+
+	if byCategory {
+		inventory, err := s.service.GetInventoryByCategory(r.Context())
+		if err != nil {
+			_ = s.rr.WriteError(w, r, err)
+			return
+		}
+		_ = s.rr.WriteJSON(w, 200, inventory)
+		return
+	}
+
+	inventory, err := s.service.GetInventory(r.Context())
+	if err != nil {
+		_ = s.rr.WriteError(w, r, err)
+		return
+	}
+
+	_ = s.rr.WriteJSON(w, 200, inventory)
 }
 
 // CreateOrder godoc
 // @Summary create order
+// @Security ApiKeyAuth
 // @Description Place an order for a pet
 // @Tags store
 // @Accept json
 // @Produce json
 // @Param body body entities.Order true "Order placed for purchasing a pet"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,401,403,409,422,500 {object} rr.JSONResponse
 // @Router /store/order [post]
 func (s *StoreControl) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var order entities.Order
-	_ = s.rr.ReadJSON(w, r, &order)
+	if err := s.rr.ReadJSON(w, r, &order); err != nil {
+		_ = s.rr.WriteError(w, r, err)
+		return
+	}
 
 	orderId, err := s.service.CreateOrder(r.Context(), order)
 	if err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("couldn't create order", err))
+		_ = s.rr.WriteError(w, r, e.Wrap("couldn't create order", err))
 		return
 	}
 
@@ -63,25 +90,26 @@ func (s *StoreControl) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 // GetOrderById godoc
 // @Summary get order
+// @Security ApiKeyAuth
 // @Description Fund purchase order by id
 // @Tags store
 // @Produce json
 // @Param orderId path int true "ID of order that needs to be fetched"
 // @Success 200 {object} entities.Order
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,401,403,404,500 {object} rr.JSONResponse
 // @Router /store/order/{orderId} [get]
 func (s *StoreControl) GetOrderById(w http.ResponseWriter, r *http.Request) {
 	id := u.ParamFromPath(r.URL.Path)
 
 	orderId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("invalid id supplied", err), 400)
+		_ = s.rr.WriteError(w, r, e.Wrap("invalid id supplied", err), 400)
 		return
 	}
 
 	order, err := s.service.GetOrderById(r.Context(), orderId)
 	if err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("couldn't get order", err), 404)
+		_ = s.rr.WriteError(w, r, e.Wrap("couldn't get order", err))
 		return
 	}
 
@@ -90,29 +118,30 @@ func (s *StoreControl) GetOrderById(w http.ResponseWriter, r *http.Request) {
 
 // DeleteOrder godoc
 // @Summary delete order
+// @Security ApiKeyAuth
 // @Description Delete purchase order by id
 // @Tags store
 // @Produce json
 // @Param orderId path int true "ID of the order that needs to be deleted"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,401,403,404,500 {object} rr.JSONResponse
 // @Router /store/order/{orderId} [delete]
 func (s *StoreControl) DeleteOrder(w http.ResponseWriter, r *http.Request) {
 	id := u.ParamFromPath(r.URL.Path)
 
 	orderId, err := strconv.Atoi(id)
 	if err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("invalid id supplied", err))
+		_ = s.rr.WriteError(w, r, e.Wrap("invalid id supplied", err))
 		return
 	}
 
 	if _, err = s.service.GetOrderById(r.Context(), orderId); err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("couldn't get order", err), 404)
+		_ = s.rr.WriteError(w, r, e.Wrap("couldn't get order", err))
 		return
 	}
 
 	if err = s.service.DeleteOrder(r.Context(), orderId); err != nil {
-		_ = s.rr.WriteJSONError(w, e.Wrap("couldn't delete order", err))
+		_ = s.rr.WriteError(w, r, e.Wrap("couldn't delete order", err))
 		return
 	}
 
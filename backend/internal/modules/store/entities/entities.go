@@ -4,11 +4,20 @@ import "time"
 
 type Order struct {
 	Id         int       `json:"id,int"`
-	PetId      int       `json:"petId,int" example:"1" binding:"required"`
-	Quantity   int       `json:"quantity,int" example:"1" binding:"required"`
+	UserId     int       `json:"userId,int"` // owner of the order, set by the server from the caller
+	PetId      int       `json:"petId,int" example:"1" binding:"required,gt=0"`
+	Quantity   int       `json:"quantity,int" example:"1" binding:"required,gt=0"`
 	ShipDate   time.Time `json:"shipDate" example:"2024-08-01T07:25:40.698Z"`
-	Status     string    `json:"status" example:"placed"` // placed | approved | delivered
+	Status     string    `json:"status" example:"placed" binding:"oneof=placed approved delivered"` // placed | approved | delivered
 	IsComplete bool      `json:"complete" example:"true"`
 }
 
 type Inventory map[string]int
+
+type CategoryInventory map[string]Inventory
+
+type InventoryItem struct {
+	Category string
+	Status   string
+	Count    int
+}
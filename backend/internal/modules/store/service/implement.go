@@ -1,10 +1,13 @@
 package service
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/validate"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/store/entities"
 	"backend/internal/repository"
 	"context"
-	"errors"
 	"time"
 )
 
@@ -17,9 +20,11 @@ func NewStoreService(db repository.Repository) *StoreService {
 }
 
 func (s *StoreService) CreateOrder(ctx context.Context, order entities.Order) (int, error) {
-	if order.PetId == 0 || order.Quantity == 0 {
-		return 0, errors.New("order id and quantity must be greater than zero")
+	caller, err := customer(ctx)
+	if err != nil {
+		return 0, err
 	}
+	order.UserId = caller.UserId
 
 	if order.ShipDate.IsZero() {
 		order.ShipDate = time.Now()
@@ -29,11 +34,15 @@ func (s *StoreService) CreateOrder(ctx context.Context, order entities.Order) (i
 		order.Status = "placed"
 	}
 
-	if !s.statusIsValid(order.Status) {
-		return 0, errors.New("invalid order status")
+	if err := validate.Struct(order); err != nil {
+		return 0, err
 	}
 
-	orderId, err := s.DB.CreateOrder(ctx, order)
+	var orderId int
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) (err error) {
+		orderId, err = repo.CreateOrder(ctx, order)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -42,13 +51,84 @@ func (s *StoreService) CreateOrder(ctx context.Context, order entities.Order) (i
 }
 
 func (s *StoreService) GetOrderById(ctx context.Context, orderId int) (entities.Order, error) {
-	return s.DB.GetOrderById(ctx, orderId)
+	return s.getOrder(ctx, s.DB, orderId)
 }
 
 func (s *StoreService) DeleteOrder(ctx context.Context, orderId int) error {
-	return s.DB.DeleteOrder(ctx, orderId)
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		if _, err := s.getOrder(ctx, repo, orderId); err != nil {
+			return err
+		}
+		return repo.DeleteOrder(ctx, orderId)
+	})
 }
 
-func (s *StoreService) statusIsValid(status string) bool {
-	return status == "placed" || status == "approved" || status == "delivered"
+// getOrder returns the order if the caller owns it or is staff; orders of other
+// customers are reported as not found so that their ids can't be probed
+func (s *StoreService) getOrder(ctx context.Context, repo repository.Repository, orderId int) (entities.Order, error) {
+	caller, err := customer(ctx)
+	if err != nil {
+		return entities.Order{}, err
+	}
+
+	order, err := repo.GetOrderById(ctx, orderId)
+	if err != nil {
+		return entities.Order{}, err
+	}
+
+	if order.UserId != caller.UserId && !caller.HasRole(ae.RoleStaff) {
+		return entities.Order{}, e.NotFound("order not found")
+	}
+
+	return order, nil
+}
+
+func (s *StoreService) GetInventory(ctx context.Context) (entities.Inventory, error) {
+	items, err := s.DB.GetInventory(ctx)
+	if err != nil {
+		return nil, e.Wrap("couldn't get inventory", err)
+	}
+
+	inventory := s.emptyInventory()
+	for _, item := range items {
+		inventory[item.Status] += item.Count
+	}
+
+	return inventory, nil
+}
+
+func (s *StoreService) GetInventoryByCategory(ctx context.Context) (entities.CategoryInventory, error) {
+	items, err := s.DB.GetInventory(ctx)
+	if err != nil {
+		return nil, e.Wrap("couldn't get inventory", err)
+	}
+
+	inventory := make(entities.CategoryInventory)
+	for _, item := range items {
+		if _, ok := inventory[item.Category]; !ok {
+			inventory[item.Category] = s.emptyInventory()
+		}
+		inventory[item.Category][item.Status] += item.Count
+	}
+
+	return inventory, nil
+}
+
+// emptyInventory lists every pet status so that missing ones are reported as zero
+func (s *StoreService) emptyInventory() entities.Inventory {
+	return entities.Inventory{"available": 0, "pending": 0, "sold": 0}
+}
+
+// customer returns the user making the call, orders can't be placed by machine clients
+func customer(ctx context.Context) (ae.Principal, error) {
+	caller, ok := as.PrincipalFromContext(ctx)
+	if !ok {
+		return ae.Principal{}, e.Unauthorized("user is not authenticated")
+	}
+
+	if caller.IsClient() {
+		return ae.Principal{}, e.Forbidden("clients can't place orders")
+	}
+
+	return caller, nil
 }
@@ -9,4 +9,6 @@ type StoreServicer interface {
 	CreateOrder(ctx context.Context, order entities.Order) (int, error)
 	GetOrderById(ctx context.Context, orderId int) (entities.Order, error)
 	DeleteOrder(ctx context.Context, orderId int) error
+	GetInventory(ctx context.Context) (entities.Inventory, error)
+	GetInventoryByCategory(ctx context.Context) (entities.CategoryInventory, error)
 }
@@ -1,10 +1,17 @@
 package service
 
 import (
+	"backend/internal/lib/e"
 	"backend/internal/mocks/mock_repository"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/store/entities"
+	"backend/internal/repository"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
+	"reflect"
 	"testing"
 )
 
@@ -13,12 +20,15 @@ import (
 func TestStoreService_CreateOrder(t *testing.T) {
 	testCases := []struct {
 		name    string
+		caller  *ae.Principal
 		order   entities.Order
-		wantErr bool
+		wantErr error
 	}{
-		{"normal case", entities.Order{PetId: 1, Quantity: 1}, false},
-		{"invalid pet/order id", entities.Order{PetId: 0, Quantity: 0}, true},
-		{"invalid status", entities.Order{PetId: 1, Quantity: 1, Status: "unknown"}, true},
+		{"normal case", customerPrincipal(1), entities.Order{PetId: 1, Quantity: 1}, nil},
+		{"invalid pet/order id", customerPrincipal(1), entities.Order{PetId: 0, Quantity: 0}, e.ErrValidation},
+		{"invalid status", customerPrincipal(1), entities.Order{PetId: 1, Quantity: 1, Status: "unknown"}, e.ErrValidation},
+		{"anonymous", nil, entities.Order{PetId: 1, Quantity: 1}, e.ErrUnauthorized},
+		{"client", &ae.Principal{Username: "inventory-sync", ClientId: "inventory-sync"}, entities.Order{PetId: 1, Quantity: 1}, e.ErrForbidden},
 	}
 
 	controller := gomock.NewController(t)
@@ -29,28 +39,86 @@ func TestStoreService_CreateOrder(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if _, err := ss.CreateOrder(context.Background(), tc.order); (err != nil) != tc.wantErr {
+			if _, err := ss.CreateOrder(callerContext(tc.caller), tc.order); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
 				t.Errorf("CreateOrder() error = %v, wantErr %v", err, tc.wantErr)
 			}
 		})
 	}
+
+	t.Run("owner", func(t *testing.T) {
+		mockDb := mock_repository.NewMockRepository(controller)
+		mockDb.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+			return fn(mockDb)
+		})
+		mockDb.EXPECT().CreateOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order entities.Order) (int, error) {
+			if order.UserId != 7 {
+				t.Errorf("CreateOrder() user id = %d, want %d", order.UserId, 7)
+			}
+			return 1, nil
+		})
+
+		// the owner comes from the caller, not from the request body
+		order := entities.Order{UserId: 1, PetId: 1, Quantity: 1}
+		if _, err := NewStoreService(mockDb).CreateOrder(callerContext(customerPrincipal(7)), order); err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+	})
 }
 
 func TestStoreService_GetOrderById(t *testing.T) {
+	testCases := []struct {
+		name    string
+		caller  *ae.Principal
+		wantErr error
+	}{
+		{"owner", customerPrincipal(1), nil},
+		{"another customer", customerPrincipal(2), e.ErrNotFound},
+		{"staff", &ae.Principal{UserId: 2, Username: "jenstar", Roles: []ae.Role{ae.RoleStaff}}, nil},
+		{"anonymous", nil, e.ErrUnauthorized},
+	}
+
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
 	db := NewMockRepository(controller)
 	ss := NewStoreService(db)
 
-	t.Run("normal case", func(t *testing.T) {
-		if _, err := ss.GetOrderById(context.Background(), 1); err != nil {
-			t.Errorf("GetOrderById() error = %v, wantErr %v", err, nil)
-		}
-	})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ss.GetOrderById(callerContext(tc.caller), 1); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("GetOrderById() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
 }
 
 func TestStoreService_DeleteOrder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		caller  *ae.Principal
+		wantErr error
+	}{
+		{"owner", customerPrincipal(1), nil},
+		{"another customer", customerPrincipal(2), e.ErrNotFound},
+		{"admin", &ae.Principal{UserId: 3, Username: "wanomir", Roles: []ae.Role{ae.RoleAdmin}}, nil},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	db := NewMockRepository(controller)
+	ss := NewStoreService(db)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ss.DeleteOrder(callerContext(tc.caller), 1); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("DeleteOrder() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStoreService_GetInventory(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
@@ -58,8 +126,29 @@ func TestStoreService_DeleteOrder(t *testing.T) {
 	ss := NewStoreService(db)
 
 	t.Run("normal case", func(t *testing.T) {
-		if err := ss.DeleteOrder(context.Background(), 1); err != nil {
-			t.Errorf("DeleteOrder() error = %v, wantErr %v", err, nil)
+		inventory, err := ss.GetInventory(context.Background())
+		if err != nil {
+			t.Fatalf("GetInventory() error = %v, wantErr %v", err, nil)
+		}
+
+		want := entities.Inventory{"available": 3, "pending": 1, "sold": 0}
+		if !reflect.DeepEqual(inventory, want) {
+			t.Errorf("GetInventory() = %v, want %v", inventory, want)
+		}
+	})
+
+	t.Run("by category", func(t *testing.T) {
+		inventory, err := ss.GetInventoryByCategory(context.Background())
+		if err != nil {
+			t.Fatalf("GetInventoryByCategory() error = %v, wantErr %v", err, nil)
+		}
+
+		want := entities.CategoryInventory{
+			"cat": {"available": 2, "pending": 1, "sold": 0},
+			"dog": {"available": 1, "pending": 0, "sold": 0},
+		}
+		if !reflect.DeepEqual(inventory, want) {
+			t.Errorf("GetInventoryByCategory() = %v, want %v", inventory, want)
 		}
 	})
 }
@@ -67,11 +156,33 @@ func TestStoreService_DeleteOrder(t *testing.T) {
 func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepository {
 	mockDb := mock_repository.NewMockRepository(controller)
 
+	mockDb.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+		return fn(mockDb)
+	}).AnyTimes()
+
 	mockDb.EXPECT().CreateOrder(gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
 
-	mockDb.EXPECT().GetOrderById(gomock.Any(), gomock.Any()).Return(entities.Order{}, nil).AnyTimes()
+	mockDb.EXPECT().GetOrderById(gomock.Any(), gomock.Any()).Return(entities.Order{Id: 1, UserId: 1, PetId: 1, Quantity: 1}, nil).AnyTimes()
 
 	mockDb.EXPECT().DeleteOrder(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
+	mockDb.EXPECT().GetInventory(gomock.Any()).Return([]entities.InventoryItem{
+		{Category: "cat", Status: "available", Count: 2},
+		{Category: "cat", Status: "pending", Count: 1},
+		{Category: "dog", Status: "available", Count: 1},
+	}, nil).AnyTimes()
+
 	return mockDb
 }
+
+func customerPrincipal(userId int) *ae.Principal {
+	return &ae.Principal{UserId: userId, Username: fmt.Sprintf("customer%d", userId), Roles: []ae.Role{ae.RoleCustomer}}
+}
+
+// callerContext returns a context carrying the principal stored by the auth middleware
+func callerContext(caller *ae.Principal) context.Context {
+	if caller == nil {
+		return context.Background()
+	}
+	return as.NewContext(context.Background(), *caller)
+}
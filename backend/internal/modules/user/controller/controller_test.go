@@ -1,17 +1,22 @@
 package controller
 
 import (
+	"backend/internal/lib/e"
 	"backend/internal/lib/rr"
 	mock_service "backend/internal/mocks/mock_user_service"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/user/entities"
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestUserControl_GetByUsername(t *testing.T) {
@@ -42,6 +47,9 @@ func TestUserControl_GetByUsername(t *testing.T) {
 			if r.StatusCode != tc.wantStatus {
 				t.Errorf("want status %d, got %d", tc.wantStatus, r.StatusCode)
 			}
+			if strings.Contains(wr.Body.String(), `"password"`) {
+				t.Errorf("response %s has a password field", wr.Body.String())
+			}
 		})
 	}
 }
@@ -57,6 +65,8 @@ func TestUserControl_Update(t *testing.T) {
 		{"invalid username", "", entities.User{Username: "wanomir"}, http.StatusBadRequest},
 		{"unknown user", "john", entities.User{Username: "john"}, http.StatusNotFound},
 		{"username mismatch", "wanomir", entities.User{Username: "john"}, http.StatusBadRequest},
+		{"role change by non admin", "wanomir", entities.User{Username: "wanomir", Role: ae.RoleAdmin}, http.StatusForbidden},
+		{"invalid role", "wanomir", entities.User{Username: "wanomir", Role: "owner"}, http.StatusUnprocessableEntity},
 	}
 
 	controller := gomock.NewController(t)
@@ -91,8 +101,8 @@ func TestUserControl_Create(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", entities.User{Username: "john", Password: "snow"}, http.StatusCreated},
-		{"missing password", entities.User{Username: "john", Password: ""}, http.StatusBadRequest},
-		{"user already exists", entities.User{Username: "wanomir", Password: "password"}, http.StatusBadRequest},
+		{"missing password", entities.User{Username: "john", Password: ""}, http.StatusUnprocessableEntity},
+		{"user already exists", entities.User{Username: "wanomir", Password: "password"}, http.StatusConflict},
 	}
 
 	controller := gomock.NewController(t)
@@ -159,8 +169,8 @@ func TestUserControl_CreateWithArray(t *testing.T) {
 		wantStatus int
 	}{
 		{"normal case", entities.Users{entities.User{Username: "john", Password: "snow"}}, http.StatusCreated},
-		{"missing password", entities.Users{entities.User{Username: "john", Password: ""}}, http.StatusBadRequest},
-		{"user already exists", entities.Users{entities.User{Username: "wanomir", Password: "password"}}, http.StatusBadRequest},
+		{"missing password", entities.Users{entities.User{Username: "john", Password: ""}}, http.StatusUnprocessableEntity},
+		{"user already exists", entities.Users{entities.User{Username: "wanomir", Password: "password"}}, http.StatusConflict},
 	}
 
 	controller := gomock.NewController(t)
@@ -188,7 +198,211 @@ func TestUserControl_CreateWithArray(t *testing.T) {
 	}
 }
 
+func TestUserControl_VerifyEmail(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"normal case", `{"token":"verify-token"}`, http.StatusOK},
+		{"invalid token", `{"token":"used-token"}`, http.StatusUnauthorized},
+		{"missing token", `{}`, http.StatusUnprocessableEntity},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/verify", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			wr := httptest.NewRecorder()
+
+			uc.VerifyEmail(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestUserControl_PasswordReset(t *testing.T) {
+	testCases := []struct {
+		name       string
+		target     string
+		body       string
+		handler    func(uc *UserControl) http.HandlerFunc
+		wantStatus int
+	}{
+		{"forgot", "/user/password/forgot", `{"email":"wanomir@example.com"}`, func(uc *UserControl) http.HandlerFunc { return uc.ForgotPassword }, http.StatusAccepted},
+		{"forgot unknown email", "/user/password/forgot", `{"email":"nobody@example.com"}`, func(uc *UserControl) http.HandlerFunc { return uc.ForgotPassword }, http.StatusAccepted},
+		{"forgot invalid email", "/user/password/forgot", `{"email":"wanomir"}`, func(uc *UserControl) http.HandlerFunc { return uc.ForgotPassword }, http.StatusUnprocessableEntity},
+		{"reset", "/user/password/reset", `{"token":"reset-token","password":"new-password"}`, func(uc *UserControl) http.HandlerFunc { return uc.ResetPassword }, http.StatusOK},
+		{"reset used token", "/user/password/reset", `{"token":"used-token","password":"new-password"}`, func(uc *UserControl) http.HandlerFunc { return uc.ResetPassword }, http.StatusUnauthorized},
+		{"reset without password", "/user/password/reset", `{"token":"reset-token"}`, func(uc *UserControl) http.HandlerFunc { return uc.ResetPassword }, http.StatusUnprocessableEntity},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			wr := httptest.NewRecorder()
+
+			tc.handler(uc)(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
 func TestUserControl_Login(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"json", "application/json", `{"username":"wanomir","password":"password"}`, http.StatusOK},
+		{"form", "application/x-www-form-urlencoded", "username=wanomir&password=password", http.StatusOK},
+		{"incorrect credentials", "application/json", `{"username":"jenstar","password":"my-password"}`, http.StatusUnauthorized},
+		{"missing password", "application/x-www-form-urlencoded", "username=wanomir", http.StatusUnprocessableEntity},
+		{"invalid json", "application/json", `{"username":`, http.StatusBadRequest},
+		{"locked out", "application/json", `{"username":"locked","password":"password"}`, http.StatusTooManyRequests},
+		{"second factor", "application/json", `{"username":"staff","password":"password"}`, http.StatusOK},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			wr := httptest.NewRecorder()
+
+			uc.Login(wr, req)
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Fatalf("want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+
+			if r.StatusCode == http.StatusTooManyRequests && r.Header.Get("Retry-After") != "90" {
+				t.Errorf("want Retry-After %q, got %q", "90", r.Header.Get("Retry-After"))
+			}
+
+			if r.StatusCode == http.StatusOK {
+				var tokens ae.TokensPair
+				if err := json.NewDecoder(r.Body).Decode(&rr.JSONResponse{Data: &tokens}); err != nil || (tokens.AccessToken == "") == (tokens.ChallengeToken == "") {
+					t.Errorf("want tokens pair or challenge, got %+v, err %v", tokens, err)
+				}
+				// the refresh token cookie is only set once the login is complete
+				if cookies := r.Cookies(); (len(cookies) == 0) != (tokens.ChallengeToken != "") {
+					t.Errorf("want cookie only with tokens, got %d cookies", len(cookies))
+				}
+			}
+		})
+	}
+}
+
+func TestUserControl_LoginTwoFactor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"normal case", `{"challenge_token":"challenge","code":"287082"}`, http.StatusOK},
+		{"wrong code", `{"challenge_token":"challenge","code":"000000"}`, http.StatusUnauthorized},
+		{"missing code", `{"challenge_token":"challenge"}`, http.StatusUnprocessableEntity},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/login/2fa", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			wr := httptest.NewRecorder()
+
+			uc.LoginTwoFactor(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestUserControl_LoginExternal(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	uc := NewUserController(NewMockUserService(controller), rr.NewReadRespond())
+
+	t.Run("start", func(t *testing.T) {
+		wr := httptest.NewRecorder()
+		uc.LoginExternal(wr, httptest.NewRequest(http.MethodGet, "/user/login/oidc", nil))
+
+		if wr.Code != http.StatusFound || !strings.HasPrefix(wr.Header().Get("Location"), "https://idp.example.com/") {
+			t.Errorf("want a redirect to the provider, got %d to %q", wr.Code, wr.Header().Get("Location"))
+		}
+		if !strings.Contains(wr.Header().Get("Set-Cookie"), "login-state") {
+			t.Errorf("want the login state cookie, got %q", wr.Header().Get("Set-Cookie"))
+		}
+	})
+
+	testCases := []struct {
+		name       string
+		query      string
+		cookie     bool
+		wantStatus int
+	}{
+		{"normal case", "?code=code&state=state", true, http.StatusOK},
+		{"without cookie", "?code=code&state=state", false, http.StatusUnauthorized},
+		{"denied by the provider", "?error=access_denied&state=state", true, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user/login/oidc/callback"+tc.query, nil)
+			if tc.cookie {
+				req.AddCookie(&http.Cookie{Name: "__Host-oidc_login", Value: "login-state"})
+			}
+			wr := httptest.NewRecorder()
+
+			uc.LoginExternalCallback(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+			// the login state is single use
+			if !strings.Contains(wr.Header().Get("Set-Cookie"), "__Host-oidc_login=;") {
+				t.Errorf("want the login state cookie removed, got %q", wr.Header().Values("Set-Cookie"))
+			}
+		})
+	}
+}
+
+func TestUserControl_LegacyLogin(t *testing.T) {
 	testCases := []struct {
 		name       string
 		username   string
@@ -211,7 +425,7 @@ func TestUserControl_Login(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/user/login?username="+tc.username+"&password="+tc.password, nil)
 			wr := httptest.NewRecorder()
 
-			uc.Login(wr, req)
+			uc.LegacyLogin(wr, req)
 			r := wr.Result()
 
 			if r.StatusCode != tc.wantStatus {
@@ -222,6 +436,211 @@ func TestUserControl_Login(t *testing.T) {
 	}
 }
 
+// every endpoint issuing tokens answers the same way and keeps the tokens out of caches
+func TestUserControl_TokenResponses(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	uc := NewUserController(NewMockUserService(controller), rr.NewReadRespond())
+
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		request func() *http.Request
+	}{
+		{"login", uc.Login, func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(`{"username":"wanomir","password":"password"}`))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}},
+		{"second factor", uc.LoginTwoFactor, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/user/login/2fa", strings.NewReader(`{"challenge_token":"challenge","code":"287082"}`))
+		}},
+		{"external login", uc.LoginExternalCallback, func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/user/login/oidc/callback?code=code&state=state", nil)
+			req.AddCookie(&http.Cookie{Name: "__Host-oidc_login", Value: "login-state"})
+			return req
+		}},
+		{"legacy login", uc.LegacyLogin, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/user/login?username=wanomir&password=password", nil)
+		}},
+		{"refresh", uc.Refresh, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/user/refresh", strings.NewReader(`{"refresh_token":"refresh-token"}`))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wr := httptest.NewRecorder()
+			tc.handler(wr, tc.request())
+
+			var tokens ae.TokensPair
+			resp := rr.JSONResponse{Data: &tokens}
+			if err := json.NewDecoder(wr.Body).Decode(&resp); err != nil || wr.Code != http.StatusOK || resp.Error || tokens.AccessToken == "" {
+				t.Errorf("want tokens in the response envelope, got %d %+v, err %v", wr.Code, resp, err)
+			}
+			if wr.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("want Cache-Control no-store, got %q", wr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestUserControl_Unlock(t *testing.T) {
+	testCases := []struct {
+		name       string
+		username   string
+		wantStatus int
+	}{
+		{"normal case", "wanomir", http.StatusOK},
+		{"unknown user", "john", http.StatusNotFound},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/user/"+tc.username+"/lockout", nil)
+			wr := httptest.NewRecorder()
+
+			uc.Unlock(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestUserControl_TwoFactor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    func(uc *UserControl) http.HandlerFunc
+		wantStatus int
+	}{
+		{"enroll", http.MethodPost, "/user/wanomir/2fa", "", func(uc *UserControl) http.HandlerFunc { return uc.EnrollTwoFactor }, http.StatusCreated},
+		{"enroll other user", http.MethodPost, "/user/jenstar/2fa", "", func(uc *UserControl) http.HandlerFunc { return uc.EnrollTwoFactor }, http.StatusForbidden},
+		{"confirm", http.MethodPost, "/user/wanomir/2fa/verify", `{"code":"287082"}`, func(uc *UserControl) http.HandlerFunc { return uc.ConfirmTwoFactor }, http.StatusOK},
+		{"confirm wrong code", http.MethodPost, "/user/wanomir/2fa/verify", `{"code":"000000"}`, func(uc *UserControl) http.HandlerFunc { return uc.ConfirmTwoFactor }, http.StatusUnprocessableEntity},
+		{"disable", http.MethodDelete, "/user/wanomir/2fa", `{"code":"287082"}`, func(uc *UserControl) http.HandlerFunc { return uc.DisableTwoFactor }, http.StatusOK},
+		{"disable without code", http.MethodDelete, "/user/wanomir/2fa", "", func(uc *UserControl) http.HandlerFunc { return uc.DisableTwoFactor }, http.StatusUnprocessableEntity},
+		{"disable unknown user", http.MethodDelete, "/user/john/2fa", `{"code":"287082"}`, func(uc *UserControl) http.HandlerFunc { return uc.DisableTwoFactor }, http.StatusNotFound},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			wr := httptest.NewRecorder()
+
+			tc.handler(uc)(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+		})
+	}
+}
+
+func TestUserControl_APIKeys(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    func(uc *UserControl) http.HandlerFunc
+		wantStatus int
+	}{
+		{"create", http.MethodPost, "/user/wanomir/apikeys", `{"name":"sync","scopes":["read:pets"]}`, func(uc *UserControl) http.HandlerFunc { return uc.CreateAPIKey }, http.StatusCreated},
+		{"create without scopes", http.MethodPost, "/user/wanomir/apikeys", `{"name":"sync"}`, func(uc *UserControl) http.HandlerFunc { return uc.CreateAPIKey }, http.StatusUnprocessableEntity},
+		{"create too long", http.MethodPost, "/user/wanomir/apikeys", `{"name":"sync","scopes":["read:pets"],"expiresInDays":366}`, func(uc *UserControl) http.HandlerFunc { return uc.CreateAPIKey }, http.StatusUnprocessableEntity},
+		{"create for other user", http.MethodPost, "/user/jenstar/apikeys", `{"name":"sync","scopes":["read:pets"]}`, func(uc *UserControl) http.HandlerFunc { return uc.CreateAPIKey }, http.StatusForbidden},
+		{"list", http.MethodGet, "/user/wanomir/apikeys", "", func(uc *UserControl) http.HandlerFunc { return uc.APIKeys }, http.StatusOK},
+		{"revoke", http.MethodDelete, "/user/wanomir/apikeys/1", "", func(uc *UserControl) http.HandlerFunc { return uc.RevokeAPIKey }, http.StatusOK},
+		{"revoke unknown key", http.MethodDelete, "/user/wanomir/apikeys/2", "", func(uc *UserControl) http.HandlerFunc { return uc.RevokeAPIKey }, http.StatusNotFound},
+		{"revoke invalid id", http.MethodDelete, "/user/wanomir/apikeys/abc", "", func(uc *UserControl) http.HandlerFunc { return uc.RevokeAPIKey }, http.StatusBadRequest},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			wr := httptest.NewRecorder()
+
+			tc.handler(uc)(wr, req)
+
+			if wr.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, wr.Code)
+			}
+			// the key is only shown once and must not end up in caches
+			if wr.Code == http.StatusCreated && wr.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("want Cache-Control no-store, got %q", wr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestUserControl_Refresh(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       any
+		cookie     string
+		wantStatus int
+	}{
+		{"token in body", ae.RefreshRequest{RefreshToken: "refresh-token"}, "", http.StatusOK},
+		{"token in cookie", nil, "refresh-token", http.StatusOK},
+		{"invalid token", ae.RefreshRequest{RefreshToken: "used-token"}, "", http.StatusUnauthorized},
+		{"missing token", nil, "", http.StatusUnauthorized},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body bytes.Buffer
+			if tc.body != nil {
+				json.NewEncoder(&body).Encode(tc.body)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/user/refresh", &body)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tc.cookie})
+			}
+			wr := httptest.NewRecorder()
+
+			uc.Refresh(wr, req)
+			r := wr.Result()
+
+			if r.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, r.StatusCode)
+			}
+		})
+	}
+}
+
 func TestUserControl_Logout(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
@@ -242,25 +661,66 @@ func TestUserControl_Logout(t *testing.T) {
 	})
 }
 
+func TestUserControl_Sessions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		claims     *ae.Claims
+		wantStatus int
+	}{
+		{"normal case", &ae.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "wanomir", ID: "token-id"}}, http.StatusOK},
+		{"not authenticated", nil, http.StatusUnauthorized},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockService := NewMockUserService(controller)
+	uc := NewUserController(mockService, rr.NewReadRespond())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, handler := range []http.HandlerFunc{uc.Sessions, uc.LogoutAll} {
+				req := httptest.NewRequest(http.MethodGet, "/user/sessions", nil)
+				if tc.claims != nil {
+					req = req.WithContext(as.NewContext(req.Context(), tc.claims.Principal()))
+				}
+				wr := httptest.NewRecorder()
+
+				handler(wr, req)
+				r := wr.Result()
+
+				if r.StatusCode != tc.wantStatus {
+					t.Errorf("want status %d, got %d", tc.wantStatus, r.StatusCode)
+				}
+			}
+		})
+	}
+}
+
 func NewMockUserService(controller *gomock.Controller) *mock_service.MockUserServicer {
 	mockService := mock_service.NewMockUserServicer(controller)
 
-	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) (entities.User, error) {
+	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) (entities.UserProfile, error) {
 		if name == "wanomir" || name == "jenstar" {
-			return entities.User{}, nil
+			return entities.UserProfile{Username: name}, nil
 		}
-		return entities.User{}, errors.New("user not found")
+		return entities.UserProfile{}, e.NotFound("user not found")
 	}).AnyTimes()
 
-	mockService.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockService.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entities.User) error {
+		if user.Role != "" {
+			return e.Forbidden("only admins can change roles")
+		}
+		return nil
+	}).AnyTimes()
 
 	mockService.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entities.User) (int, error) {
 		if user.Username == "" || user.Password == "" {
-			return 0, errors.New("need username and password")
+			return 0, e.Validation("need username and password")
 		}
 
 		if user.Username == "wanomir" || user.Username == "jenstar" {
-			return 0, errors.New("user already exists")
+			return 0, e.Conflict("user already exists")
 		}
 
 		return 1, nil
@@ -268,13 +728,129 @@ func NewMockUserService(controller *gomock.Controller) *mock_service.MockUserSer
 
 	mockService.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-	mockService.EXPECT().Authorize(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username, password string) (string, *http.Cookie, error) {
+	mockService.EXPECT().VerifyEmail(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token string) error {
+		if token != "verify-token" {
+			return e.Unauthorized("token has already been used")
+		}
+		return nil
+	}).AnyTimes()
+
+	mockService.EXPECT().ForgotPassword(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	mockService.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token, _ string) error {
+		if token != "reset-token" {
+			return e.Unauthorized("token has already been used")
+		}
+		return nil
+	}).AnyTimes()
+
+	mockService.EXPECT().Unlock(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username string) error {
+		if username != "wanomir" && username != "jenstar" {
+			return e.NotFound("user not found")
+		}
+		return nil
+	}).AnyTimes()
+
+	mockService.EXPECT().Authorize(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username, password, _ string) (ae.TokensPair, *http.Cookie, error) {
+		if username == "locked" {
+			return ae.TokensPair{}, nil, e.RateLimited("account is temporarily locked", 90*time.Second)
+		}
+		if username == "staff" && password == "password" {
+			return ae.TokensPair{ChallengeToken: "challenge"}, nil, nil
+		}
 		if (username == "wanomir" || username == "jenstar") && password == "password" {
-			return "token", &http.Cookie{}, nil
+			return ae.TokensPair{AccessToken: "token", RefreshToken: "refresh-token"}, &http.Cookie{Name: "refresh_token"}, nil
+		}
+		return ae.TokensPair{}, nil, e.Unauthorized("unauthorized user")
+	}).AnyTimes()
+
+	mockService.EXPECT().AuthorizeTwoFactor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, challenge, code, _ string) (ae.TokensPair, *http.Cookie, error) {
+		if challenge == "challenge" && code == "287082" {
+			return ae.TokensPair{AccessToken: "token", RefreshToken: "refresh-token"}, &http.Cookie{Name: "refresh_token"}, nil
+		}
+		return ae.TokensPair{}, nil, e.Unauthorized("invalid credentials")
+	}).AnyTimes()
+
+	mockService.EXPECT().StartExternalLogin(gomock.Any()).Return("https://idp.example.com/authorize?state=state", &http.Cookie{Name: "__Host-oidc_login", Value: "login-state"}, nil).AnyTimes()
+
+	mockService.EXPECT().AuthorizeExternal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, stateToken, state, code string) (ae.TokensPair, *http.Cookie, error) {
+		if stateToken == "login-state" && state == "state" && code == "code" {
+			return ae.TokensPair{AccessToken: "token", RefreshToken: "refresh-token"}, &http.Cookie{Name: "refresh_token"}, nil
+		}
+		return ae.TokensPair{}, nil, e.Unauthorized("invalid login state")
+	}).AnyTimes()
+
+	mockService.EXPECT().LoginStateFromCookie(gomock.Any()).DoAndReturn(func(r *http.Request) string {
+		if cookie, err := r.Cookie("__Host-oidc_login"); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}).AnyTimes()
+
+	mockService.EXPECT().ResetLoginStateCookie().Return(&http.Cookie{Name: "__Host-oidc_login", MaxAge: -1}).AnyTimes()
+
+	// the caller in the two-factor tests is wanomir
+	mockService.EXPECT().EnrollTwoFactor(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username string) (ae.TwoFactorEnrollment, error) {
+		if username != "wanomir" {
+			return ae.TwoFactorEnrollment{}, e.Forbidden("users can only set up their own two-factor authentication")
+		}
+		return ae.TwoFactorEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/Petstore:wanomir", RecoveryCodes: []string{"k7gq-2mxd-p4ta"}}, nil
+	}).AnyTimes()
+
+	mockService.EXPECT().ConfirmTwoFactor(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _, code string) error {
+		if code != "287082" {
+			return e.Validation("invalid code")
+		}
+		return nil
+	}).AnyTimes()
+
+	mockService.EXPECT().DisableTwoFactor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username, code, _ string) error {
+		if username != "wanomir" && username != "jenstar" {
+			return e.NotFound("user not found")
+		}
+		if code != "287082" {
+			return e.Validation("invalid code")
+		}
+		return nil
+	}).AnyTimes()
+
+	// the caller in the API key tests is wanomir, with the key 1
+	mockService.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username string, request ae.CreateAPIKeyRequest) (ae.NewAPIKey, error) {
+		if username != "wanomir" {
+			return ae.NewAPIKey{}, e.Forbidden("users can only create their own API keys")
+		}
+		return ae.NewAPIKey{APIKey: ae.APIKey{Id: 1, Name: request.Name, Prefix: "psk_Xk3v9QbT", Scopes: request.Scopes}, Key: "psk_Xk3v9QbTz2Lq8WnR4yHc1MdF7sJpA0eGvUoK5tIbN6w"}, nil
+	}).AnyTimes()
+
+	mockService.EXPECT().APIKeys(gomock.Any(), gomock.Any()).Return([]ae.APIKey{{Id: 1, Name: "sync", Prefix: "psk_Xk3v9QbT", Scopes: []string{ae.ScopeReadPets}}}, nil).AnyTimes()
+
+	mockService.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, keyId int) error {
+		if keyId != 1 {
+			return e.NotFound("API key not found")
 		}
-		return "", &http.Cookie{}, errors.New("unauthorized user")
+		return nil
 	}).AnyTimes()
 
+	mockService.EXPECT().Refresh(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, refreshToken string) (ae.TokensPair, *http.Cookie, error) {
+		if refreshToken == "refresh-token" {
+			return ae.TokensPair{AccessToken: "new-token", RefreshToken: "new-refresh-token"}, &http.Cookie{}, nil
+		}
+		return ae.TokensPair{}, nil, e.Unauthorized("invalid refresh token")
+	}).AnyTimes()
+
+	mockService.EXPECT().RefreshTokenFromCookie(gomock.Any()).DoAndReturn(func(r *http.Request) string {
+		if cookie, err := r.Cookie("refresh_token"); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}).AnyTimes()
+
+	mockService.EXPECT().Logout(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	mockService.EXPECT().LogoutAll(gomock.Any(), "wanomir").Return(nil).AnyTimes()
+
+	mockService.EXPECT().Sessions(gomock.Any(), "wanomir", gomock.Any()).Return([]ae.Session{{Id: "family", Current: true}}, nil).AnyTimes()
+
 	mockService.EXPECT().ResetCookie().Return(&http.Cookie{}).AnyTimes()
 
 	return mockService
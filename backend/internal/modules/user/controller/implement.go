@@ -4,13 +4,22 @@ import (
 	"backend/internal/lib/e"
 	"backend/internal/lib/rr"
 	"backend/internal/lib/u"
+	"backend/internal/lib/validate"
+	ae "backend/internal/modules/auth/entities"
+	as "backend/internal/modules/auth/service"
 	"backend/internal/modules/user/entities"
 	"backend/internal/modules/user/service"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// maxFormSize limits the login form, it only carries the credentials
+const maxFormSize = 1 << 10
+
 type UserControl struct {
 	service service.UserServicer
 	rr      rr.ReadResponder
@@ -25,24 +34,25 @@ func NewUserController(service service.UserServicer, readResponder rr.ReadRespon
 
 // GetByUsername godoc
 // @Summary get user
-// @Description Get user by username
+// @Description Get user by username. The email, phone, role and email verification are only shown to the user and admins.
+// @Security ApiKeyAuth
 // @Tags user
 // @Produce json
 // @Param username path string true "The name that needs to be fetched"
-// @Success 200 {object} entities.User
-// @Failure 400,404 {object} rr.JSONResponse
+// @Success 200 {object} entities.UserProfile
+// @Failure 400,401,404,500 {object} rr.JSONResponse
 // @Router /user/{username} [get]
 func (c *UserControl) GetByUsername(w http.ResponseWriter, r *http.Request) {
 	username := u.ParamFromPath(r.URL.Path)
 
 	if username == "" {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid username"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid username"))
 		return
 	}
 
 	user, err := c.service.GetByName(r.Context(), username)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't get user", err), 404)
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't get user", err))
 		return
 	}
 
@@ -52,38 +62,42 @@ func (c *UserControl) GetByUsername(w http.ResponseWriter, r *http.Request) {
 
 // Update godoc
 // @Summary update user
-// @Description Updated user
+// @Security ApiKeyAuth
+// @Description Updated user. Users can update themselves, admins can update anyone and change roles.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param username path string true "Name that need to be updated"
 // @Param body body entities.User true "Updated user object"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,401,403,404,422,500 {object} rr.JSONResponse
 // @Router /user/{username} [put]
 func (c *UserControl) Update(w http.ResponseWriter, r *http.Request) {
 	username := u.ParamFromPath(r.URL.Path)
 
 	if username == "" {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid username"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid username"))
 		return
 	}
 
 	if _, err := c.service.GetByName(r.Context(), username); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't get user", err), 404)
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't get user", err))
 		return
 	}
 
 	var userUpdate entities.User
-	_ = c.rr.ReadJSON(w, r, &userUpdate)
+	if err := c.rr.ReadPartialJSON(w, r, &userUpdate); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
 
 	if userUpdate.Username != username {
-		_ = c.rr.WriteJSONError(w, errors.New("username does not match"))
+		_ = c.rr.WriteError(w, r, errors.New("username does not match"))
 		return
 	}
 
 	if err := c.service.Update(r.Context(), userUpdate); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't update user", err))
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't update user", err))
 		return
 	}
 
@@ -94,21 +108,26 @@ func (c *UserControl) Update(w http.ResponseWriter, r *http.Request) {
 
 // Create godoc
 // @Summary create user
-// @Description Create user
+// @Description Create user. The password must be at least 8 characters long, mix 3 of lowercase letters,
+// @Description uppercase letters, digits and symbols, differ from the username and email and must not
+// @Description appear in a list of breached passwords; every violation is reported at once.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param body body entities.User true "User object"
 // @Success 201 {object} rr.JSONResponse
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,409,422,500 {object} rr.JSONResponse
 // @Router /user [post]
 func (c *UserControl) Create(w http.ResponseWriter, r *http.Request) {
 	var user entities.User
-	_ = c.rr.ReadJSON(w, r, &user)
+	if err := c.rr.ReadJSON(w, r, &user); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
 
 	userId, err := c.service.Create(r.Context(), user)
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't create user", err))
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't create user", err))
 		return
 	}
 
@@ -118,29 +137,30 @@ func (c *UserControl) Create(w http.ResponseWriter, r *http.Request) {
 
 // Delete godoc
 // @Summary delete user
-// @Description Delete user
+// @Security ApiKeyAuth
+// @Description Delete user. Users can delete themselves, only admins can delete other users.
 // @Tags user
 // @Produce json
 // @Param username path string true "The name that needs to be deleted"
 // @Success 200 {object} rr.JSONResponse
-// @Failure 400,404 {object} rr.JSONResponse
+// @Failure 400,401,403,404,500 {object} rr.JSONResponse
 // @Router /user/{username} [delete]
 func (c *UserControl) Delete(w http.ResponseWriter, r *http.Request) {
 	username := u.ParamFromPath(r.URL.Path)
 	ctx := r.Context()
 
 	if username == "" {
-		_ = c.rr.WriteJSONError(w, errors.New("invalid username"))
+		_ = c.rr.WriteError(w, r, errors.New("invalid username"))
 		return
 	}
 
 	if _, err := c.service.GetByName(ctx, username); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't get user", err), 404)
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't get user", err))
 		return
 	}
 
 	if err := c.service.Delete(ctx, username); err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't delete user", err))
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't delete user", err))
 		return
 	}
 
@@ -156,15 +176,18 @@ func (c *UserControl) Delete(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param body body entities.Users true "List of user objects"
 // @Success 201 {object} rr.JSONResponse
-// @Failure 400 {object} rr.JSONResponse
+// @Failure 400,409,422,500 {object} rr.JSONResponse
 // @Router /user/createWithArray [post]
 func (c *UserControl) CreateWithArray(w http.ResponseWriter, r *http.Request) {
 	var users entities.Users
-	_ = c.rr.ReadJSON(w, r, &users)
+	if err := c.rr.ReadJSON(w, r, &users); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
 
 	for _, user := range users {
 		if _, err := c.service.Create(r.Context(), user); err != nil {
-			_ = c.rr.WriteJSONError(w, e.Wrap("couldn't create user "+user.Username, err))
+			_ = c.rr.WriteError(w, r, e.Wrap("couldn't create user "+user.Username, err))
 			return
 		}
 	}
@@ -173,44 +196,574 @@ func (c *UserControl) CreateWithArray(w http.ResponseWriter, r *http.Request) {
 	_ = c.rr.WriteJSON(w, 201, resp)
 }
 
+// VerifyEmail godoc
+// @Summary verify email
+// @Description Marks the email of a user verified with the token of the link mailed to it.
+// @Description The link is mailed on sign up and whenever the user changes the email.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param body body entities.VerifyEmailRequest true "Token from the mailed link"
+// @Success 200 {object} rr.JSONResponse
+// @Failure 400,401,422,500 {object} rr.JSONResponse
+// @Router /user/verify [post]
+func (c *UserControl) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req entities.VerifyEmailRequest
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	if err := c.service.VerifyEmail(r.Context(), req.Token); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't verify email", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "email verified"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// ForgotPassword godoc
+// @Summary forgot password
+// @Description Mails a password reset link to the accounts with the given email. The response is
+// @Description the same whether or not there are any, so it doesn't tell which emails are registered.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param body body entities.ForgotPasswordRequest true "Email of the account"
+// @Success 202 {object} rr.JSONResponse
+// @Failure 400,422,500 {object} rr.JSONResponse
+// @Router /user/password/forgot [post]
+func (c *UserControl) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req entities.ForgotPasswordRequest
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	if err := c.service.ForgotPassword(r.Context(), req.Email); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't send password reset", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "if an account has this email, a password reset link was sent to it"}
+	_ = c.rr.WriteJSON(w, 202, resp)
+}
+
+// ResetPassword godoc
+// @Summary reset password
+// @Description Sets a new password with the token of a password reset link. Every session of the user ends.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param body body entities.ResetPasswordRequest true "Token from the mailed link and the new password"
+// @Success 200 {object} rr.JSONResponse
+// @Failure 400,401,422,500 {object} rr.JSONResponse
+// @Router /user/password/reset [post]
+func (c *UserControl) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req entities.ResetPasswordRequest
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	if err := c.service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't reset password", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "password reset, log in with the new password"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
 // Login godoc
 // @Summary login
-// @Description Log user into the system
+// @Description Log user into the system. Credentials are sent as JSON or as a urlencoded form
+// @Description with the same fields. Users with two-factor authentication get a challenge token
+// @Description instead of the tokens, to be sent along with their code to POST /user/login/2fa.
+// @Tags user
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param body body entities.LoginRequest true "Credentials"
+// @Success 200 {object} rr.JSONResponse{data=ae.TokensPair}
+// @Header 200 {string} Cache-Control "no-store"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 400,401,422,429,500 {object} rr.JSONResponse
+// @Router /user/login [post]
+func (c *UserControl) Login(w http.ResponseWriter, r *http.Request) {
+	credentials, err := c.readCredentials(w, r)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	tokens, cookie, err := c.service.Authorize(r.Context(), credentials.Username, credentials.Password, u.ClientIP(r))
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't authorize user", err))
+		return
+	}
+
+	c.writeTokens(w, "user authorized", tokens, cookie)
+}
+
+// LoginTwoFactor godoc
+// @Summary login second step
+// @Description Completes the login of a user with two-factor authentication, exchanging the challenge
+// @Description token returned by POST /user/login along with a TOTP or recovery code for the tokens.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param body body ae.TwoFactorLogin true "Challenge token and code"
+// @Success 200 {object} rr.JSONResponse{data=ae.TokensPair}
+// @Header 200 {string} Cache-Control "no-store"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 400,401,422,429,500 {object} rr.JSONResponse
+// @Router /user/login/2fa [post]
+func (c *UserControl) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req ae.TwoFactorLogin
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	tokens, cookie, err := c.service.AuthorizeTwoFactor(r.Context(), req.ChallengeToken, req.Code, u.ClientIP(r))
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't authorize user", err))
+		return
+	}
+
+	c.writeTokens(w, "user authorized", tokens, cookie)
+}
+
+// LoginExternal godoc
+// @Summary login with the identity provider
+// @Description Starts a login at the OpenID provider of the company, redirecting the browser to it.
+// @Description The provider redirects back to GET /user/login/oidc/callback, the cookie set here ties
+// @Description the callback to this browser.
+// @Tags user
+// @Success 302
+// @Header 302 {string} Location "The authorization endpoint of the provider"
+// @Failure 404,500 {object} rr.JSONResponse
+// @Router /user/login/oidc [get]
+func (c *UserControl) LoginExternal(w http.ResponseWriter, r *http.Request) {
+	authURL, cookie, err := c.service.StartExternalLogin(r.Context())
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't start login", err))
+		return
+	}
+
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// LoginExternalCallback godoc
+// @Summary identity provider callback
+// @Description Completes the login at the OpenID provider. The user the provider authenticated is
+// @Description logged in, accounts are created on the first login or linked to the account with the
+// @Description same verified email.
+// @Tags user
+// @Produce json
+// @Param code query string true "The authorization code"
+// @Param state query string true "The state the login was started with"
+// @Success 200 {object} rr.JSONResponse{data=ae.TokensPair}
+// @Header 200 {string} Cache-Control "no-store"
+// @Failure 401,404,500 {object} rr.JSONResponse
+// @Router /user/login/oidc/callback [get]
+func (c *UserControl) LoginExternalCallback(w http.ResponseWriter, r *http.Request) {
+	// the login state is single use, whatever the outcome
+	stateToken := c.service.LoginStateFromCookie(r)
+	http.SetCookie(w, c.service.ResetLoginStateCookie())
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		_ = c.rr.WriteError(w, r, e.Unauthorized("login was denied by the identity provider"))
+		return
+	}
+
+	tokens, cookie, err := c.service.AuthorizeExternal(r.Context(), stateToken, query.Get("state"), query.Get("code"))
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't authorize user", err))
+		return
+	}
+
+	c.writeTokens(w, "user authorized", tokens, cookie)
+}
+
+// writeTokens responds with the tokens of a login or refresh and sets the refresh token
+// cookie, if any. Every endpoint issuing tokens answers this way, none of it may be cached.
+// Users with two-factor authentication only get a challenge token at first.
+func (c *UserControl) writeTokens(w http.ResponseWriter, message string, tokens ae.TokensPair, cookie *http.Cookie) {
+	if tokens.ChallengeToken != "" {
+		message = "second factor required"
+	}
+
+	if cookie != nil {
+		http.SetCookie(w, cookie)
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: message, Data: tokens}
+	_ = c.rr.WriteJSON(w, 200, resp, http.Header{"Cache-Control": {"no-store"}})
+}
+
+// readCredentials reads the login credentials from a urlencoded form or from a JSON body
+func (c *UserControl) readCredentials(w http.ResponseWriter, r *http.Request) (entities.LoginRequest, error) {
+	var credentials entities.LoginRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		err := c.rr.ReadJSON(w, r, &credentials)
+		return credentials, err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		return credentials, e.Wrap("couldn't parse form", err)
+	}
+
+	credentials.Username = r.PostForm.Get("username")
+	credentials.Password = r.PostForm.Get("password")
+
+	return credentials, validate.Struct(credentials)
+}
+
+// LegacyLogin godoc
+// @Summary login with query
+// @Description Log user into the system. Deprecated in favour of POST /user/login, since credentials
+// @Description in the query string end up in access logs and browser history.
 // @Tags user
 // @Produce json
 // @Param username query string true "The username for login"
 // @Param password query string true "The password for login in clear text"
-// @Success 200 {object} rr.JSONResponse
-// @Failure 401 {object} rr.JSONResponse
+// @Success 200 {object} rr.JSONResponse{data=ae.TokensPair}
+// @Header 200 {string} Cache-Control "no-store"
+// @Header 200 {string} Deprecation "When the route was deprecated, e.g. @1792281600"
+// @Header 200 {string} Sunset "When the route will be removed"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 401,429,500 {object} rr.JSONResponse
+// @Deprecated
 // @Router /user/login [get]
-func (c *UserControl) Login(w http.ResponseWriter, r *http.Request) {
+func (c *UserControl) LegacyLogin(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	username := query.Get("username")
 	password := query.Get("password")
 
-	token, cookie, err := c.service.Authorize(r.Context(), username, password)
+	tokens, cookie, err := c.service.Authorize(r.Context(), username, password, u.ClientIP(r))
 	if err != nil {
-		_ = c.rr.WriteJSONError(w, e.Wrap("couldn't authorize user", err), 401)
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't authorize user", err))
 		return
 	}
 
-	resp := rr.JSONResponse{Error: false, Message: "user authorized", Data: token}
+	c.writeTokens(w, "user authorized", tokens, cookie)
+}
+
+// Unlock godoc
+// @Summary unlock user
+// @Security ApiKeyAuth
+// @Description Lifts the lockout of a user after too many failed logins. Only admins can unlock users.
+// @Tags user
+// @Produce json
+// @Param username path string true "The name of the locked out user"
+// @Success 200 {object} rr.JSONResponse
+// @Failure 401,403,404,500 {object} rr.JSONResponse
+// @Router /user/{username}/lockout [delete]
+func (c *UserControl) Unlock(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-2]
+
+	if err := c.service.Unlock(r.Context(), username); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't unlock user", err))
+		return
+	}
 
-	http.SetCookie(w, cookie)
+	resp := rr.JSONResponse{Error: false, Message: "user unlocked"}
 	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// EnrollTwoFactor godoc
+// @Summary enroll two-factor authentication
+// @Security ApiKeyAuth
+// @Description Starts setting up TOTP two-factor authentication for the logged in user. Returns the
+// @Description secret as an otpauth URI for authenticator apps along with single-use recovery codes,
+// @Description which are not shown again. Takes effect once confirmed with POST /user/{username}/2fa/verify.
+// @Tags user
+// @Produce json
+// @Param username path string true "The name of the logged in user"
+// @Success 201 {object} rr.JSONResponse{data=ae.TwoFactorEnrollment}
+// @Failure 401,403,404,409,500 {object} rr.JSONResponse
+// @Router /user/{username}/2fa [post]
+func (c *UserControl) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-2]
+
+	enrollment, err := c.service.EnrollTwoFactor(r.Context(), username)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't enroll two-factor authentication", err))
+		return
+	}
 
+	resp := rr.JSONResponse{Error: false, Message: "confirm with a code from the authenticator", Data: enrollment}
+	_ = c.rr.WriteJSON(w, 201, resp, http.Header{"Cache-Control": {"no-store"}})
+}
+
+// ConfirmTwoFactor godoc
+// @Summary confirm two-factor authentication
+// @Security ApiKeyAuth
+// @Description Enables two-factor authentication with a code from the authenticator app the user enrolled
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param username path string true "The name of the logged in user"
+// @Param body body ae.TwoFactorCode true "Code from the authenticator app"
+// @Success 200 {object} rr.JSONResponse
+// @Failure 400,401,403,404,409,422,500 {object} rr.JSONResponse
+// @Router /user/{username}/2fa/verify [post]
+func (c *UserControl) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-3]
+
+	var req ae.TwoFactorCode
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	if err := c.service.ConfirmTwoFactor(r.Context(), username, req.Code); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't confirm two-factor authentication", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "two-factor authentication enabled"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// DisableTwoFactor godoc
+// @Summary disable two-factor authentication
+// @Security ApiKeyAuth
+// @Description Turns two-factor authentication off. Users confirm it with a TOTP or recovery code, wrong codes
+// @Description count as failed logins. Admins can do it for other users without a code.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param username path string true "The name of the user"
+// @Param body body ae.TwoFactorCode false "TOTP or recovery code, required for the own account"
+// @Success 200 {object} rr.JSONResponse
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 400,401,403,404,422,429,500 {object} rr.JSONResponse
+// @Router /user/{username}/2fa [delete]
+func (c *UserControl) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-2]
+
+	var req ae.TwoFactorCode
+	if r.ContentLength != 0 {
+		if err := c.rr.ReadJSON(w, r, &req); err != nil {
+			_ = c.rr.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if err := c.service.DisableTwoFactor(r.Context(), username, req.Code, u.ClientIP(r)); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't disable two-factor authentication", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "two-factor authentication disabled"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// CreateAPIKey godoc
+// @Summary create API key
+// @Security ApiKeyAuth
+// @Description Creates an API key for scripts and jobs, sent in the X-API-Key header instead of an access token.
+// @Description The key acts as the logged in user with the requested scopes and is shown only in this response.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param username path string true "The name of the logged in user"
+// @Param body body ae.CreateAPIKeyRequest true "Name, scopes and validity of the key"
+// @Success 201 {object} rr.JSONResponse{data=ae.NewAPIKey}
+// @Failure 400,401,403,404,422,500 {object} rr.JSONResponse
+// @Router /user/{username}/apikeys [post]
+func (c *UserControl) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-2]
+
+	var req ae.CreateAPIKeyRequest
+	if err := c.rr.ReadJSON(w, r, &req); err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	key, err := c.service.CreateAPIKey(r.Context(), username, req)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't create API key", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "API key created, it won't be shown again", Data: key}
+	_ = c.rr.WriteJSON(w, 201, resp, http.Header{"Cache-Control": {"no-store"}})
+}
+
+// APIKeys godoc
+// @Summary list API keys
+// @Security ApiKeyAuth
+// @Description Lists the API keys of the user that are neither revoked nor expired. Admins can list the keys of others.
+// @Tags user
+// @Produce json
+// @Param username path string true "The name of the user"
+// @Success 200 {object} rr.JSONResponse{data=[]ae.APIKey}
+// @Failure 401,403,500 {object} rr.JSONResponse
+// @Router /user/{username}/apikeys [get]
+func (c *UserControl) APIKeys(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-2]
+
+	keys, err := c.service.APIKeys(r.Context(), username)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't list API keys", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "API keys", Data: keys}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// RevokeAPIKey godoc
+// @Summary revoke API key
+// @Security ApiKeyAuth
+// @Description Makes the API key unusable. Admins can revoke the keys of others.
+// @Tags user
+// @Produce json
+// @Param username path string true "The name of the user"
+// @Param keyId path int true "Id of the API key"
+// @Success 200 {object} rr.JSONResponse
+// @Failure 400,401,403,404,500 {object} rr.JSONResponse
+// @Router /user/{username}/apikeys/{keyId} [delete]
+func (c *UserControl) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	username := parts[len(parts)-3]
+
+	keyId, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("invalid id supplied", err), 400)
+		return
+	}
+
+	if err = c.service.RevokeAPIKey(r.Context(), username, keyId); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't revoke API key", err))
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "API key revoked"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// Refresh godoc
+// @Summary refresh tokens
+// @Description Exchange a refresh token, sent in the body or in the refresh token cookie, for a new pair of tokens.
+// @Description Every refresh token can only be used once, reusing one revokes the session.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param body body ae.RefreshRequest false "Refresh token, if not sent in the cookie"
+// @Success 200 {object} rr.JSONResponse{data=ae.TokensPair}
+// @Header 200 {string} Cache-Control "no-store"
+// @Failure 400,401,500 {object} rr.JSONResponse
+// @Router /user/refresh [post]
+func (c *UserControl) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req ae.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := c.rr.ReadJSON(w, r, &req); err != nil {
+			_ = c.rr.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if req.RefreshToken == "" {
+		req.RefreshToken = c.service.RefreshTokenFromCookie(r)
+	}
+
+	tokens, cookie, err := c.service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		// the client has to log in again, so drop the cookie it can't use anymore
+		http.SetCookie(w, c.service.ResetCookie())
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't refresh tokens", err))
+		return
+	}
+
+	c.writeTokens(w, "tokens refreshed", tokens, cookie)
 }
 
 // Logout godoc
 // @Summary logout
-// @Description Logs out currently logged user
+// @Description Logs out currently logged user and revokes their refresh token
 // @Tags user
 // @Produce json
 // @Success 200 {object} rr.JSONResponse
+// @Failure 500 {object} rr.JSONResponse
 // @Router /user/logout [get]
 func (c *UserControl) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := c.service.Logout(r.Context(), c.service.RefreshTokenFromCookie(r)); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't log out user", err))
+		return
+	}
+
 	http.SetCookie(w, c.service.ResetCookie())
 
 	resp := rr.JSONResponse{Error: false, Message: "user logged out"}
 	_ = c.rr.WriteJSON(w, 200, resp)
 }
+
+// Sessions godoc
+// @Summary list sessions
+// @Security ApiKeyAuth
+// @Description Lists the active sessions of the logged in user, i.e. the logins that can still be refreshed
+// @Tags user
+// @Produce json
+// @Success 200 {object} rr.JSONResponse{data=[]ae.Session}
+// @Failure 401,500 {object} rr.JSONResponse
+// @Router /user/sessions [get]
+func (c *UserControl) Sessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := as.PrincipalFromContext(r.Context())
+	if !ok {
+		_ = c.rr.WriteError(w, r, e.Unauthorized("user is not authenticated"))
+		return
+	}
+
+	sessions, err := c.service.Sessions(r.Context(), principal.Username, principal.TokenId)
+	if err != nil {
+		_ = c.rr.WriteError(w, r, err)
+		return
+	}
+
+	resp := rr.JSONResponse{Error: false, Message: "active sessions", Data: sessions}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
+
+// LogoutAll godoc
+// @Summary logout all sessions
+// @Security ApiKeyAuth
+// @Description Logs the logged in user out of every session, revoking all of their tokens
+// @Tags user
+// @Produce json
+// @Success 200 {object} rr.JSONResponse
+// @Failure 401,500 {object} rr.JSONResponse
+// @Router /user/sessions [delete]
+func (c *UserControl) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, ok := as.PrincipalFromContext(r.Context())
+	if !ok {
+		_ = c.rr.WriteError(w, r, e.Unauthorized("user is not authenticated"))
+		return
+	}
+
+	if err := c.service.LogoutAll(r.Context(), principal.Username); err != nil {
+		_ = c.rr.WriteError(w, r, e.Wrap("couldn't log out user", err))
+		return
+	}
+
+	http.SetCookie(w, c.service.ResetCookie())
+
+	resp := rr.JSONResponse{Error: false, Message: "user logged out of all sessions"}
+	_ = c.rr.WriteJSON(w, 200, resp)
+}
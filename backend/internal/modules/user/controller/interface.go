@@ -6,8 +6,25 @@ type UserController interface {
 	GetByUsername(w http.ResponseWriter, r *http.Request)
 	Update(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
 	Login(w http.ResponseWriter, r *http.Request)
+	LegacyLogin(w http.ResponseWriter, r *http.Request)
+	LoginTwoFactor(w http.ResponseWriter, r *http.Request)
+	LoginExternal(w http.ResponseWriter, r *http.Request)
+	LoginExternalCallback(w http.ResponseWriter, r *http.Request)
+	Unlock(w http.ResponseWriter, r *http.Request)
+	EnrollTwoFactor(w http.ResponseWriter, r *http.Request)
+	ConfirmTwoFactor(w http.ResponseWriter, r *http.Request)
+	DisableTwoFactor(w http.ResponseWriter, r *http.Request)
+	CreateAPIKey(w http.ResponseWriter, r *http.Request)
+	APIKeys(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+	Refresh(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
+	LogoutAll(w http.ResponseWriter, r *http.Request)
+	Sessions(w http.ResponseWriter, r *http.Request)
 	Create(w http.ResponseWriter, r *http.Request)
 	CreateWithArray(w http.ResponseWriter, r *http.Request)
 }
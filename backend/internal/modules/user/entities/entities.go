@@ -1,14 +1,74 @@
 package entities
 
+import ae "backend/internal/modules/auth/entities"
+
 type User struct {
-	Id         int    `json:"id,int"`
-	Username   string `json:"username" example:"johndoe001" binding:"required"`
-	FirstName  string `json:"firstName" example:"John"`
-	LastName   string `json:"lastName" example:"Doe"`
-	Email      string `json:"email" example:"johndoe@example.com"`
-	Password   string `json:"password" example:"123456" binding:"required"`
-	Phone      string `json:"phone" example:"7-999-999-99-99"`
-	UserStatus int    `json:"userStatus,int" example:"0"`
+	Id         int     `json:"id,int"`
+	Username   string  `json:"username" example:"johndoe001" binding:"required,max=255"`
+	FirstName  string  `json:"firstName" example:"John" binding:"max=255"`
+	LastName   string  `json:"lastName" example:"Doe" binding:"max=255"`
+	Email      string  `json:"email" example:"johndoe@example.com" binding:"email,max=255"`
+	Password   string  `json:"password" example:"123456" binding:"required,max=72"`
+	Phone      string  `json:"phone" example:"7-999-999-99-99" binding:"max=255"`
+	UserStatus int     `json:"userStatus,int" example:"0"`
+	Role       ae.Role `json:"role,omitempty" example:"customer" binding:"oneof=customer staff admin"`
+	// EmailVerified is set once the user follows the link mailed to them, it is ignored on input
+	EmailVerified bool `json:"emailVerified" readonly:"true"`
 }
 
 type Users []User
+
+// UserProfile is a user as shown by GET /user/{username}. The password hash is never shown,
+// the contact details, the role and whether the email is verified only to the user and admins.
+type UserProfile struct {
+	Id            int     `json:"id,int"`
+	Username      string  `json:"username" example:"johndoe001"`
+	FirstName     string  `json:"firstName" example:"John"`
+	LastName      string  `json:"lastName" example:"Doe"`
+	Email         string  `json:"email,omitempty" example:"johndoe@example.com"`
+	Phone         string  `json:"phone,omitempty" example:"7-999-999-99-99"`
+	UserStatus    int     `json:"userStatus,int" example:"0"`
+	Role          ae.Role `json:"role,omitempty" example:"customer"`
+	EmailVerified *bool   `json:"emailVerified,omitempty"`
+}
+
+// NewUserProfile returns the profile of user, with the private fields if private is set
+func NewUserProfile(user User, private bool) UserProfile {
+	profile := UserProfile{
+		Id:         user.Id,
+		Username:   user.Username,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		UserStatus: user.UserStatus,
+	}
+
+	if private {
+		profile.Email = user.Email
+		profile.Phone = user.Phone
+		profile.Role = user.Role
+		profile.EmailVerified = &user.EmailVerified
+	}
+
+	return profile
+}
+
+// LoginRequest carries the credentials of POST /user/login, sent as JSON or as a form
+type LoginRequest struct {
+	Username string `json:"username" example:"johndoe001" binding:"required,max=255"`
+	Password string `json:"password" example:"123456" binding:"required,max=72"`
+}
+
+// VerifyEmailRequest carries the token of the link mailed to verify an email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"johndoe@example.com" binding:"required,email,max=255"`
+}
+
+// ResetPasswordRequest carries the token of the password reset link along with the new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" example:"123456" binding:"required,max=72"`
+}
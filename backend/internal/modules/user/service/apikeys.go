@@ -0,0 +1,95 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	ae "backend/internal/modules/auth/entities"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// defaultAPIKeyExpiry is how long API keys are valid unless the user asks for another period
+const defaultAPIKeyExpiry = 90 * 24 * time.Hour
+
+// CreateAPIKey creates an API key for the user with scopes their role grants. Keys act with
+// the identity of the user, so like an authenticator only the user can create them.
+// The key is only shown here, the server keeps its hash.
+func (s *UserService) CreateAPIKey(ctx context.Context, username string, request ae.CreateAPIKeyRequest) (ae.NewAPIKey, error) {
+	caller, err := authorize(ctx, username)
+	if err != nil {
+		return ae.NewAPIKey{}, err
+	}
+
+	if caller.Username != username {
+		return ae.NewAPIKey{}, e.Forbidden("users can only create their own API keys")
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if err != nil {
+		return ae.NewAPIKey{}, e.Wrap("couldn't get user", err)
+	}
+
+	var violations []e.Violation
+	for _, scope := range request.Scopes {
+		if !slices.Contains(user.Role.Scopes(), scope) {
+			violations = append(violations, e.Violation{Field: "scopes", Message: fmt.Sprintf("scope %s is not granted to the user", scope)})
+		}
+	}
+	if len(violations) > 0 {
+		return ae.NewAPIKey{}, e.Validation("invalid scopes", violations...)
+	}
+
+	expiry := defaultAPIKeyExpiry
+	if request.ExpiresInDays > 0 {
+		expiry = time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	}
+
+	key, record, err := s.auth.GenerateAPIKey()
+	if err != nil {
+		return ae.NewAPIKey{}, e.Internal("failed to generate API key", err)
+	}
+
+	record.UserId = user.Id
+	record.Name = request.Name
+	record.Scopes = request.Scopes
+	record.CreatedAt = time.Now().UTC()
+	record.ExpiresAt = record.CreatedAt.Add(expiry)
+
+	if record.Id, err = s.DB.CreateAPIKey(ctx, record); err != nil {
+		return ae.NewAPIKey{}, e.Wrap("couldn't save API key", err)
+	}
+
+	return ae.NewAPIKey{APIKey: record, Key: key}, nil
+}
+
+// APIKeys lists the usable API keys of the user, admins can list the keys of others
+func (s *UserService) APIKeys(ctx context.Context, username string) ([]ae.APIKey, error) {
+	if _, err := authorize(ctx, username); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.DB.ListAPIKeys(ctx, username)
+	if err != nil {
+		return nil, e.Wrap("couldn't list API keys", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey makes the key unusable at once, admins can revoke the keys of others
+func (s *UserService) RevokeAPIKey(ctx context.Context, username string, keyId int) error {
+	if _, err := authorize(ctx, username); err != nil {
+		return err
+	}
+
+	revoked, err := s.DB.RevokeAPIKey(ctx, username, keyId)
+	if err != nil {
+		return e.Wrap("couldn't revoke API key", err)
+	}
+	if !revoked {
+		return e.NotFound("API key not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/mail"
+	ae "backend/internal/modules/auth/entities"
+	"backend/internal/modules/user/entities"
+	"backend/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// VerifyEmail marks the email of the user verified with the token mailed to it. Tokens are
+// single-use and bound to the email they were sent to.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.auth.VerifyUserToken(ae.PurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		if err := useToken(ctx, repo, claims.ID, ae.PurposeVerifyEmail); err != nil {
+			return err
+		}
+
+		ok, err := repo.VerifyUserEmail(ctx, claims.UserId, claims.Email)
+		if err != nil {
+			return e.Wrap("couldn't verify email", err)
+		}
+		if !ok {
+			return e.Unauthorized("the email of the user changed since the token was issued")
+		}
+
+		return nil
+	})
+}
+
+// ForgotPassword mails a password reset link to every account with the given email. It
+// succeeds whether or not there is one, so that it doesn't tell which emails are registered.
+func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return e.Internal("password reset is not available", fmt.Errorf("no mailer configured"))
+	}
+
+	users, err := s.DB.GetUsersByEmail(ctx, email)
+	if err != nil {
+		return e.Wrap("couldn't get users", err)
+	}
+
+	for _, user := range users {
+		link, err := s.mailLink(ctx, ae.PurposeResetPassword, user, "/reset-password")
+		if err != nil {
+			return err
+		}
+
+		s.send(ctx, mail.Message{
+			To:      user.Email,
+			Subject: "Reset your Petstore password",
+			Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset the password of your Petstore account. "+
+				"To choose a new password, follow the link below within an hour:\n\n%s\n\n"+
+				"If it wasn't you, ignore this email, your password stays the same.\n", user.Username, link),
+		})
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with the token of a password reset link. Every session
+// of the user ends and the login lockout is lifted; as the token was mailed to the user,
+// their email is verified as well.
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	claims, err := s.auth.VerifyUserToken(ae.PurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	if err = s.passwords.Check(password, claims.Subject, claims.Email); err != nil {
+		return err
+	}
+
+	encrypted, err := s.auth.EncryptPassword(password)
+	if err != nil {
+		return e.Internal("failed to encrypt password", err)
+	}
+
+	var username string
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		if err := useToken(ctx, repo, claims.ID, ae.PurposeResetPassword); err != nil {
+			return err
+		}
+
+		user, err := repo.GetUserByUsername(ctx, claims.Subject)
+		if err != nil || user.Id != claims.UserId {
+			return e.Unauthorized("the user of the token no longer exists")
+		}
+		username = user.Username
+
+		user.Password = encrypted
+		if err = repo.UpdateUser(ctx, user); err != nil {
+			return e.Wrap("couldn't update user", err)
+		}
+
+		// the other reset links sent before are no longer needed
+		if err = repo.RevokeUserTokens(ctx, user.Id, ae.PurposeResetPassword); err != nil {
+			return e.Wrap("couldn't revoke tokens", err)
+		}
+
+		// the link went to the email in the token, it doesn't prove the user owns a new one
+		ok, err := repo.VerifyUserEmail(ctx, user.Id, claims.Email)
+		if err != nil {
+			return e.Wrap("couldn't verify email", err)
+		}
+		if !ok {
+			return e.Unauthorized("the email of the user changed since the token was issued")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = s.LogoutAll(ctx, username); err != nil {
+		return err
+	}
+
+	return s.throttle.Unlock(ctx, username)
+}
+
+// sendVerification mails the user a link to verify their email. Failures are only logged,
+// the account change they follow is done already and the link can be requested again.
+func (s *UserService) sendVerification(ctx context.Context, user entities.User) {
+	if s.mailer == nil {
+		return
+	}
+
+	link, err := s.mailLink(ctx, ae.PurposeVerifyEmail, user, "/verify-email")
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	s.send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Petstore email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm that this is your email by following the link below "+
+			"within a day:\n\n%s\n\nIf you don't have a Petstore account, ignore this email.\n", user.Username, link),
+	})
+}
+
+// mailLink issues a single-use token for purpose and returns the link to mail it in
+func (s *UserService) mailLink(ctx context.Context, purpose ae.TokenPurpose, user entities.User, path string) (string, error) {
+	token, record, err := s.auth.GenerateUserToken(purpose, user.Id, user.Username, user.Email)
+	if err != nil {
+		return "", e.Internal("failed to generate token", err)
+	}
+
+	if err = s.DB.CreateUserToken(ctx, record); err != nil {
+		return "", e.Wrap("couldn't save token", err)
+	}
+
+	return s.linkBaseUrl + path + "?token=" + url.QueryEscape(token), nil
+}
+
+// send delivers msg, failures are logged rather than reported to the client so that
+// the response doesn't tell which emails are registered
+func (s *UserService) send(ctx context.Context, msg mail.Message) {
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("failed to mail %s: %v", msg.To, err)
+	}
+}
+
+// useToken marks a mailed token used, it fails if the token was used before
+func useToken(ctx context.Context, repo repository.Repository, tokenId string, purpose ae.TokenPurpose) error {
+	ok, err := repo.UseUserToken(ctx, tokenId, purpose)
+	if err != nil {
+		return e.Wrap("couldn't use token", err)
+	}
+	if !ok {
+		return e.Unauthorized("token has already been used")
+	}
+	return nil
+}
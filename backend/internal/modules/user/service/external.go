@@ -0,0 +1,220 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/oidc"
+	ae "backend/internal/modules/auth/entities"
+	"backend/internal/modules/user/entities"
+	"backend/internal/repository"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxUsernameAttempts is how many usernames are tried for a new external user before giving up
+const maxUsernameAttempts = 10
+
+// WithExternalLogin enables logging in through the OpenID provider. Users logging in for
+// the first time get an account with defaultRole, or are linked to an existing account with
+// the same email if both the provider and we have verified it.
+func WithExternalLogin(provider *oidc.Provider, defaultRole ae.Role) UserServiceOption {
+	return func(s *UserService) {
+		s.provider = provider
+		s.externalRole = defaultRole
+	}
+}
+
+// StartExternalLogin returns the address of the provider to send the user to along with the
+// cookie binding the login to their browser, which has to be set on the redirect
+func (s *UserService) StartExternalLogin(ctx context.Context) (string, *http.Cookie, error) {
+	if s.provider == nil {
+		return "", nil, e.NotFound("external login is not enabled")
+	}
+
+	var state ae.LoginState
+	var challenge string
+	var err error
+	if state.State, err = oidc.RandomString(); err != nil {
+		return "", nil, e.Internal("failed to start login", err)
+	}
+	if state.Nonce, err = oidc.RandomString(); err != nil {
+		return "", nil, e.Internal("failed to start login", err)
+	}
+	if state.Verifier, challenge, err = oidc.NewVerifier(); err != nil {
+		return "", nil, e.Internal("failed to start login", err)
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state.State, state.Nonce, challenge)
+	if err != nil {
+		return "", nil, e.Internal("identity provider is unavailable", err)
+	}
+
+	token, err := s.auth.GenerateLoginState(state)
+	if err != nil {
+		return "", nil, e.Internal("failed to start login", err)
+	}
+
+	return authURL, s.auth.CreateLoginStateCookie(token), nil
+}
+
+// AuthorizeExternal completes the login at the provider: it checks that the state the
+// provider redirected back with is the one in the cookie of the browser, redeems the code and
+// logs in the user the identity in the ID token maps to. The provider is trusted to have
+// authenticated the user, so the login throttle and two-factor authentication don't apply.
+func (s *UserService) AuthorizeExternal(ctx context.Context, stateToken, state, code string) (ae.TokensPair, *http.Cookie, error) {
+	if s.provider == nil {
+		return ae.TokensPair{}, nil, e.NotFound("external login is not enabled")
+	}
+
+	loginState, err := s.auth.VerifyLoginState(stateToken)
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	// a mismatch means the redirect wasn't caused by a login started in this browser
+	if subtle.ConstantTimeCompare([]byte(state), []byte(loginState.State)) != 1 {
+		return ae.TokensPair{}, nil, e.Unauthorized("invalid login state")
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, loginState.Verifier, loginState.Nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) || errors.Is(err, oidc.ErrRejected) {
+		return ae.TokensPair{}, nil, &e.Error{Kind: e.ErrUnauthorized, Msg: "external login failed", Err: err}
+	} else if err != nil {
+		return ae.TokensPair{}, nil, e.Internal("identity provider is unavailable", err)
+	}
+
+	user, err := s.externalUser(ctx, identity)
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	return s.login(ctx, user)
+}
+
+// externalUser returns the user identity is linked to, linking or creating one on first login
+func (s *UserService) externalUser(ctx context.Context, identity oidc.Identity) (entities.User, error) {
+	user, err := s.DB.GetUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return user, nil
+	} else if !errors.Is(err, e.ErrNotFound) {
+		return entities.User{}, e.Wrap("couldn't get user", err)
+	}
+
+	// an account is only taken over by email if the email is proven on both sides, otherwise
+	// anyone registering the email of someone else at either end would get their account
+	if identity.EmailVerified {
+		users, err := s.DB.GetUsersByEmail(ctx, identity.Email)
+		if err != nil {
+			return entities.User{}, e.Wrap("couldn't get users", err)
+		}
+
+		var verified []entities.User
+		for _, user := range users {
+			if user.EmailVerified {
+				verified = append(verified, user)
+			}
+		}
+
+		if len(verified) == 1 {
+			if err = s.DB.LinkIdentity(ctx, verified[0].Id, identity.Issuer, identity.Subject); err != nil {
+				return entities.User{}, e.Wrap("couldn't link account", err)
+			}
+			return verified[0], nil
+		}
+	}
+
+	return s.createExternalUser(ctx, identity)
+}
+
+// createExternalUser creates an account for identity. Its password is random and never
+// told, the user can still set one through the password reset.
+func (s *UserService) createExternalUser(ctx context.Context, identity oidc.Identity) (entities.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return entities.User{}, e.Internal("failed to create user", err)
+	}
+	if password, err = s.auth.EncryptPassword(password); err != nil {
+		return entities.User{}, e.Internal("failed to encrypt password", err)
+	}
+
+	user := entities.User{
+		FirstName:     identity.GivenName,
+		LastName:      identity.FamilyName,
+		Email:         identity.Email,
+		Password:      password,
+		Role:          s.externalRole,
+		EmailVerified: identity.EmailVerified,
+	}
+
+	base := externalUsername(identity)
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		user.Username = base
+		if attempt > 1 {
+			user.Username = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		err = s.DB.WithTx(ctx, func(repo repository.Repository) (err error) {
+			if user.Id, err = repo.CreateUser(ctx, user); err != nil {
+				return err
+			}
+			if err = repo.LinkIdentity(ctx, user.Id, identity.Issuer, identity.Subject); err != nil {
+				return err
+			}
+			if user.EmailVerified {
+				_, err = repo.VerifyUserEmail(ctx, user.Id, user.Email)
+			}
+			return err
+		})
+		if !errors.Is(err, e.ErrConflict) {
+			break
+		}
+
+		// the conflict is either the username or the identity, linked by a concurrent first login
+		if linked, err := s.DB.GetUserByIdentity(ctx, identity.Issuer, identity.Subject); err == nil {
+			return linked, nil
+		}
+	}
+	if err != nil {
+		return entities.User{}, e.Wrap("couldn't create user", err)
+	}
+
+	return user, nil
+}
+
+// externalUsername derives a username from the preferred username or the email of identity
+func externalUsername(identity oidc.Identity) string {
+	name := identity.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, name)
+
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	if name == "" {
+		name = "user"
+	}
+
+	return name
+}
+
+func (s *UserService) LoginStateFromCookie(r *http.Request) string {
+	return s.auth.LoginStateFromCookie(r)
+}
+
+func (s *UserService) ResetLoginStateCookie() *http.Cookie {
+	return s.auth.CreateExpiredLoginStateCookie()
+}
@@ -2,33 +2,126 @@ package service
 
 import (
 	"backend/internal/lib/e"
+	"backend/internal/lib/oidc"
+	"backend/internal/lib/passwords"
+	"backend/internal/lib/validate"
+	"backend/internal/mail"
+	ae "backend/internal/modules/auth/entities"
 	"backend/internal/modules/auth/service"
 	"backend/internal/modules/user/entities"
 	"backend/internal/repository"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type UserService struct {
-	DB   repository.Repository
-	auth service.AuthServicer
+	DB           repository.Repository
+	auth         service.AuthServicer
+	throttle     *service.LoginThrottle
+	passwords    *passwords.Policy
+	mailer       mail.Mailer
+	linkBaseUrl  string
+	provider     *oidc.Provider
+	externalRole ae.Role
 }
 
-func NewUserService(db repository.Repository, auth service.AuthServicer) *UserService {
-	return &UserService{DB: db, auth: auth}
+type UserServiceOption func(*UserService)
+
+// WithLoginThrottle sets the brute-force protection of Authorize, by default failed logins
+// are tracked in memory with the default backoff and lockout
+func WithLoginThrottle(throttle *service.LoginThrottle) UserServiceOption {
+	return func(s *UserService) {
+		s.throttle = throttle
+	}
+}
+
+// WithPasswordPolicy sets the rules new passwords are checked against, by default
+// passwords.NewPolicy with the bundled breached password list
+func WithPasswordPolicy(policy *passwords.Policy) UserServiceOption {
+	return func(s *UserService) {
+		s.passwords = policy
+	}
+}
+
+// WithMailer enables email verification and password reset. linkBaseUrl is the address of
+// the frontend the mailed links lead to, e.g. "https://petstore.example.com"; its pages
+// post the token from the link to the API.
+func WithMailer(mailer mail.Mailer, linkBaseUrl string) UserServiceOption {
+	return func(s *UserService) {
+		s.mailer = mailer
+		s.linkBaseUrl = strings.TrimSuffix(linkBaseUrl, "/")
+	}
+}
+
+func NewUserService(db repository.Repository, auth service.AuthServicer, options ...UserServiceOption) *UserService {
+	s := &UserService{
+		DB:        db,
+		auth:      auth,
+		throttle:  service.NewLoginThrottle(service.NewMemoryAttempts()),
+		passwords: passwords.NewPolicy(),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
-func (s *UserService) GetByName(ctx context.Context, name string) (entities.User, error) {
+// GetByName returns the profile of the user, anyone can see it. Only the user and admins,
+// as far as authorize lets them manage the account, see its private fields.
+func (s *UserService) GetByName(ctx context.Context, name string) (entities.UserProfile, error) {
 	user, err := s.DB.GetUserByUsername(ctx, name)
 	if err != nil {
-		return entities.User{}, err
+		return entities.UserProfile{}, err
 	}
-	return user, nil
+
+	_, err = authorize(ctx, name)
+	return entities.NewUserProfile(user, err == nil), nil
 }
 
+// Update changes the account of userUpdate.Username. Users can only update themselves and
+// only admins can change roles, the caller is the identity verified by the auth middleware.
 func (s *UserService) Update(ctx context.Context, userUpdate entities.User) error {
-	user, _ := s.DB.GetUserByUsername(ctx, userUpdate.Username)
+	caller, err := authorize(ctx, userUpdate.Username)
+	if err != nil {
+		return err
+	}
+
+	if userUpdate.Role != "" && !caller.HasRole(ae.RoleAdmin) {
+		return e.Forbidden("only admins can change roles")
+	}
+
+	var user entities.User
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) (err error) {
+		user, err = s.update(ctx, repo, userUpdate)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// a new email is unverified until the user follows the link mailed to it, sending
+	// an unverified email again resends the link
+	if userUpdate.Email != "" && !user.EmailVerified {
+		s.sendVerification(ctx, user)
+	}
+
+	return nil
+}
+
+// update saves userUpdate over the stored account and returns the account as updated
+func (s *UserService) update(ctx context.Context, repo repository.Repository, userUpdate entities.User) (entities.User, error) {
+	user, err := repo.GetUserByUsername(ctx, userUpdate.Username)
+	if err != nil {
+		return entities.User{}, e.Wrap("couldn't get user", err)
+	}
 
 	if userUpdate.FirstName != "" {
 		user.FirstName = userUpdate.FirstName
@@ -38,8 +131,9 @@ func (s *UserService) Update(ctx context.Context, userUpdate entities.User) erro
 		user.LastName = userUpdate.LastName
 	}
 
-	if userUpdate.Email != "" {
+	if userUpdate.Email != "" && userUpdate.Email != user.Email {
 		user.Email = userUpdate.Email
+		user.EmailVerified = false
 	}
 
 	if userUpdate.Phone != "" {
@@ -51,62 +145,424 @@ func (s *UserService) Update(ctx context.Context, userUpdate entities.User) erro
 	}
 
 	if userUpdate.Password != "" {
-		user.Password, _ = s.auth.EncryptPassword(userUpdate.Password)
+		if err := s.passwords.Check(userUpdate.Password, user.Username, user.Email); err != nil {
+			return entities.User{}, err
+		}
+
+		encrypted, err := s.auth.EncryptPassword(userUpdate.Password)
+		if err != nil {
+			return entities.User{}, e.Internal("failed to encrypt password", err)
+		}
+		user.Password = encrypted
 	}
 
-	if err := s.DB.UpdateUser(ctx, user); err != nil {
-		return err
+	if userUpdate.Role != "" {
+		user.Role = userUpdate.Role
 	}
 
-	return nil
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		return entities.User{}, err
+	}
+
+	return user, nil
 }
 
 func (s *UserService) Create(ctx context.Context, user entities.User) (int, error) {
-	if user.Username == "" || user.Password == "" {
-		return 0, errors.New("username and password are mandatory")
+	// the password policy is only checked for passwords the binding rules let through,
+	// the violations of both are reported at once
+	err := validate.Struct(user)
+	if err == nil || !hasViolation(err, passwords.Field) {
+		err = joinViolations(err, s.passwords.Check(user.Password, user.Username, user.Email))
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	if _, err := s.DB.GetUserByUsername(ctx, user.Username); err == nil {
-		return 0, errors.New("user already exists")
+	if user.Password, err = s.auth.EncryptPassword(user.Password); err != nil {
+		return 0, e.Internal("failed to encrypt password", err)
 	}
 
-	user.Password, _ = s.auth.EncryptPassword(user.Password)
+	// roles are granted by admins later on, emails are verified through the mailed link
+	user.Role = ae.RoleCustomer
+	user.EmailVerified = false
+
+	var userId int
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) (err error) {
+		if _, err = repo.GetUserByUsername(ctx, user.Username); err == nil {
+			return e.Conflict("user already exists")
+		}
 
-	userId, err := s.DB.CreateUser(ctx, user)
+		userId, err = repo.CreateUser(ctx, user)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
 
+	if user.Email != "" {
+		user.Id = userId
+		s.sendVerification(ctx, user)
+	}
+
 	return userId, nil
 
 }
 
-func (s *UserService) Delete(ctx context.Context, username string) error {
-	if err := s.DB.DeleteUser(ctx, username); err != nil {
+// BootstrapAdmin makes the first admin, the operator sets it up instead of it being seeded
+// with a known password. Nothing is done once there is an admin. An existing account is only
+// promoted if password is its password, otherwise a new account is created with it. Either
+// way the password has to pass the policy, seeded accounts share a publicly known one.
+func (s *UserService) BootstrapAdmin(ctx context.Context, username, password, email string) error {
+	admins, err := s.DB.CountUsersByRole(ctx, ae.RoleAdmin)
+	if err != nil {
+		return e.Wrap("couldn't count admins", err)
+	} else if admins > 0 {
+		return nil
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if err == nil {
+		ok, err := s.auth.VerifyPassword(password, user.Password)
+		if err != nil || !ok {
+			return e.Unauthorized("invalid credentials of the admin to promote")
+		}
+		if err = s.passwords.Check(password, user.Username, user.Email); err != nil {
+			return err
+		}
+
+		user.Role = ae.RoleAdmin
+		return s.DB.UpdateUser(ctx, user)
+	} else if !errors.Is(err, e.ErrNotFound) {
+		return e.Wrap("couldn't get user", err)
+	}
+
+	user = entities.User{Username: username, Password: password, Email: email}
+	if err = joinViolations(validate.Struct(user), s.passwords.Check(password, username, email)); err != nil {
 		return err
 	}
+
+	if user.Password, err = s.auth.EncryptPassword(password); err != nil {
+		return e.Internal("failed to encrypt password", err)
+	}
+	user.Role = ae.RoleAdmin
+
+	if _, err = s.DB.CreateUser(ctx, user); err != nil {
+		return e.Wrap("couldn't create admin", err)
+	}
+
 	return nil
 }
 
-func (s *UserService) Authorize(ctx context.Context, username, password string) (string, *http.Cookie, error) {
-	user, err := s.GetByName(ctx, username)
-	if err != nil {
-		return "", nil, e.Wrap("user not found", err)
+// Delete removes the account of username, users can delete themselves and admins anyone
+func (s *UserService) Delete(ctx context.Context, username string) error {
+	if _, err := authorize(ctx, username); err != nil {
+		return err
+	}
+
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		return repo.DeleteUser(ctx, username)
+	})
+}
+
+// Authorize logs the user in with a password. Failed logins are throttled per username
+// and per client IP, see service.LoginThrottle. Users with two-factor authentication get
+// a challenge token instead of the tokens, to be passed to AuthorizeTwoFactor.
+func (s *UserService) Authorize(ctx context.Context, username, password, clientIP string) (ae.TokensPair, *http.Cookie, error) {
+	if err := s.throttle.Check(ctx, username, clientIP); err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if errors.Is(err, e.ErrNotFound) {
+		return ae.TokensPair{}, nil, s.loginFailed(ctx, username, clientIP)
+	} else if err != nil {
+		return ae.TokensPair{}, nil, e.Wrap("couldn't get user", err)
 	}
 
 	ok, err := s.auth.VerifyPassword(password, user.Password)
 	if err != nil || !ok {
-		return "", nil, errors.New("invalid credentials")
+		return ae.TokensPair{}, nil, s.loginFailed(ctx, username, clientIP)
+	}
+
+	if s.auth.NeedsRehash(user.Password) {
+		s.rehash(ctx, user, password)
+	}
+
+	twoFactor, err := s.DB.GetTwoFactor(ctx, user.Id)
+	if err != nil && !errors.Is(err, e.ErrNotFound) {
+		return ae.TokensPair{}, nil, e.Wrap("couldn't get two-factor authentication", err)
+	}
+
+	// the failed logins are only forgotten once the second factor is passed as well,
+	// otherwise knowing the password would allow unlimited guesses of the code
+	if twoFactor.Enabled() {
+		challenge, err := s.auth.GenerateChallengeToken(user.Id, user.Username)
+		if err != nil {
+			return ae.TokensPair{}, nil, e.Internal("failed to generate challenge token", err)
+		}
+		return ae.TokensPair{ChallengeToken: challenge}, nil, nil
+	}
+
+	if err = s.throttle.Succeed(ctx, username); err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	return s.login(ctx, user)
+}
+
+// rehash replaces the stored hash of the password, made with outdated parameters, by one
+// made with the current ones. The password is only known at login, so failures are logged
+// rather than failing the login; the next one tries again.
+func (s *UserService) rehash(ctx context.Context, user entities.User, password string) {
+	hash, err := s.auth.EncryptPassword(password)
+	if err != nil {
+		log.Printf("failed to rehash password of %s: %v", user.Username, err)
+		return
+	}
+
+	if _, err = s.DB.UpdateUserPassword(ctx, user.Id, user.Password, hash); err != nil {
+		log.Printf("failed to save rehashed password of %s: %v", user.Username, err)
 	}
+}
+
+// login starts a new session of the user, every login starts a new refresh token family
+func (s *UserService) login(ctx context.Context, user entities.User) (ae.TokensPair, *http.Cookie, error) {
+	familyId, err := newFamilyId()
+	if err != nil {
+		return ae.TokensPair{}, nil, e.Internal("failed to generate tokens", err)
+	}
+
+	tokens, err := s.issueTokens(ctx, s.DB, user.Id, user.Username, user.Role, familyId)
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	return tokens, s.auth.CreateCookie(tokens.RefreshToken), nil
+}
+
+// loginFailed records the failed login and returns the error to report it with. Unknown
+// usernames count as well, so that the response doesn't tell which accounts exist.
+func (s *UserService) loginFailed(ctx context.Context, username, clientIP string) error {
+	if err := s.throttle.Fail(ctx, username, clientIP); err != nil {
+		return err
+	}
+	return e.Unauthorized("invalid credentials")
+}
 
-	token, err := s.auth.GenerateToken(username)
+// Unlock lifts the lockout of username after too many failed logins, only admins can do it
+func (s *UserService) Unlock(ctx context.Context, username string) error {
+	caller, err := authorize(ctx, username)
 	if err != nil {
-		return "", nil, e.Wrap("failed to generate tokens", err)
+		return err
+	}
+
+	if !caller.HasRole(ae.RoleAdmin) {
+		return e.Forbidden("only admins can unlock accounts")
+	}
+
+	if _, err = s.DB.GetUserByUsername(ctx, username); err != nil {
+		return e.Wrap("couldn't get user", err)
+	}
+
+	return s.throttle.Unlock(ctx, username)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. Every refresh token can be
+// used once; presenting one that was already exchanged means it leaked, so the whole
+// family of tokens issued since the login is revoked.
+func (s *UserService) Refresh(ctx context.Context, refreshToken string) (ae.TokensPair, *http.Cookie, error) {
+	if refreshToken == "" {
+		return ae.TokensPair{}, nil, e.Unauthorized("missing refresh token")
 	}
 
-	refreshCookie := s.auth.CreateCookie(token)
+	var (
+		tokens  ae.TokensPair
+		reused  bool
+		revoked []ae.RevokedToken
+	)
+	err := s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		current, err := repo.GetRefreshTokenByHash(ctx, s.auth.HashRefreshToken(refreshToken))
+		if errors.Is(err, e.ErrNotFound) {
+			return e.Unauthorized("invalid refresh token")
+		} else if err != nil {
+			return e.Wrap("couldn't get refresh token", err)
+		}
 
-	return token, refreshCookie, nil
+		if !current.RevokedAt.IsZero() {
+			return e.Unauthorized("refresh token is revoked")
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return e.Unauthorized("refresh token is expired")
+		}
+
+		ok := false
+		if current.UsedAt.IsZero() {
+			if ok, err = repo.UseRefreshToken(ctx, current.Id); err != nil {
+				return e.Wrap("couldn't use refresh token", err)
+			}
+		}
+
+		// the revocation has to be committed, the error is reported once the transaction is over
+		if !ok {
+			reused = true
+			if revoked, err = repo.RevokeRefreshTokenFamily(ctx, current.FamilyId); err != nil {
+				return e.Wrap("couldn't revoke refresh tokens", err)
+			}
+			return nil
+		}
+
+		tokens, err = s.issueTokens(ctx, repo, current.UserId, current.Username, current.Role, current.FamilyId)
+		return err
+	})
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	if reused {
+		if err = s.auth.RevokeTokens(ctx, revoked...); err != nil {
+			return ae.TokensPair{}, nil, err
+		}
+		return ae.TokensPair{}, nil, e.Unauthorized("refresh token has already been used, the session is revoked")
+	}
+
+	return tokens, s.auth.CreateCookie(tokens.RefreshToken), nil
+}
+
+// Logout ends the session the given refresh token belongs to, revoking its refresh tokens
+// along with the access tokens issued in the session
+func (s *UserService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+
+	var revoked []ae.RevokedToken
+	err := s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		current, err := repo.GetRefreshTokenByHash(ctx, s.auth.HashRefreshToken(refreshToken))
+		if errors.Is(err, e.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return e.Wrap("couldn't get refresh token", err)
+		}
+
+		revoked, err = repo.RevokeRefreshTokenFamily(ctx, current.FamilyId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.auth.RevokeTokens(ctx, revoked...)
+}
+
+// LogoutAll ends every session of the user
+func (s *UserService) LogoutAll(ctx context.Context, username string) error {
+	revoked, err := s.DB.RevokeUserRefreshTokens(ctx, username)
+	if err != nil {
+		return e.Wrap("couldn't revoke refresh tokens", err)
+	}
+
+	return s.auth.RevokeTokens(ctx, revoked...)
+}
+
+// Sessions lists the active sessions of the user, currentTokenId is the id of the access
+// token the request was made with
+func (s *UserService) Sessions(ctx context.Context, username string, currentTokenId string) ([]ae.Session, error) {
+	sessions, err := s.DB.ListSessions(ctx, username, currentTokenId)
+	if err != nil {
+		return nil, e.Wrap("couldn't list sessions", err)
+	}
+	return sessions, nil
+}
+
+func (s *UserService) RefreshTokenFromCookie(r *http.Request) string {
+	return s.auth.RefreshTokenFromCookie(r)
+}
+
+// issueTokens generates an access token and stores a new refresh token in the given family
+func (s *UserService) issueTokens(ctx context.Context, repo repository.Repository, userId int, username string, role ae.Role, familyId string) (ae.TokensPair, error) {
+	accessToken, tokenId, err := s.auth.GenerateToken(userId, username, role)
+	if err != nil {
+		return ae.TokensPair{}, e.Internal("failed to generate tokens", err)
+	}
+
+	refreshToken, record, err := s.auth.GenerateRefreshToken()
+	if err != nil {
+		return ae.TokensPair{}, e.Internal("failed to generate tokens", err)
+	}
+
+	record.FamilyId = familyId
+	record.UserId = userId
+	record.AccessTokenId = tokenId
+	if _, err = repo.CreateRefreshToken(ctx, record); err != nil {
+		return ae.TokensPair{}, e.Wrap("couldn't save refresh token", err)
+	}
+
+	return ae.TokensPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// authorize checks that the caller stored in ctx by the auth middleware may manage the
+// account of username: users manage their own accounts and admins manage everyone's
+func authorize(ctx context.Context, username string) (ae.Principal, error) {
+	caller, ok := service.PrincipalFromContext(ctx)
+	if !ok {
+		return ae.Principal{}, e.Unauthorized("user is not authenticated")
+	}
+
+	if caller.IsClient() {
+		return ae.Principal{}, e.Forbidden("clients can't manage users")
+	}
+
+	// a leaked API key must not be enough to take over the account
+	if caller.IsAPIKey() {
+		return ae.Principal{}, e.Forbidden("API keys can't manage users")
+	}
+
+	if caller.Username != username && !caller.HasRole(ae.RoleAdmin) {
+		return ae.Principal{}, e.Forbidden("only admins can manage other users")
+	}
+
+	return caller, nil
+}
+
+// joinViolations merges validation errors into one listing all of their violations,
+// any other error is returned as it is
+func joinViolations(errs ...error) error {
+	var violations []e.Violation
+	for _, err := range errs {
+		var ve *e.Error
+		if err == nil {
+			continue
+		} else if !errors.As(err, &ve) || !errors.Is(err, e.ErrValidation) {
+			return err
+		}
+		violations = append(violations, ve.Violations...)
+	}
+
+	if len(violations) > 0 {
+		return e.Validation("invalid request", violations...)
+	}
+	return nil
+}
+
+func hasViolation(err error, field string) bool {
+	var ve *e.Error
+	if errors.As(err, &ve) {
+		for _, violation := range ve.Violations {
+			if violation.Field == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newFamilyId() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 func (s *UserService) ResetCookie() *http.Cookie {
@@ -1,6 +1,7 @@
 package service
 
 import (
+	ae "backend/internal/modules/auth/entities"
 	ue "backend/internal/modules/user/entities"
 	"context"
 	"net/http"
@@ -9,10 +10,32 @@ import (
 //go:generate mockgen -source=./interface.go -destination=../../../mocks/mock_user_service/mock_user_service.go
 
 type UserServicer interface {
-	GetByName(ctx context.Context, name string) (ue.User, error)
+	GetByName(ctx context.Context, name string) (ue.UserProfile, error)
 	Update(ctx context.Context, user ue.User) error
 	Create(ctx context.Context, user ue.User) (int, error)
 	Delete(ctx context.Context, username string) error
-	Authorize(ctx context.Context, username, password string) (string, *http.Cookie, error)
+	BootstrapAdmin(ctx context.Context, username, password, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	Authorize(ctx context.Context, username, password, clientIP string) (ae.TokensPair, *http.Cookie, error)
+	AuthorizeTwoFactor(ctx context.Context, challengeToken, code, clientIP string) (ae.TokensPair, *http.Cookie, error)
+	StartExternalLogin(ctx context.Context) (authURL string, stateCookie *http.Cookie, err error)
+	AuthorizeExternal(ctx context.Context, stateToken, state, code string) (ae.TokensPair, *http.Cookie, error)
+	Unlock(ctx context.Context, username string) error
+	EnrollTwoFactor(ctx context.Context, username string) (ae.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, username, code string) error
+	DisableTwoFactor(ctx context.Context, username, code, clientIP string) error
+	TwoFactorEnabled(ctx context.Context, userId int) (bool, error)
+	Refresh(ctx context.Context, refreshToken string) (ae.TokensPair, *http.Cookie, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, username string) error
+	Sessions(ctx context.Context, username string, currentTokenId string) ([]ae.Session, error)
+	CreateAPIKey(ctx context.Context, username string, request ae.CreateAPIKeyRequest) (ae.NewAPIKey, error)
+	APIKeys(ctx context.Context, username string) ([]ae.APIKey, error)
+	RevokeAPIKey(ctx context.Context, username string, keyId int) error
+	RefreshTokenFromCookie(r *http.Request) string
 	ResetCookie() *http.Cookie
+	LoginStateFromCookie(r *http.Request) string
+	ResetLoginStateCookie() *http.Cookie
 }
@@ -1,24 +1,46 @@
 package service
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/oidc"
+	"backend/internal/lib/oidc/oidctest"
+	"backend/internal/lib/passwords"
+	"backend/internal/lib/totp"
+	"backend/internal/mail"
 	mock_service "backend/internal/mocks/mock_auth_service"
+	"backend/internal/mocks/mock_mail"
 	"backend/internal/mocks/mock_repository"
+	ae "backend/internal/modules/auth/entities"
+	"backend/internal/modules/auth/service"
 	"backend/internal/modules/user/entities"
+	"backend/internal/repository"
 	"context"
 	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestUserService_GetByName(t *testing.T) {
 	testCases := []struct {
-		name     string
-		username string
-		wantErr  bool
+		name        string
+		caller      *ae.Claims
+		username    string
+		wantErr     bool
+		wantPrivate bool
 	}{
-		{"normal case", "wanomir", false},
-		{"unknown user", "john", true},
+		{"anonymous", nil, "wanomir", false, false},
+		{"unknown user", nil, "john", true, false},
+		{"the user", caller("wanomir", ae.RoleCustomer), "wanomir", false, true},
+		{"another user", caller("jenstar", ae.RoleStaff), "wanomir", false, false},
+		{"admin", caller("jenstar", ae.RoleAdmin), "wanomir", false, true},
+		{"client", &ae.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "wanomir"}, ClientId: "wanomir"}, "wanomir", false, false},
 	}
 
 	controller := gomock.NewController(t)
@@ -31,8 +53,13 @@ func TestUserService_GetByName(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if _, err := us.GetByName(context.Background(), tc.username); (err != nil) != tc.wantErr {
-				t.Errorf("GetByName() error = %v, wantErr %v", err, tc.wantErr)
+			user, err := us.GetByName(callerContext(tc.caller), tc.username)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetByName() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if private := user.Email != "" || user.Role != "" || user.EmailVerified != nil; private != tc.wantPrivate {
+				t.Errorf("GetByName() = %+v, want private fields %v", user, tc.wantPrivate)
 			}
 		})
 	}
@@ -41,10 +68,19 @@ func TestUserService_GetByName(t *testing.T) {
 func TestUserService_Update(t *testing.T) {
 	testCases := []struct {
 		name       string
+		caller     *ae.Claims
 		userUpdate entities.User
-		wantErr    bool
+		wantErr    error
 	}{
-		{"normal case", entities.User{FirstName: "John", LastName: "Snow", Email: "j.snow@wfell.com", Phone: "7-999-412-42-42", Password: "password", UserStatus: 1}, false},
+		{"normal case", caller("wanomir", ae.RoleCustomer), entities.User{Username: "wanomir", FirstName: "John", LastName: "Snow", Email: "j.snow@wfell.com", Phone: "7-999-412-42-42", Password: "Correct-Horse-7", UserStatus: 1}, nil},
+		{"weak password", caller("wanomir", ae.RoleCustomer), entities.User{Username: "wanomir", Password: "password"}, e.ErrValidation},
+		{"not authenticated", nil, entities.User{Username: "wanomir", FirstName: "John"}, e.ErrUnauthorized},
+		{"another user", caller("jenstar", ae.RoleStaff), entities.User{Username: "wanomir", FirstName: "John"}, e.ErrForbidden},
+		{"admin updates another user", caller("jenstar", ae.RoleAdmin), entities.User{Username: "wanomir", FirstName: "John"}, nil},
+		{"role change", caller("wanomir", ae.RoleStaff), entities.User{Username: "wanomir", Role: ae.RoleAdmin}, e.ErrForbidden},
+		{"role change by admin", caller("jenstar", ae.RoleAdmin), entities.User{Username: "wanomir", Role: ae.RoleStaff}, nil},
+		{"admin updates a missing user", caller("jenstar", ae.RoleAdmin), entities.User{Username: "john", FirstName: "John"}, e.ErrNotFound},
+		{"client", &ae.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "wanomir"}, ClientId: "wanomir"}, entities.User{Username: "wanomir", FirstName: "John"}, e.ErrForbidden},
 	}
 
 	controller := gomock.NewController(t)
@@ -57,8 +93,8 @@ func TestUserService_Update(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if err := us.Update(context.Background(), tc.userUpdate); (err != nil) != tc.wantErr {
-				t.Errorf("Update() error = %v, wantErr %v", err, tc.wantErr)
+			if err := us.Update(callerContext(tc.caller), tc.userUpdate); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("Update() error = %v, want %v", err, tc.wantErr)
 			}
 		})
 	}
@@ -70,9 +106,10 @@ func TestUserService_Create(t *testing.T) {
 		user    entities.User
 		wantErr bool
 	}{
-		{"normal case", entities.User{Username: "john", Password: "password"}, false},
+		{"normal case", entities.User{Username: "john", Password: "Correct-Horse-7"}, false},
 		{"incomplete credentials", entities.User{Username: "", Password: ""}, true},
-		{"user exists", entities.User{Username: "wanomir", Password: "password"}, true},
+		{"weak password", entities.User{Username: "john", Password: "password"}, true},
+		{"user exists", entities.User{Username: "wanomir", Password: "Correct-Horse-7"}, true},
 	}
 
 	controller := gomock.NewController(t)
@@ -92,14 +129,116 @@ func TestUserService_Create(t *testing.T) {
 	}
 }
 
+func TestUserService_PasswordPolicy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		user       entities.User
+		wantFields []string
+	}{
+		{"strong", entities.User{Username: "john", Password: "Correct-Horse-7"}, nil},
+		{"breached", entities.User{Username: "john", Password: "Password123"}, []string{"password"}},
+		{"username", entities.User{Username: "Johnny-Be-Good1", Password: "johnny-be-good1"}, []string{"password"}},
+		{"email", entities.User{Username: "john", Email: "John.Snow-42@example.com", Password: "john.snow-42"}, []string{"password"}},
+		{"with invalid fields", entities.User{Username: "john", Email: "john", Password: "short"}, []string{"email", "password", "password"}},
+		// the binding rules report a missing password, the policy doesn't repeat it
+		{"missing password", entities.User{Username: "john"}, []string{"password"}},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller),
+		WithPasswordPolicy(passwords.NewPolicy(passwords.WithLength(10, 72), passwords.WithMinClasses(2))))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := us.Create(context.Background(), tc.user)
+
+			var fields []string
+			var ve *e.Error
+			if errors.As(err, &ve) {
+				for _, violation := range ve.Violations {
+					fields = append(fields, violation.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("Create() error = %v, want validation error", err)
+			}
+
+			if !slices.Equal(fields, tc.wantFields) {
+				t.Errorf("Create() violations = %+v, want fields %v", err, tc.wantFields)
+			}
+		})
+	}
+}
+
+func TestUserService_BootstrapAdmin(t *testing.T) {
+	testCases := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+		wantRole ae.Role
+	}{
+		{"new admin", "root", "Correct-Horse-7", nil, ae.RoleAdmin},
+		{"weak password", "root", "password", e.ErrValidation, ""},
+		{"promotes existing user", "operator", "Correct-Horse-7", nil, ae.RoleAdmin},
+		{"wrong password of existing user", "operator", "Battery-Staple-9", e.ErrUnauthorized, ae.RoleStaff},
+		// a seeded account keeps its publicly known password when promoted
+		{"existing user with a weak password", "jenstar", "password", e.ErrValidation, ae.RoleStaff},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			mockRepo := NewMockRepository(controller)
+			us := NewUserService(mockRepo, NewMockAuth(controller))
+			_, _ = mockRepo.CreateUser(context.Background(), entities.User{Username: "operator", Password: "hash:Correct-Horse-7", Role: ae.RoleStaff})
+
+			err := us.BootstrapAdmin(context.Background(), tc.username, tc.password, "")
+			if !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Fatalf("BootstrapAdmin() error = %v, want %v", err, tc.wantErr)
+			}
+
+			user, _ := mockRepo.GetUserByUsername(context.Background(), tc.username)
+			if user.Role != tc.wantRole {
+				t.Errorf("role = %q, want %q", user.Role, tc.wantRole)
+			}
+		})
+	}
+
+	t.Run("admin exists", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+
+		mockRepo := NewMockRepository(controller)
+		us := NewUserService(mockRepo, NewMockAuth(controller))
+
+		if err := us.BootstrapAdmin(context.Background(), "admin", "Battery-Staple-9", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := us.BootstrapAdmin(context.Background(), "root", "Correct-Horse-7", ""); err != nil {
+			t.Fatalf("BootstrapAdmin() error = %v once there is an admin", err)
+		}
+		if _, err := mockRepo.GetUserByUsername(context.Background(), "root"); !errors.Is(err, e.ErrNotFound) {
+			t.Errorf("BootstrapAdmin() created another admin, want nothing done once there is an admin")
+		}
+	})
+}
+
 func TestUserService_Delete(t *testing.T) {
 	testCases := []struct {
 		name     string
+		caller   *ae.Claims
 		username string
 		wantErr  bool
 	}{
-		{"normal case", "wanomir", false},
-		{"unknown user", "john", true},
+		{"normal case", caller("wanomir", ae.RoleCustomer), "wanomir", false},
+		{"unknown user", caller("john", ae.RoleCustomer), "john", true},
+		{"not authenticated", nil, "wanomir", true},
+		{"another user", caller("jenstar", ae.RoleStaff), "wanomir", true},
+		{"admin deletes another user", caller("jenstar", ae.RoleAdmin), "wanomir", false},
 	}
 
 	controller := gomock.NewController(t)
@@ -112,7 +251,7 @@ func TestUserService_Delete(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if err := us.Delete(context.Background(), tc.username); (err != nil) != tc.wantErr {
+			if err := us.Delete(callerContext(tc.caller), tc.username); (err != nil) != tc.wantErr {
 				t.Errorf("Delete() error = %v, wantErr %v", err, tc.wantErr)
 			}
 		})
@@ -141,26 +280,757 @@ func TestUserService_Authorize(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if _, _, err := us.Authorize(context.Background(), tc.username, tc.password); (err != nil) != tc.wantErr {
+			if _, _, err := us.Authorize(context.Background(), tc.username, tc.password, "192.0.2.1"); (err != nil) != tc.wantErr {
 				t.Errorf("Authorize() error = %v, wantErr %v", err, tc.wantErr)
 			}
 		})
 	}
 }
 
+func TestUserService_AuthorizeRehash(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	us := NewUserService(mockRepo, NewMockAuth(controller))
+	ctx := context.Background()
+
+	// wanomir has a hash with outdated parameters, jenstar a current one
+	for _, username := range []string{"wanomir", "jenstar"} {
+		if _, _, err := us.Authorize(ctx, username, "password", "192.0.2.1"); err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+		if user, _ := mockRepo.GetUserByUsername(ctx, username); user.Password != "password" {
+			t.Errorf("Authorize() left the hash of %s as %q, want it rehashed", username, user.Password)
+		}
+	}
+
+	t.Run("not on failed login", func(t *testing.T) {
+		mockRepo := NewMockRepository(controller)
+		us := NewUserService(mockRepo, NewMockAuth(controller))
+
+		_, _, _ = us.Authorize(ctx, "wanomir", "my-password", "192.0.2.1")
+		if user, _ := mockRepo.GetUserByUsername(ctx, "wanomir"); user.Password != "" {
+			t.Errorf("Authorize() rehashed a wrong password")
+		}
+	})
+}
+
+func TestUserService_AuthorizeThrottled(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	mockAuth := NewMockAuth(controller)
+
+	throttle := service.NewLoginThrottle(service.NewMemoryAttempts(), service.WithBackoff(1, 100, time.Minute, time.Hour))
+	us := NewUserService(mockRepo, mockAuth, WithLoginThrottle(throttle))
+
+	for _, password := range []string{"my-password", "my-password"} {
+		if _, _, err := us.Authorize(context.Background(), "wanomir", password, "192.0.2.1"); !errors.Is(err, e.ErrUnauthorized) {
+			t.Fatalf("Authorize() error = %v, wantErr %v", err, e.ErrUnauthorized)
+		}
+	}
+
+	// even the right password is rejected until the backoff is over
+	_, _, err := us.Authorize(context.Background(), "wanomir", "password", "192.0.2.1")
+	if wait := e.RetryAfterOf(err); !errors.Is(err, e.ErrRateLimited) || wait <= 0 || wait > time.Minute {
+		t.Fatalf("Authorize() error = %v, retry after %v, want %v", err, e.RetryAfterOf(err), e.ErrRateLimited)
+	}
+
+	if err = us.Unlock(callerContext(caller("jenstar", ae.RoleAdmin)), "wanomir"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if _, _, err = us.Authorize(context.Background(), "wanomir", "password", "192.0.2.1"); err != nil {
+		t.Errorf("Authorize() error = %v, wantErr %v", err, nil)
+	}
+}
+
+func TestUserService_Unlock(t *testing.T) {
+	testCases := []struct {
+		name     string
+		caller   *ae.Claims
+		username string
+		wantErr  error
+	}{
+		{"admin", caller("jenstar", ae.RoleAdmin), "wanomir", nil},
+		{"unknown user", caller("jenstar", ae.RoleAdmin), "john", e.ErrNotFound},
+		{"locked out user", caller("wanomir", ae.RoleCustomer), "wanomir", e.ErrForbidden},
+		{"staff", caller("jenstar", ae.RoleStaff), "wanomir", e.ErrForbidden},
+		{"not authenticated", nil, "wanomir", e.ErrUnauthorized},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := us.Unlock(callerContext(tc.caller), tc.username); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("Unlock() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserService_TwoFactor(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller))
+	ctx := callerContext(caller("wanomir", ae.RoleStaff))
+	anonymous := context.Background()
+
+	if _, err := us.EnrollTwoFactor(callerContext(caller("jenstar", ae.RoleAdmin)), "wanomir"); !errors.Is(err, e.ErrForbidden) {
+		t.Fatalf("EnrollTwoFactor() error = %v, want %v", err, e.ErrForbidden)
+	}
+
+	enrollment, err := us.EnrollTwoFactor(ctx, "wanomir")
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor() error = %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") || len(enrollment.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("EnrollTwoFactor() = %+v, want an otpauth uri and %d recovery codes", enrollment, recoveryCodeCount)
+	}
+
+	// codes one and two steps ahead, the confirmation uses the current one
+	step := totp.Step(time.Now())
+	current, _ := totp.Code(enrollment.Secret, step)
+	next, _ := totp.Code(enrollment.Secret, step+1)
+
+	t.Run("not confirmed yet", func(t *testing.T) {
+		tokens, _, err := us.Authorize(anonymous, "wanomir", "password", "192.0.2.1")
+		if err != nil || tokens.AccessToken == "" {
+			t.Errorf("Authorize() = %+v, %v, want tokens", tokens, err)
+		}
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		if err := us.ConfirmTwoFactor(ctx, "wanomir", "000000"+current); !errors.Is(err, e.ErrValidation) {
+			t.Fatalf("ConfirmTwoFactor() error = %v, want %v", err, e.ErrValidation)
+		}
+		if err := us.ConfirmTwoFactor(ctx, "wanomir", current); err != nil {
+			t.Fatalf("ConfirmTwoFactor() error = %v", err)
+		}
+		if _, err := us.EnrollTwoFactor(ctx, "wanomir"); !errors.Is(err, e.ErrConflict) {
+			t.Errorf("EnrollTwoFactor() error = %v, want %v", err, e.ErrConflict)
+		}
+	})
+
+	var challenge string
+
+	t.Run("password gives a challenge", func(t *testing.T) {
+		tokens, cookie, err := us.Authorize(anonymous, "wanomir", "password", "192.0.2.1")
+		if err != nil || tokens.ChallengeToken == "" || tokens.AccessToken != "" || cookie != nil {
+			t.Fatalf("Authorize() = %+v, %v, %v, want only a challenge token", tokens, cookie, err)
+		}
+		challenge = tokens.ChallengeToken
+	})
+
+	testCases := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{"code used to confirm", current, e.ErrUnauthorized},
+		{"next code", next, nil},
+		{"replayed code", next, e.ErrUnauthorized},
+		{"recovery code", strings.ToUpper(enrollment.RecoveryCodes[0]), nil},
+		{"used recovery code", enrollment.RecoveryCodes[0], e.ErrUnauthorized},
+		{"wrong code", "123456789", e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, _, err := us.AuthorizeTwoFactor(anonymous, challenge, tc.code, "192.0.2.1")
+			if !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Fatalf("AuthorizeTwoFactor() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && tokens.AccessToken == "" {
+				t.Errorf("AuthorizeTwoFactor() = %+v, want tokens", tokens)
+			}
+		})
+	}
+
+	t.Run("disable without the second factor", func(t *testing.T) {
+		for _, code := range []string{"", "123456789", enrollment.RecoveryCodes[0]} {
+			if err := us.DisableTwoFactor(ctx, "wanomir", code, "192.0.2.2"); !errors.Is(err, e.ErrValidation) {
+				t.Errorf("DisableTwoFactor() error = %v with code %q, want %v", err, code, e.ErrValidation)
+			}
+		}
+		if enabled, err := us.TwoFactorEnabled(ctx, 1); err != nil || !enabled {
+			t.Errorf("TwoFactorEnabled() = %v, %v, want true", enabled, err)
+		}
+
+		// wrong codes count as failed logins
+		if err := us.DisableTwoFactor(ctx, "wanomir", "123456789", "192.0.2.2"); !errors.Is(err, e.ErrRateLimited) {
+			t.Errorf("DisableTwoFactor() error = %v, want %v", err, e.ErrRateLimited)
+		}
+		_ = us.Unlock(callerContext(caller("jenstar", ae.RoleAdmin)), "wanomir")
+	})
+
+	t.Run("disabled by admin", func(t *testing.T) {
+		if err := us.DisableTwoFactor(callerContext(caller("jenstar", ae.RoleAdmin)), "wanomir", "", "192.0.2.1"); err != nil {
+			t.Fatalf("DisableTwoFactor() error = %v", err)
+		}
+		tokens, _, err := us.Authorize(anonymous, "wanomir", "password", "192.0.2.1")
+		if err != nil || tokens.AccessToken == "" {
+			t.Errorf("Authorize() = %+v, %v, want tokens", tokens, err)
+		}
+	})
+
+	t.Run("disabled by the user", func(t *testing.T) {
+		enrollment, _ := us.EnrollTwoFactor(ctx, "wanomir")
+		current, _ := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+		if err := us.ConfirmTwoFactor(ctx, "wanomir", current); err != nil {
+			t.Fatalf("ConfirmTwoFactor() error = %v", err)
+		}
+
+		if err := us.DisableTwoFactor(ctx, "wanomir", enrollment.RecoveryCodes[1], "192.0.2.1"); err != nil {
+			t.Fatalf("DisableTwoFactor() error = %v", err)
+		}
+		if enabled, err := us.TwoFactorEnabled(ctx, 1); err != nil || enabled {
+			t.Errorf("TwoFactorEnabled() = %v, %v, want false", enabled, err)
+		}
+	})
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	var outbox []mail.Message
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller),
+		WithMailer(NewMockMailer(controller, &outbox), "https://petstore.example.com/"))
+	ctx := callerContext(caller("wanomir", ae.RoleCustomer))
+
+	t.Run("sign up", func(t *testing.T) {
+		if _, err := us.Create(context.Background(), entities.User{Username: "john", Password: "Correct-Horse-7", Email: "john@example.com"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if link := "https://petstore.example.com/verify-email?token="; len(outbox) != 1 || !strings.Contains(outbox[0].Body, link) {
+			t.Errorf("Create() mailed %+v, want a verification link", outbox)
+		}
+	})
+
+	if err := us.Update(ctx, entities.User{Username: "wanomir", Email: "w@example.org"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	token := mailedToken(t, outbox, "w@example.org")
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"normal case", token, nil},
+		{"used token", token, e.ErrUnauthorized},
+		{"unknown token", "token:unknown", e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := us.VerifyEmail(context.Background(), tc.token); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("VerifyEmail() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	if user, _ := us.DB.GetUserByUsername(ctx, "wanomir"); !user.EmailVerified {
+		t.Errorf("VerifyEmail() left the email unverified")
+	}
+
+	t.Run("email changed since", func(t *testing.T) {
+		_ = us.Update(ctx, entities.User{Username: "wanomir", Email: "w@example.net"})
+		stale := mailedToken(t, outbox, "w@example.net")
+		_ = us.Update(ctx, entities.User{Username: "wanomir", Email: "w@example.com"})
+
+		if err := us.VerifyEmail(context.Background(), stale); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("VerifyEmail() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+		if user, _ := us.DB.GetUserByUsername(ctx, "wanomir"); user.EmailVerified {
+			t.Errorf("Update() kept the new email verified")
+		}
+	})
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	var outbox []mail.Message
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller),
+		WithMailer(NewMockMailer(controller, &outbox), "https://petstore.example.com"))
+	ctx := context.Background()
+
+	if err := us.ForgotPassword(ctx, "nobody@example.com"); err != nil || len(outbox) != 0 {
+		t.Fatalf("ForgotPassword() = %v, mailed %d messages, want nothing mailed", err, len(outbox))
+	}
+
+	session, _, err := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = us.ForgotPassword(ctx, "WANOMIR@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	token := mailedToken(t, outbox, "wanomir@example.com")
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"unknown token", "token:unknown", e.ErrUnauthorized},
+		{"normal case", token, nil},
+		{"used token", token, e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := us.ResetPassword(ctx, tc.token, "New-Password-7"); !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Errorf("ResetPassword() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("sessions end", func(t *testing.T) {
+		if _, _, err := us.Refresh(ctx, session.RefreshToken); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("Refresh() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+
+	t.Run("email verified", func(t *testing.T) {
+		if user, _ := us.DB.GetUserByUsername(ctx, "wanomir"); !user.EmailVerified {
+			t.Errorf("ResetPassword() left the email unverified")
+		}
+	})
+
+	t.Run("email changed since", func(t *testing.T) {
+		if err := us.ForgotPassword(ctx, "wanomir@example.com"); err != nil {
+			t.Fatalf("ForgotPassword() error = %v", err)
+		}
+		stale := mailedToken(t, outbox, "wanomir@example.com")
+		_ = us.Update(callerContext(caller("wanomir", ae.RoleCustomer)), entities.User{Username: "wanomir", Email: "w@example.com"})
+
+		if err := us.ResetPassword(ctx, stale, "Other-Password-7"); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("ResetPassword() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+		if user, _ := us.DB.GetUserByUsername(ctx, "wanomir"); user.EmailVerified {
+			t.Errorf("ResetPassword() verified the new email")
+		}
+	})
+}
+
+func TestUserService_Refresh(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	mockAuth := NewMockAuth(controller)
+
+	us := NewUserService(mockRepo, mockAuth)
+	ctx := context.Background()
+
+	login, _, err := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rotated ae.TokensPair
+
+	t.Run("rotation", func(t *testing.T) {
+		if rotated, _, err = us.Refresh(ctx, login.RefreshToken); err != nil {
+			t.Fatalf("Refresh() error = %v, want nil", err)
+		}
+		if rotated.RefreshToken == login.RefreshToken || rotated.AccessToken == "" {
+			t.Errorf("Refresh() tokens = %+v, want a new pair", rotated)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		if _, _, err := us.Refresh(ctx, "unknown"); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("Refresh() error = %v, want unauthorized", err)
+		}
+	})
+
+	t.Run("reuse revokes the family", func(t *testing.T) {
+		if _, _, err := us.Refresh(ctx, login.RefreshToken); !errors.Is(err, e.ErrUnauthorized) {
+			t.Fatalf("Refresh() error = %v, want unauthorized", err)
+		}
+		// the token issued by the legitimate rotation is revoked along with the reused one
+		if _, _, err := us.Refresh(ctx, rotated.RefreshToken); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("Refresh() error = %v, want unauthorized", err)
+		}
+		// and so is the access token issued with it
+		if !revokedTokens[accessTokenId(t, mockRepo, mockAuth, rotated)] {
+			t.Errorf("Refresh() left the access token of the revoked session valid")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		tokens, _, _ := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+
+		record, _ := mockRepo.GetRefreshTokenByHash(ctx, mockAuth.HashRefreshToken(tokens.RefreshToken))
+		record.ExpiresAt = time.Now().Add(-time.Minute)
+		mockRepo.CreateRefreshToken(ctx, record)
+
+		if _, _, err := us.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("Refresh() error = %v, want unauthorized", err)
+		}
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		tokens, _, _ := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+
+		if err := us.Logout(ctx, tokens.RefreshToken); err != nil {
+			t.Fatalf("Logout() error = %v, want nil", err)
+		}
+		if _, _, err := us.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("Refresh() error = %v, want unauthorized", err)
+		}
+		if !revokedTokens[accessTokenId(t, mockRepo, mockAuth, tokens)] {
+			t.Errorf("Logout() left the access token valid")
+		}
+	})
+}
+
+func TestUserService_Sessions(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := NewMockRepository(controller)
+	mockAuth := NewMockAuth(controller)
+
+	us := NewUserService(mockRepo, mockAuth)
+	ctx := context.Background()
+
+	first, _, _ := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+	second, _, _ := us.Authorize(ctx, "wanomir", "password", "192.0.2.1")
+	currentTokenId := accessTokenId(t, mockRepo, mockAuth, second)
+
+	t.Run("list sessions", func(t *testing.T) {
+		sessions, err := us.Sessions(ctx, "wanomir", currentTokenId)
+		if err != nil {
+			t.Fatalf("Sessions() error = %v, want nil", err)
+		}
+		if len(sessions) != 2 {
+			t.Fatalf("Sessions() returned %d sessions, want 2", len(sessions))
+		}
+
+		current := 0
+		for _, session := range sessions {
+			if session.Current {
+				current++
+			}
+		}
+		if current != 1 {
+			t.Errorf("Sessions() returned %d current sessions, want 1", current)
+		}
+	})
+
+	t.Run("logout all", func(t *testing.T) {
+		if err := us.LogoutAll(ctx, "wanomir"); err != nil {
+			t.Fatalf("LogoutAll() error = %v, want nil", err)
+		}
+
+		for _, tokens := range []ae.TokensPair{first, second} {
+			if !revokedTokens[accessTokenId(t, mockRepo, mockAuth, tokens)] {
+				t.Errorf("LogoutAll() left access token %s valid", tokens.AccessToken)
+			}
+		}
+
+		if sessions, _ := us.Sessions(ctx, "wanomir", currentTokenId); len(sessions) != 0 {
+			t.Errorf("Sessions() returned %d sessions after logout, want 0", len(sessions))
+		}
+	})
+}
+
+func TestUserService_APIKeys(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller))
+
+	apiKeyCaller := service.NewContext(context.Background(), ae.Principal{Username: "wanomir", Roles: []ae.Role{ae.RoleCustomer}, APIKeyId: 1})
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		username string
+		request  ae.CreateAPIKeyRequest
+		wantErr  error
+	}{
+		{"normal case", callerContext(caller("wanomir", ae.RoleCustomer)), "wanomir", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeReadPets}}, nil},
+		{"scope not granted", callerContext(caller("wanomir", ae.RoleCustomer)), "wanomir", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeWritePets}}, e.ErrValidation},
+		{"staff scopes", callerContext(caller("jenstar", ae.RoleStaff)), "jenstar", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeReadPets, ae.ScopeWritePets}, ExpiresInDays: 7}, nil},
+		{"admin for another user", callerContext(caller("jenstar", ae.RoleAdmin)), "wanomir", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeReadPets}}, e.ErrForbidden},
+		{"with an API key", apiKeyCaller, "wanomir", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeReadPets}}, e.ErrForbidden},
+		{"not authenticated", context.Background(), "wanomir", ae.CreateAPIKeyRequest{Name: "sync", Scopes: []string{ae.ScopeReadPets}}, e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := us.CreateAPIKey(tc.ctx, tc.username, tc.request)
+			if !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Fatalf("CreateAPIKey() error = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			expiry := defaultAPIKeyExpiry
+			if tc.request.ExpiresInDays > 0 {
+				expiry = time.Duration(tc.request.ExpiresInDays) * 24 * time.Hour
+			}
+			if key.Key == "" || key.Id == 0 || key.ExpiresAt.Sub(key.CreatedAt) != expiry {
+				t.Errorf("CreateAPIKey() = %+v, want a stored key expiring in %v", key, expiry)
+			}
+		})
+	}
+
+	t.Run("list and revoke", func(t *testing.T) {
+		ctx := callerContext(caller("wanomir", ae.RoleCustomer))
+
+		keys, err := us.APIKeys(ctx, "wanomir")
+		if err != nil || len(keys) != 1 {
+			t.Fatalf("APIKeys() = %v, %v, want the one key of wanomir", keys, err)
+		}
+
+		if err = us.RevokeAPIKey(ctx, "jenstar", keys[0].Id); !errors.Is(err, e.ErrForbidden) {
+			t.Errorf("RevokeAPIKey() of another user error = %v, want %v", err, e.ErrForbidden)
+		}
+		if err = us.RevokeAPIKey(callerContext(caller("jenstar", ae.RoleAdmin)), "jenstar", keys[0].Id); !errors.Is(err, e.ErrNotFound) {
+			t.Errorf("RevokeAPIKey() of a key of someone else error = %v, want %v", err, e.ErrNotFound)
+		}
+		if err = us.RevokeAPIKey(ctx, "wanomir", keys[0].Id); err != nil {
+			t.Errorf("RevokeAPIKey() error = %v, want nil", err)
+		}
+		if keys, _ = us.APIKeys(ctx, "wanomir"); len(keys) != 0 {
+			t.Errorf("APIKeys() = %v after revoking, want none", keys)
+		}
+	})
+}
+
+func TestUserService_ExternalLogin(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	idp := oidctest.NewProvider("petstore", "secret")
+	defer idp.Close()
+
+	provider := oidc.NewProvider(idp.Issuer(), "petstore", "secret", "http://localhost/user/login/oidc/callback")
+	us := NewUserService(NewMockRepository(controller), NewMockAuth(controller), WithExternalLogin(provider, ae.RoleStaff))
+	ctx := context.Background()
+
+	// login goes through the provider as the browser would, claims are those of the ID token
+	login := func(claims map[string]any) (entities.User, error) {
+		idp.Claims = claims
+
+		authURL, cookie, err := us.StartExternalLogin(ctx)
+		if err != nil {
+			return entities.User{}, err
+		}
+		code, state, err := idp.Login(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tokens, _, err := us.AuthorizeExternal(ctx, cookie.Value, state, code)
+		if err != nil {
+			return entities.User{}, err
+		}
+		if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+			t.Errorf("AuthorizeExternal() = %+v, want the tokens", tokens)
+		}
+
+		return us.DB.GetUserByIdentity(ctx, idp.Issuer(), claims["sub"].(string))
+	}
+
+	testCases := []struct {
+		name         string
+		claims       map[string]any
+		wantUsername string
+		wantErr      error
+	}{
+		{"new user", map[string]any{"sub": "subject-1", "preferred_username": "Jane.Doe"}, "jane.doe", nil},
+		{"returning user", map[string]any{"sub": "subject-1", "preferred_username": "Jane.Doe", "email": "jane.doe@example.com"}, "jane.doe", nil},
+		{"linked by verified email", map[string]any{"sub": "subject-2", "email": "jen@example.com"}, "jenstar", nil},
+		{"unverified email at the provider", map[string]any{"sub": "subject-3", "email": "jen@example.com", "email_verified": false}, "jen", nil},
+		{"unverified email of the account", map[string]any{"sub": "subject-4", "email": "wanomir@example.com"}, "wanomir-2", nil},
+		{"invalid ID token", map[string]any{"sub": "subject-5", "aud": "other"}, "", e.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := login(tc.claims)
+			if !errors.Is(err, tc.wantErr) || (err != nil) != (tc.wantErr != nil) {
+				t.Fatalf("AuthorizeExternal() error = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if user.Username != tc.wantUsername {
+				t.Errorf("AuthorizeExternal() logged in %s, want %s", user.Username, tc.wantUsername)
+			}
+		})
+	}
+
+	t.Run("new users", func(t *testing.T) {
+		user, err := us.DB.GetUserByUsername(ctx, "jane.doe")
+		if err != nil || user.Role != ae.RoleStaff || !user.EmailVerified || user.FirstName != "Jane" {
+			t.Errorf("user = %+v, %v, want a staff member with a verified email", user, err)
+		}
+		if user, _ = us.DB.GetUserByUsername(ctx, "jen"); user.EmailVerified {
+			t.Errorf("user = %+v, want an unverified email", user)
+		}
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		authURL, cookie, _ := us.StartExternalLogin(ctx)
+		code, _, _ := idp.Login(authURL)
+
+		if _, _, err := us.AuthorizeExternal(ctx, cookie.Value, "other", code); !errors.Is(err, e.ErrUnauthorized) {
+			t.Errorf("AuthorizeExternal() error = %v, want %v", err, e.ErrUnauthorized)
+		}
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		us := NewUserService(NewMockRepository(controller), NewMockAuth(controller))
+		if _, _, err := us.StartExternalLogin(ctx); !errors.Is(err, e.ErrNotFound) {
+			t.Errorf("StartExternalLogin() error = %v, want %v", err, e.ErrNotFound)
+		}
+	})
+}
+
+func caller(username string, role ae.Role) *ae.Claims {
+	return &ae.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: username}, Role: role}
+}
+
+// callerContext returns a context carrying the identity verified by the auth middleware
+func callerContext(caller *ae.Claims) context.Context {
+	if caller == nil {
+		return context.Background()
+	}
+	return service.NewContext(context.Background(), caller.Principal())
+}
+
+// accessTokenId returns the id of the access token issued along with the refresh token of the pair
+func accessTokenId(t *testing.T, repo repository.Repository, auth service.AuthServicer, tokens ae.TokensPair) string {
+	t.Helper()
+
+	record, err := repo.GetRefreshTokenByHash(context.Background(), auth.HashRefreshToken(tokens.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record.AccessTokenId
+}
+
+// revokedTokens holds the ids of the access tokens revoked through the mock auth service
+var revokedTokens = make(map[string]bool)
+
 func NewMockAuth(controller *gomock.Controller) *mock_service.MockAuthServicer {
 	mockAuth := mock_service.NewMockAuthServicer(controller)
 
 	mockAuth.EXPECT().EncryptPassword(gomock.Any()).Return("password", nil).AnyTimes()
 
-	mockAuth.EXPECT().VerifyPassword(gomock.Any(), gomock.Any()).DoAndReturn(func(password, _ string) (bool, error) {
-		if password != "password" {
-			return false, nil
+	// hashes are outdated unless made by EncryptPassword
+	mockAuth.EXPECT().NeedsRehash(gomock.Any()).DoAndReturn(func(hash string) bool {
+		return hash != "password"
+	}).AnyTimes()
+
+	// "password" opens every account, other passwords only the ones hashed as "hash:<password>"
+	mockAuth.EXPECT().VerifyPassword(gomock.Any(), gomock.Any()).DoAndReturn(func(password, hash string) (bool, error) {
+		return password == "password" || hash == "hash:"+password, nil
+	}).AnyTimes()
+
+	var issued int
+	mockAuth.EXPECT().GenerateToken(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(int, string, ae.Role) (string, string, error) {
+		issued++
+		return fmt.Sprintf("token-%d", issued), fmt.Sprintf("token-id-%d", issued), nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().RevokeTokens(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tokens ...ae.RevokedToken) error {
+		for _, token := range tokens {
+			revokedTokens[token.TokenId] = true
 		}
-		return true, nil
+		return nil
+	}).AnyTimes()
+
+	var refreshed int
+	mockAuth.EXPECT().GenerateRefreshToken().DoAndReturn(func() (string, ae.RefreshToken, error) {
+		refreshed++
+		token := fmt.Sprintf("refresh-token-%d", refreshed)
+		return token, ae.RefreshToken{TokenHash: "hash:" + token, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().HashRefreshToken(gomock.Any()).DoAndReturn(func(token string) string {
+		return "hash:" + token
+	}).AnyTimes()
+
+	// challenge tokens carry the user in the clear, e.g. "challenge:1:wanomir"
+	mockAuth.EXPECT().GenerateChallengeToken(gomock.Any(), gomock.Any()).DoAndReturn(func(userId int, username string) (string, error) {
+		return fmt.Sprintf("challenge:%d:%s", userId, username), nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().VerifyChallengeToken(gomock.Any()).DoAndReturn(func(token string) (*ae.Claims, error) {
+		var userId int
+		parts := strings.Split(token, ":")
+		if len(parts) != 3 || parts[0] != "challenge" {
+			return nil, e.Unauthorized("invalid challenge token")
+		}
+		fmt.Sscan(parts[1], &userId)
+		return &ae.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: parts[2]}, UserId: userId}, nil
+	}).AnyTimes()
+
+	// tokens mailed to users are looked up by the token itself
+	userTokens := make(map[string]*ae.Claims)
+
+	mockAuth.EXPECT().GenerateUserToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(purpose ae.TokenPurpose, userId int, username, email string) (string, ae.UserToken, error) {
+		tokenId := fmt.Sprintf("%s-%d", purpose, len(userTokens)+1)
+		userTokens["token:"+tokenId] = &ae.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: username, ID: tokenId},
+			Type:             string(purpose),
+			UserId:           userId,
+			Email:            email,
+		}
+		return "token:" + tokenId, ae.UserToken{TokenId: tokenId, UserId: userId, Purpose: purpose, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().VerifyUserToken(gomock.Any(), gomock.Any()).DoAndReturn(func(purpose ae.TokenPurpose, token string) (*ae.Claims, error) {
+		claims, ok := userTokens[token]
+		if !ok || claims.Type != string(purpose) {
+			return nil, e.Unauthorized("invalid token")
+		}
+		return claims, nil
+	}).AnyTimes()
+
+	var apiKeys int
+	mockAuth.EXPECT().GenerateAPIKey().DoAndReturn(func() (string, ae.APIKey, error) {
+		apiKeys++
+		key := fmt.Sprintf("psk_key-%d", apiKeys)
+		return key, ae.APIKey{Prefix: key[:8], KeyHash: "hash:" + key}, nil
 	}).AnyTimes()
 
-	mockAuth.EXPECT().GenerateToken(gomock.Any()).Return("token", nil).AnyTimes()
+	// login states carry their fields in the clear, e.g. "login:state:nonce:verifier"
+	mockAuth.EXPECT().GenerateLoginState(gomock.Any()).DoAndReturn(func(state ae.LoginState) (string, error) {
+		return strings.Join([]string{"login", state.State, state.Nonce, state.Verifier}, ":"), nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().VerifyLoginState(gomock.Any()).DoAndReturn(func(token string) (ae.LoginState, error) {
+		parts := strings.Split(token, ":")
+		if len(parts) != 4 || parts[0] != "login" {
+			return ae.LoginState{}, e.Unauthorized("invalid login state")
+		}
+		return ae.LoginState{State: parts[1], Nonce: parts[2], Verifier: parts[3]}, nil
+	}).AnyTimes()
+
+	mockAuth.EXPECT().CreateLoginStateCookie(gomock.Any()).DoAndReturn(func(token string) *http.Cookie {
+		return &http.Cookie{Value: token}
+	}).AnyTimes()
 
 	mockAuth.EXPECT().CreateCookie(gomock.Any()).Return(&http.Cookie{}).AnyTimes()
 
@@ -172,16 +1042,100 @@ func NewMockAuth(controller *gomock.Controller) *mock_service.MockAuthServicer {
 func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepository {
 	mockDb := mock_repository.NewMockRepository(controller)
 
+	mockDb.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+		return fn(mockDb)
+	}).AnyTimes()
+
+	users := map[string]entities.User{
+		"wanomir": {Id: 1, Username: "wanomir", Email: "wanomir@example.com", Role: ae.RoleCustomer},
+		"jenstar": {Id: 2, Username: "jenstar", Email: "jen@example.com", EmailVerified: true, Password: "password", Role: ae.RoleStaff},
+	}
+
 	mockDb.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).DoAndReturn(func(_ctx context.Context, name string) (entities.User, error) {
-		if name == "wanomir" || name == "jenstar" {
-			return entities.User{}, nil
+		if user, ok := users[name]; ok {
+			return user, nil
+		}
+		return entities.User{}, e.NotFound("user not found")
+	}).AnyTimes()
+
+	mockDb.EXPECT().GetUsersByEmail(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, email string) ([]entities.User, error) {
+		var found []entities.User
+		for _, user := range users {
+			if strings.EqualFold(user.Email, email) {
+				found = append(found, user)
+			}
+		}
+		return found, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().CountUsersByRole(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, role ae.Role) (int, error) {
+		var count int
+		for _, user := range users {
+			if user.Role == role {
+				count++
+			}
+		}
+		return count, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entities.User) error {
+		if _, ok := users[user.Username]; ok {
+			users[user.Username] = user
+		}
+		return nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, oldHash, newHash string) (bool, error) {
+		for name, user := range users {
+			if user.Id == userId && user.Password == oldHash {
+				user.Password = newHash
+				users[name] = user
+				return true, nil
+			}
 		}
-		return entities.User{}, errors.New("user not found")
+		return false, nil
 	}).AnyTimes()
 
-	mockDb.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockDb.EXPECT().VerifyUserEmail(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, email string) (bool, error) {
+		for name, user := range users {
+			if user.Id == userId && user.Email == email {
+				user.EmailVerified = true
+				users[name] = user
+				return true, nil
+			}
+		}
+		return false, nil
+	}).AnyTimes()
 
-	mockDb.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+	// usernames are unique among all users, deleted ones included
+	mockDb.EXPECT().CreateUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entities.User) (int, error) {
+		if _, ok := users[user.Username]; ok {
+			return 0, e.Conflict("conflict")
+		}
+		user.Id = len(users) + 1
+		users[user.Username] = user
+		return user.Id, nil
+	}).AnyTimes()
+
+	// accounts at external providers are kept by issuer and subject
+	identities := make(map[string]int)
+
+	mockDb.EXPECT().GetUserByIdentity(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, issuer, subject string) (entities.User, error) {
+		for _, user := range users {
+			if userId, ok := identities[issuer+" "+subject]; ok && user.Id == userId {
+				return user, nil
+			}
+		}
+		return entities.User{}, e.NotFound("user not found")
+	}).AnyTimes()
+
+	mockDb.EXPECT().LinkIdentity(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, issuer, subject string) error {
+		if _, ok := identities[issuer+" "+subject]; ok {
+			return e.Conflict("conflict")
+		}
+		identities[issuer+" "+subject] = userId
+		return nil
+	}).AnyTimes()
 
 	mockDb.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ctx context.Context, name string) error {
 		if name == "wanomir" || name == "jenstar" {
@@ -190,5 +1144,230 @@ func NewMockRepository(controller *gomock.Controller) *mock_repository.MockRepos
 		return errors.New("user not found")
 	}).AnyTimes()
 
+	// refresh tokens are kept in memory by their hash
+	refreshTokens := make(map[string]ae.RefreshToken)
+
+	mockDb.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token ae.RefreshToken) (int, error) {
+		if token.Id == 0 {
+			token.Id = len(refreshTokens) + 1
+		}
+		refreshTokens[token.TokenHash] = token
+		return token.Id, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().GetRefreshTokenByHash(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, hash string) (ae.RefreshToken, error) {
+		token, ok := refreshTokens[hash]
+		if !ok {
+			return ae.RefreshToken{}, e.NotFound("refresh token not found")
+		}
+		return token, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (bool, error) {
+		for hash, token := range refreshTokens {
+			if token.Id == id && token.UsedAt.IsZero() && token.RevokedAt.IsZero() {
+				token.UsedAt = time.Now()
+				refreshTokens[hash] = token
+				return true, nil
+			}
+		}
+		return false, nil
+	}).AnyTimes()
+
+	revoke := func(match func(ae.RefreshToken) bool) []ae.RevokedToken {
+		var revoked []ae.RevokedToken
+		for hash, token := range refreshTokens {
+			if match(token) && token.RevokedAt.IsZero() {
+				token.RevokedAt = time.Now()
+				refreshTokens[hash] = token
+				revoked = append(revoked, ae.RevokedToken{TokenId: token.AccessTokenId, ExpiresAt: token.ExpiresAt})
+			}
+		}
+		return revoked
+	}
+
+	mockDb.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, familyId string) ([]ae.RevokedToken, error) {
+		return revoke(func(token ae.RefreshToken) bool { return token.FamilyId == familyId }), nil
+	}).AnyTimes()
+
+	// every refresh token in the tests belongs to the same user
+	mockDb.EXPECT().RevokeUserRefreshTokens(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string) ([]ae.RevokedToken, error) {
+		return revoke(func(ae.RefreshToken) bool { return true }), nil
+	}).AnyTimes()
+
+	// API keys are kept by id, ids of the users map stand in for the join with users
+	apiKeys := make(map[int]ae.APIKey)
+	ownerOf := func(key ae.APIKey) string {
+		for name, user := range users {
+			if user.Id == key.UserId {
+				return name
+			}
+		}
+		return ""
+	}
+
+	mockDb.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key ae.APIKey) (int, error) {
+		key.Id = len(apiKeys) + 1
+		apiKeys[key.Id] = key
+		return key.Id, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username string) ([]ae.APIKey, error) {
+		keys := make([]ae.APIKey, 0)
+		for id := 1; id <= len(apiKeys); id++ {
+			if key, ok := apiKeys[id]; ok && ownerOf(key) == username {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, username string, keyId int) (bool, error) {
+		key, ok := apiKeys[keyId]
+		if !ok || ownerOf(key) != username {
+			return false, nil
+		}
+		delete(apiKeys, keyId)
+		return true, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().ListSessions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, currentTokenId string) ([]ae.Session, error) {
+		families := make(map[string]*ae.Session)
+		revoked := make(map[string]bool)
+		for _, token := range refreshTokens {
+			if !token.RevokedAt.IsZero() {
+				revoked[token.FamilyId] = true
+			}
+			if families[token.FamilyId] == nil {
+				families[token.FamilyId] = &ae.Session{Id: token.FamilyId}
+			}
+			if token.AccessTokenId == currentTokenId {
+				families[token.FamilyId].Current = true
+			}
+		}
+
+		sessions := make([]ae.Session, 0)
+		for familyId, session := range families {
+			if !revoked[familyId] {
+				sessions = append(sessions, *session)
+			}
+		}
+		return sessions, nil
+	}).AnyTimes()
+
+	// second factors are kept in memory by the user id, recovery codes by their hash
+	twoFactors := make(map[int]ae.TwoFactor)
+	recoveryCodes := make(map[int]map[string]bool)
+
+	mockDb.EXPECT().GetTwoFactor(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int) (ae.TwoFactor, error) {
+		twoFactor, ok := twoFactors[userId]
+		if !ok {
+			return ae.TwoFactor{}, e.NotFound("two-factor authentication not set up")
+		}
+		return twoFactor, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().SaveTwoFactor(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, twoFactor ae.TwoFactor, hashes []string) error {
+		twoFactors[twoFactor.UserId] = twoFactor
+		recoveryCodes[twoFactor.UserId] = make(map[string]bool)
+		for _, hash := range hashes {
+			recoveryCodes[twoFactor.UserId][hash] = true
+		}
+		return nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().EnableTwoFactor(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, step int64) error {
+		twoFactor := twoFactors[userId]
+		twoFactor.EnabledAt, twoFactor.LastUsedStep = time.Now(), step
+		twoFactors[userId] = twoFactor
+		return nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().DeleteTwoFactor(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int) error {
+		delete(twoFactors, userId)
+		delete(recoveryCodes, userId)
+		return nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UseTwoFactorStep(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, step int64) (bool, error) {
+		twoFactor := twoFactors[userId]
+		if step <= twoFactor.LastUsedStep {
+			return false, nil
+		}
+		twoFactor.LastUsedStep = step
+		twoFactors[userId] = twoFactor
+		return true, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, hash string) (bool, error) {
+		if !recoveryCodes[userId][hash] {
+			return false, nil
+		}
+		delete(recoveryCodes[userId], hash)
+		return true, nil
+	}).AnyTimes()
+
+	// tokens mailed to users are kept in memory by their id
+	userTokens := make(map[string]ae.UserToken)
+	usedTokens := make(map[string]bool)
+
+	mockDb.EXPECT().CreateUserToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token ae.UserToken) error {
+		userTokens[token.TokenId] = token
+		return nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().UseUserToken(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tokenId string, purpose ae.TokenPurpose) (bool, error) {
+		token, ok := userTokens[tokenId]
+		if !ok || token.Purpose != purpose || usedTokens[tokenId] || time.Now().After(token.ExpiresAt) {
+			return false, nil
+		}
+		usedTokens[tokenId] = true
+		return true, nil
+	}).AnyTimes()
+
+	mockDb.EXPECT().RevokeUserTokens(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userId int, purpose ae.TokenPurpose) error {
+		for tokenId, token := range userTokens {
+			if token.UserId == userId && token.Purpose == purpose {
+				usedTokens[tokenId] = true
+			}
+		}
+		return nil
+	}).AnyTimes()
+
 	return mockDb
 }
+
+// NewMockMailer returns a mailer collecting the sent messages in outbox
+func NewMockMailer(controller *gomock.Controller, outbox *[]mail.Message) *mock_mail.MockMailer {
+	mockMailer := mock_mail.NewMockMailer(controller)
+
+	mockMailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msg mail.Message) error {
+		*outbox = append(*outbox, msg)
+		return nil
+	}).AnyTimes()
+
+	return mockMailer
+}
+
+// mailedToken returns the token of the link in the last message of outbox sent to the address
+func mailedToken(t *testing.T, outbox []mail.Message, to string) string {
+	t.Helper()
+
+	for i := len(outbox) - 1; i >= 0; i-- {
+		if outbox[i].To != to {
+			continue
+		}
+		_, rest, ok := strings.Cut(outbox[i].Body, "?token=")
+		if !ok {
+			t.Fatalf("mail to %s has no link: %s", to, outbox[i].Body)
+		}
+		token, err := url.QueryUnescape(strings.Fields(rest)[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	t.Fatalf("no mail sent to %s", to)
+	return ""
+}
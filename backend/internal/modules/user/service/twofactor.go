@@ -0,0 +1,257 @@
+package service
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/lib/totp"
+	ae "backend/internal/modules/auth/entities"
+	"backend/internal/modules/user/entities"
+	"backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer        = "Petstore"
+	recoveryCodeCount = 10
+)
+
+// EnrollTwoFactor starts setting up two-factor authentication for the user. It takes effect
+// once confirmed with a code, see ConfirmTwoFactor; until then the enrollment can be restarted.
+// The recovery codes are only shown here, the server keeps their hashes.
+func (s *UserService) EnrollTwoFactor(ctx context.Context, username string) (ae.TwoFactorEnrollment, error) {
+	user, err := s.twoFactorOwner(ctx, username)
+	if err != nil {
+		return ae.TwoFactorEnrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return ae.TwoFactorEnrollment{}, e.Internal("failed to generate secret", err)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return ae.TwoFactorEnrollment{}, e.Internal("failed to generate recovery codes", err)
+	}
+
+	err = s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		current, err := repo.GetTwoFactor(ctx, user.Id)
+		if err != nil && !errors.Is(err, e.ErrNotFound) {
+			return e.Wrap("couldn't get two-factor authentication", err)
+		}
+		if current.Enabled() {
+			return e.Conflict("two-factor authentication is already enabled")
+		}
+
+		return repo.SaveTwoFactor(ctx, ae.TwoFactor{UserId: user.Id, Secret: secret}, hashes)
+	})
+	if err != nil {
+		return ae.TwoFactorEnrollment{}, err
+	}
+
+	return ae.TwoFactorEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(totpIssuer, user.Username, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves with a code
+// that the authenticator app is set up
+func (s *UserService) ConfirmTwoFactor(ctx context.Context, username, code string) error {
+	user, err := s.twoFactorOwner(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		twoFactor, err := repo.GetTwoFactor(ctx, user.Id)
+		if err != nil {
+			return e.Wrap("couldn't get two-factor authentication", err)
+		}
+		if twoFactor.Enabled() {
+			return e.Conflict("two-factor authentication is already enabled")
+		}
+
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return e.Validation("invalid code", e.Violation{Field: "code", Message: "does not match the authenticator"})
+		}
+
+		return repo.EnableTwoFactor(ctx, user.Id, step)
+	})
+}
+
+// DisableTwoFactor turns two-factor authentication off. Users have to prove they still hold
+// the second factor with a TOTP or recovery code, so that a stolen session isn't enough;
+// wrong codes count as failed logins. Admins can turn it off for users who lost both their
+// authenticator and their recovery codes without one.
+func (s *UserService) DisableTwoFactor(ctx context.Context, username, code, clientIP string) error {
+	caller, err := authorize(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if err != nil {
+		return e.Wrap("couldn't get user", err)
+	}
+
+	if caller.Username == username {
+		if code == "" {
+			return e.Validation("invalid code", e.Violation{Field: "code", Message: "is required"})
+		}
+		if err = s.throttle.Check(ctx, username, clientIP); err != nil {
+			return err
+		}
+
+		ok, err := s.verifySecondFactor(ctx, user.Id, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err = s.throttle.Fail(ctx, username, clientIP); err != nil {
+				return err
+			}
+			return e.Validation("invalid code", e.Violation{Field: "code", Message: "does not match the authenticator or a recovery code"})
+		}
+		if err = s.throttle.Succeed(ctx, username); err != nil {
+			return err
+		}
+	}
+
+	return s.DB.WithTx(ctx, func(repo repository.Repository) error {
+		return repo.DeleteTwoFactor(ctx, user.Id)
+	})
+}
+
+// TwoFactorEnabled reports whether the user has confirmed two-factor authentication
+func (s *UserService) TwoFactorEnabled(ctx context.Context, userId int) (bool, error) {
+	twoFactor, err := s.DB.GetTwoFactor(ctx, userId)
+	if errors.Is(err, e.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, e.Wrap("couldn't get two-factor authentication", err)
+	}
+
+	return twoFactor.Enabled(), nil
+}
+
+// AuthorizeTwoFactor is the second step of the login of users with two-factor authentication:
+// it exchanges the challenge token issued by Authorize along with a TOTP or recovery code for
+// the tokens. Wrong codes count as failed logins.
+func (s *UserService) AuthorizeTwoFactor(ctx context.Context, challengeToken, code, clientIP string) (ae.TokensPair, *http.Cookie, error) {
+	claims, err := s.auth.VerifyChallengeToken(challengeToken)
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	username := claims.Subject
+	if err = s.throttle.Check(ctx, username, clientIP); err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if errors.Is(err, e.ErrNotFound) || (err == nil && user.Id != claims.UserId) {
+		return ae.TokensPair{}, nil, e.Unauthorized("invalid challenge token")
+	} else if err != nil {
+		return ae.TokensPair{}, nil, e.Wrap("couldn't get user", err)
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user.Id, code)
+	if err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+	if !ok {
+		return ae.TokensPair{}, nil, s.loginFailed(ctx, username, clientIP)
+	}
+
+	if err = s.throttle.Succeed(ctx, username); err != nil {
+		return ae.TokensPair{}, nil, err
+	}
+
+	return s.login(ctx, user)
+}
+
+// verifySecondFactor accepts the current TOTP code or an unused recovery code, either only once
+func (s *UserService) verifySecondFactor(ctx context.Context, userId int, code string) (bool, error) {
+	twoFactor, err := s.DB.GetTwoFactor(ctx, userId)
+	if errors.Is(err, e.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, e.Wrap("couldn't get two-factor authentication", err)
+	}
+
+	if !twoFactor.Enabled() {
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(twoFactor.Secret, code, time.Now()); ok {
+		if ok, err = s.DB.UseTwoFactorStep(ctx, userId, step); err != nil {
+			return false, e.Wrap("couldn't use code", err)
+		}
+		return ok, nil
+	}
+
+	ok, err := s.DB.UseRecoveryCode(ctx, userId, hashRecoveryCode(code))
+	if err != nil {
+		return false, e.Wrap("couldn't use recovery code", err)
+	}
+
+	return ok, nil
+}
+
+// twoFactorOwner returns the user setting up two-factor authentication. The authenticator
+// belongs to the user, so unlike other account changes admins can't do it for others.
+func (s *UserService) twoFactorOwner(ctx context.Context, username string) (entities.User, error) {
+	caller, err := authorize(ctx, username)
+	if err != nil {
+		return entities.User{}, err
+	}
+
+	if caller.Username != username {
+		return entities.User{}, e.Forbidden("users can only set up their own two-factor authentication")
+	}
+
+	user, err := s.DB.GetUserByUsername(ctx, username)
+	if err != nil {
+		return entities.User{}, e.Wrap("couldn't get user", err)
+	}
+
+	return user, nil
+}
+
+// newRecoveryCodes generates the recovery codes shown to the user along with their hashes.
+// Codes look like "k7gq-2mxd-p4ta", 60 random bits each.
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:12]
+		code := raw[:4] + "-" + raw[4:8] + "-" + raw[8:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and separators, so codes can be typed as the user likes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
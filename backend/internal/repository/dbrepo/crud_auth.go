@@ -0,0 +1,624 @@
+package dbrepo
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/modules/auth/entities"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+func (db *PostgresDBRepo) CreateRefreshToken(ctx context.Context, token entities.RefreshToken) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO refresh_tokens (family_id, user_id, token_hash, access_token_id, expires_at)
+				 VALUES ($1, $2, $3, $4, $5)
+				 RETURNING id`
+
+	var id int
+	if err := db.conn.QueryRowContext(ctx, query, token.FamilyId, token.UserId, token.TokenHash,
+		token.AccessTokenId, token.ExpiresAt).Scan(&id); err != nil {
+		return 0, queryError("failed to execute query", err)
+	}
+
+	return id, nil
+}
+
+// GetRefreshTokenByHash returns the refresh token with the given hash along with the name of
+// its owner; tokens of deleted users are not found
+func (db *PostgresDBRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT rt.id, rt.family_id, rt.user_id, u.username, u.role, rt.token_hash,
+				        COALESCE(rt.access_token_id, ''), rt.expires_at, rt.created_at, rt.used_at, rt.revoked_at
+				 FROM refresh_tokens rt
+				 JOIN users u ON u.id = rt.user_id AND u.is_deleted = FALSE
+				 WHERE rt.token_hash = $1`
+
+	var (
+		token     entities.RefreshToken
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+	err := db.conn.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.Id,
+		&token.FamilyId,
+		&token.UserId,
+		&token.Username,
+		&token.Role,
+		&token.TokenHash,
+		&token.AccessTokenId,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&usedAt,
+		&revokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.RefreshToken{}, e.NotFound("refresh token not found")
+	} else if err != nil {
+		return entities.RefreshToken{}, queryError("failed to execute query", err)
+	}
+
+	token.UsedAt = usedAt.Time
+	token.RevokedAt = revokedAt.Time
+
+	return token, nil
+}
+
+// UseRefreshToken marks the token as exchanged. It reports false if the token has already
+// been used or revoked, so that concurrent refreshes with the same token can't both succeed.
+func (db *PostgresDBRepo) UseRefreshToken(ctx context.Context, tokenId int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE refresh_tokens SET used_at = now()
+				 WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+
+	return db.execAffectsRow(ctx, query, tokenId)
+}
+
+// execAffectsRow executes a conditional update and reports whether it changed a row
+func (db *PostgresDBRepo) execAffectsRow(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, queryError("failed to execute query", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, queryError("failed to get affected rows", err)
+	}
+
+	return rows == 1, nil
+}
+
+func (db *PostgresDBRepo) RevokeRefreshTokenFamily(ctx context.Context, familyId string) ([]entities.RevokedToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE refresh_tokens SET revoked_at = now()
+				 WHERE family_id = $1 AND revoked_at IS NULL
+				 RETURNING COALESCE(access_token_id, ''), expires_at`
+
+	return db.queryRevokedTokens(ctx, query, familyId)
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user, ending all of their sessions
+func (db *PostgresDBRepo) RevokeUserRefreshTokens(ctx context.Context, username string) ([]entities.RevokedToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE refresh_tokens rt SET revoked_at = now()
+				 FROM users u
+				 WHERE u.id = rt.user_id AND u.username = $1 AND rt.revoked_at IS NULL
+				 RETURNING COALESCE(rt.access_token_id, ''), rt.expires_at`
+
+	return db.queryRevokedTokens(ctx, query, username)
+}
+
+// queryRevokedTokens runs a query returning access token ids along with expiry times. The
+// expiry of a refresh token outlives the access token issued with it, so it is used as an
+// upper bound of how long the access token has to stay revoked.
+func (db *PostgresDBRepo) queryRevokedTokens(ctx context.Context, query string, args ...any) ([]entities.RevokedToken, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var tokens []entities.RevokedToken
+	for rows.Next() {
+		var token entities.RevokedToken
+		if err = rows.Scan(&token.TokenId, &token.ExpiresAt); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		// tokens issued before access tokens were tracked have nothing to revoke
+		if token.TokenId != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return tokens, nil
+}
+
+// ListSessions returns the sessions of the user that can still be refreshed, most recently
+// used first. The session the access token currentTokenId was issued in is marked as current.
+func (db *PostgresDBRepo) ListSessions(ctx context.Context, username string, currentTokenId string) ([]entities.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT rt.family_id, MIN(rt.created_at), MAX(rt.created_at), MAX(rt.expires_at),
+				        COALESCE(BOOL_OR(rt.access_token_id = $2), FALSE)
+				 FROM refresh_tokens rt
+				 JOIN users u ON u.id = rt.user_id AND u.is_deleted = FALSE
+				 WHERE u.username = $1
+				 GROUP BY rt.family_id
+				 HAVING BOOL_AND(rt.revoked_at IS NULL)
+				    AND BOOL_OR(rt.used_at IS NULL AND rt.expires_at > now())
+				 ORDER BY MAX(rt.created_at) DESC`
+
+	rows, err := db.conn.QueryContext(ctx, query, username, currentTokenId)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]entities.Session, 0)
+	for rows.Next() {
+		var session entities.Session
+		if err = rows.Scan(
+			&session.Id,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.Current,
+		); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return sessions, nil
+}
+
+func (db *PostgresDBRepo) RevokeAccessTokens(ctx context.Context, tokens []entities.RevokedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	tokenIds := make([]string, len(tokens))
+	expiresAt := make([]time.Time, len(tokens))
+	for i, token := range tokens {
+		tokenIds[i] = token.TokenId
+		expiresAt[i] = token.ExpiresAt
+	}
+
+	query := `INSERT INTO revoked_tokens (token_id, expires_at)
+				 SELECT * FROM UNNEST($1::VARCHAR[], $2::TIMESTAMP[])
+				 ON CONFLICT (token_id) DO NOTHING`
+
+	if _, err := db.conn.ExecContext(ctx, query, tokenIds, expiresAt); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+// GetRevokedAccessTokens returns the tokens revoked since the given time that haven't expired yet
+func (db *PostgresDBRepo) GetRevokedAccessTokens(ctx context.Context, since time.Time) ([]entities.RevokedToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT token_id, expires_at FROM revoked_tokens
+				 WHERE revoked_at >= $1 AND expires_at > now()`
+
+	return db.queryRevokedTokens(ctx, query, since)
+}
+
+func (db *PostgresDBRepo) GetClientById(ctx context.Context, clientId string) (entities.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT id, client_id, name, secret_hash, scopes, is_disabled
+				 FROM oauth_clients
+				 WHERE client_id = $1`
+
+	var (
+		client entities.Client
+		scopes string
+	)
+	err := db.conn.QueryRowContext(ctx, query, clientId).Scan(
+		&client.Id,
+		&client.ClientId,
+		&client.Name,
+		&client.SecretHash,
+		&scopes,
+		&client.Disabled,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.Client{}, e.NotFound("client not found")
+	} else if err != nil {
+		return entities.Client{}, queryError("failed to execute query", err)
+	}
+
+	client.Scopes = strings.Fields(scopes)
+
+	return client, nil
+}
+
+func (db *PostgresDBRepo) GetLoginAttempts(ctx context.Context, keys []string) ([]entities.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT key, failures, last_failure_at, locked_until
+				 FROM login_attempts
+				 WHERE key = ANY($1)`
+
+	rows, err := db.conn.QueryContext(ctx, query, keys)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	attempts := make([]entities.LoginAttempts, 0, len(keys))
+	for rows.Next() {
+		attempt, err := scanLoginAttempts(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return attempts, nil
+}
+
+// RecordLoginFailure counts the failure in a single statement, so that concurrent
+// failures of the same key are never lost
+func (db *PostgresDBRepo) RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (entities.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO login_attempts (key, failures, last_failure_at)
+				 VALUES ($1, 1, $2)
+				 ON CONFLICT (key) DO UPDATE SET
+					 failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1
+									 ELSE login_attempts.failures + 1 END,
+					 last_failure_at = EXCLUDED.last_failure_at
+				 RETURNING key, failures, last_failure_at, locked_until`
+
+	return scanLoginAttempts(db.conn.QueryRowContext(ctx, query, key, at, windowStart))
+}
+
+func (db *PostgresDBRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE login_attempts SET locked_until = $2 WHERE key = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, key, until); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `DELETE FROM login_attempts WHERE key = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, key); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func scanLoginAttempts(row interface{ Scan(dest ...any) error }) (entities.LoginAttempts, error) {
+	var (
+		attempt     entities.LoginAttempts
+		lockedUntil sql.NullTime
+	)
+	if err := row.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &lockedUntil); err != nil {
+		return entities.LoginAttempts{}, queryError("failed to scan row", err)
+	}
+	attempt.LockedUntil = lockedUntil.Time
+
+	return attempt, nil
+}
+
+func (db *PostgresDBRepo) GetTwoFactor(ctx context.Context, userId int) (entities.TwoFactor, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT user_id, secret, enabled_at, last_used_step
+				 FROM user_two_factor
+				 WHERE user_id = $1`
+
+	var (
+		twoFactor entities.TwoFactor
+		enabledAt sql.NullTime
+	)
+	err := db.conn.QueryRowContext(ctx, query, userId).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Secret,
+		&enabledAt,
+		&twoFactor.LastUsedStep,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.TwoFactor{}, e.NotFound("two-factor authentication not set up")
+	} else if err != nil {
+		return entities.TwoFactor{}, queryError("failed to execute query", err)
+	}
+	twoFactor.EnabledAt = enabledAt.Time
+
+	return twoFactor, nil
+}
+
+func (db *PostgresDBRepo) SaveTwoFactor(ctx context.Context, twoFactor entities.TwoFactor, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO user_two_factor (user_id, secret, enabled_at, last_used_step)
+				 VALUES ($1, $2, NULL, 0)
+				 ON CONFLICT (user_id) DO UPDATE SET
+					 secret = EXCLUDED.secret, enabled_at = NULL, last_used_step = 0, created_at = now()`
+
+	if _, err := db.conn.ExecContext(ctx, query, twoFactor.UserId, twoFactor.Secret); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	query = `DELETE FROM recovery_codes WHERE user_id = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, twoFactor.UserId); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	query = `INSERT INTO recovery_codes (user_id, code_hash)
+				 SELECT $1, UNNEST($2::VARCHAR[])`
+
+	if _, err := db.conn.ExecContext(ctx, query, twoFactor.UserId, recoveryCodeHashes); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) EnableTwoFactor(ctx context.Context, userId int, step int64) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE user_two_factor SET enabled_at = now(), last_used_step = $2 WHERE user_id = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, userId, step); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) DeleteTwoFactor(ctx context.Context, userId int) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `DELETE FROM recovery_codes WHERE user_id = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, userId); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	query = `DELETE FROM user_two_factor WHERE user_id = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, userId); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+// UseTwoFactorStep only moves forward, so a code is accepted once even if it is sent concurrently
+func (db *PostgresDBRepo) UseTwoFactorStep(ctx context.Context, userId int, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE user_two_factor SET last_used_step = $2
+				 WHERE user_id = $1 AND last_used_step < $2 AND enabled_at IS NOT NULL`
+
+	return db.execAffectsRow(ctx, query, userId, step)
+}
+
+func (db *PostgresDBRepo) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE recovery_codes SET used_at = now()
+				 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	return db.execAffectsRow(ctx, query, userId, codeHash)
+}
+
+func (db *PostgresDBRepo) CreateUserToken(ctx context.Context, token entities.UserToken) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO user_tokens (token_id, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4)`
+
+	if _, err := db.conn.ExecContext(ctx, query, token.TokenId, token.UserId, token.Purpose, token.ExpiresAt); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) UseUserToken(ctx context.Context, tokenId string, purpose entities.TokenPurpose) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE user_tokens SET used_at = now()
+				 WHERE token_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()`
+
+	return db.execAffectsRow(ctx, query, tokenId, purpose)
+}
+
+func (db *PostgresDBRepo) RevokeUserTokens(ctx context.Context, userId int, purpose entities.TokenPurpose) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE user_tokens SET used_at = now() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	if _, err := db.conn.ExecContext(ctx, query, userId, purpose); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) CreateAPIKey(ctx context.Context, key entities.APIKey) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+				 VALUES ($1, $2, $3, $4, $5, $6)
+				 RETURNING id`
+
+	var id int
+	if err := db.conn.QueryRowContext(ctx, query, key.UserId, key.Name, key.Prefix, key.KeyHash,
+		strings.Join(key.Scopes, " "), key.ExpiresAt).Scan(&id); err != nil {
+		return 0, queryError("failed to execute query", err)
+	}
+
+	return id, nil
+}
+
+func (db *PostgresDBRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (entities.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT k.id, k.user_id, u.username, u.role, k.name, k.prefix, k.key_hash, k.scopes,
+				        k.created_at, k.expires_at, k.last_used_at
+				 FROM api_keys k
+				 JOIN users u ON u.id = k.user_id AND u.is_deleted = FALSE
+				 WHERE k.key_hash = $1 AND k.revoked_at IS NULL`
+
+	key, err := scanAPIKey(db.conn.QueryRowContext(ctx, query, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.APIKey{}, e.NotFound("API key not found")
+	} else if err != nil {
+		return entities.APIKey{}, queryError("failed to execute query", err)
+	}
+
+	return key, nil
+}
+
+// TouchAPIKey records that the key was used. The time is kept to the minute, so a busy key
+// doesn't write on every request.
+func (db *PostgresDBRepo) TouchAPIKey(ctx context.Context, keyId int) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE api_keys SET last_used_at = now()
+				 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - INTERVAL '1 minute')`
+
+	if _, err := db.conn.ExecContext(ctx, query, keyId); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+// ListAPIKeys returns the keys of the user that are neither revoked nor expired, newest first
+func (db *PostgresDBRepo) ListAPIKeys(ctx context.Context, username string) ([]entities.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT k.id, k.user_id, u.username, u.role, k.name, k.prefix, k.key_hash, k.scopes,
+				        k.created_at, k.expires_at, k.last_used_at
+				 FROM api_keys k
+				 JOIN users u ON u.id = k.user_id AND u.is_deleted = FALSE
+				 WHERE u.username = $1 AND k.revoked_at IS NULL AND k.expires_at > now()
+				 ORDER BY k.created_at DESC`
+
+	rows, err := db.conn.QueryContext(ctx, query, username)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	keys := make([]entities.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return keys, nil
+}
+
+func (db *PostgresDBRepo) RevokeAPIKey(ctx context.Context, username string, keyId int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE api_keys k SET revoked_at = now()
+				 FROM users u
+				 WHERE k.id = $2 AND k.user_id = u.id AND u.username = $1 AND k.revoked_at IS NULL`
+
+	return db.execAffectsRow(ctx, query, username, keyId)
+}
+
+// scanAPIKey reads a row of api_keys joined with the username and role of the owner
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (entities.APIKey, error) {
+	var (
+		key        entities.APIKey
+		scopes     string
+		lastUsedAt sql.NullTime
+	)
+
+	if err := row.Scan(
+		&key.Id,
+		&key.UserId,
+		&key.Username,
+		&key.Role,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&lastUsedAt,
+	); err != nil {
+		return entities.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
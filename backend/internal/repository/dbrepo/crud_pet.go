@@ -6,42 +6,51 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
+	"strings"
 )
 
 func (db *PostgresDBRepo) GetPetById(ctx context.Context, petId int) (entities.Pet, error) {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	// get pet
-	query := `SELECT id, category_id, name, status FROM pets WHERE id = $1 AND is_deleted = FALSE`
+	// get pet along with its category
+	query := `SELECT p.id, p.category_id, c.name, p.name, p.status, COALESCE(p.created_by, ''), COALESCE(p.updated_by, '')
+				 FROM pets p
+				 JOIN categories c ON c.id = p.category_id
+				 WHERE p.id = $1 AND p.is_deleted = FALSE`
 
 	var pet entities.Pet
 	err := db.conn.QueryRowContext(ctx, query, petId).Scan(
 		&pet.Id,
 		&pet.Category.Id,
+		&pet.Category.Name,
 		&pet.Name,
 		&pet.Status,
+		&pet.CreatedBy,
+		&pet.UpdatedBy,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return entities.Pet{}, errors.New("pet not found")
+		return entities.Pet{}, e.NotFound("pet not found")
 	} else if err != nil {
-		return entities.Pet{}, e.Wrap("failed to execute query", err)
+		return entities.Pet{}, queryError("failed to execute query", err)
 	}
 
 	return pet, nil
 }
 
-func (db *PostgresDBRepo) CreatePet(ctx context.Context, categoryId int, petName string, petStatus string) (int, error) {
+func (db *PostgresDBRepo) CreatePet(ctx context.Context, categoryId int, petName string, petStatus string, createdBy string) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `INSERT into pets (category_id, name, status, is_deleted) VALUES ($1, $2, $3, FALSE) returning id`
+	query := `INSERT into pets (category_id, name, status, created_by, is_deleted)
+				 VALUES ($1, $2, $3, NULLIF($4, ''), FALSE) returning id`
 
 	var petId int
 
-	if err := db.conn.QueryRowContext(ctx, query, categoryId, petName, petStatus).Scan(&petId); err != nil {
-		return 0, e.Wrap("failed to execute query", err)
+	if err := db.conn.QueryRowContext(ctx, query, categoryId, petName, petStatus, createdBy).Scan(&petId); err != nil {
+		return 0, queryError("failed to execute query", err)
 	}
 
 	return petId, nil
@@ -51,10 +60,10 @@ func (db *PostgresDBRepo) UpdatePet(ctx context.Context, pet entities.Pet) error
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `UPDATE pets SET category_id = $1, name = $2, status = $3 WHERE id = $4`
+	query := `UPDATE pets SET category_id = $1, name = $2, status = $3, updated_by = NULLIF($4, '') WHERE id = $5`
 
-	if _, err := db.conn.ExecContext(ctx, query, pet.Category.Id, pet.Name, pet.Status, pet.Id); err != nil {
-		return e.Wrap("failed to execute query", err)
+	if _, err := db.conn.ExecContext(ctx, query, pet.Category.Id, pet.Name, pet.Status, pet.UpdatedBy, pet.Id); err != nil {
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
@@ -67,7 +76,7 @@ func (db *PostgresDBRepo) DeletePet(ctx context.Context, petId int) error {
 	query := `UPDATE pets SET is_deleted = TRUE WHERE id = $1`
 
 	if _, err := db.conn.ExecContext(ctx, query, petId); err != nil {
-		return e.Wrap("failed to execute query", err)
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
@@ -77,43 +86,174 @@ func (db *PostgresDBRepo) GetPetsByStatus(ctx context.Context, petStatus string)
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `SELECT id, category_id, name, status FROM pets WHERE status = $1 AND is_deleted = FALSE`
+	query := `SELECT p.id, p.category_id, c.name, p.name, p.status, COALESCE(p.created_by, ''), COALESCE(p.updated_by, '')
+				 FROM pets p
+				 JOIN categories c ON c.id = p.category_id
+				 WHERE p.status = $1 AND p.is_deleted = FALSE
+				 ORDER BY p.id`
 
 	rows, err := db.conn.QueryContext(ctx, query, petStatus)
 	if errors.Is(err, sql.ErrNoRows) {
 		return []entities.Pet{}, nil
 	} else if err != nil {
-		return nil, e.Wrap("failed to execute query", err)
+		return nil, queryError("failed to execute query", err)
 	}
 	defer rows.Close()
 
 	pets := make([]entities.Pet, 0)
 	for rows.Next() {
 		var pet entities.Pet
-		if err = rows.Scan(&pet.Id, &pet.Category.Id, &pet.Name, &pet.Status); err != nil {
-			return nil, e.Wrap("failed to scan row", err)
+		if err = rows.Scan(&pet.Id, &pet.Category.Id, &pet.Category.Name, &pet.Name, &pet.Status, &pet.CreatedBy, &pet.UpdatedBy); err != nil {
+			return nil, queryError("failed to scan row", err)
 		}
 		pets = append(pets, pet)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
 	return pets, nil
 }
 
-func (db *PostgresDBRepo) GetPetCategoryById(ctx context.Context, categoryId int) (entities.Category, error) {
-	query := `SELECT id, name FROM categories WHERE id = $1`
+func (db *PostgresDBRepo) GetPetsByTags(ctx context.Context, tagNames []string, matchAll bool) ([]entities.Pet, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
 
-	var category entities.Category
+	// with matchAll every requested tag must be present, otherwise a single one is enough
+	required := 1
+	if matchAll {
+		required = len(tagNames)
+	}
 
-	err := db.conn.QueryRowContext(ctx, query, categoryId).Scan(&category.Id, &category.Name)
+	query := `SELECT p.id, p.category_id, c.name, p.name, p.status, COALESCE(p.created_by, ''), COALESCE(p.updated_by, '')
+				 FROM pets p
+				 JOIN categories c ON c.id = p.category_id
+				 WHERE p.is_deleted = FALSE AND p.id IN (
+					 SELECT pt.pet_id
+					 FROM pet_tags pt
+					 JOIN tags t ON t.id = pt.tag_id
+					 WHERE t.name = ANY($1)
+					 GROUP BY pt.pet_id
+					 HAVING COUNT(DISTINCT t.name) >= $2)
+				 ORDER BY p.id`
+
+	rows, err := db.conn.QueryContext(ctx, query, tagNames, required)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return entities.Category{}, errors.New("category not found")
-	} else if err != nil {
-		return entities.Category{}, e.Wrap("failed to execute query", err)
+	pets := make([]entities.Pet, 0)
+	for rows.Next() {
+		var pet entities.Pet
+		if err = rows.Scan(&pet.Id, &pet.Category.Id, &pet.Category.Name, &pet.Name, &pet.Status, &pet.CreatedBy, &pet.UpdatedBy); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		pets = append(pets, pet)
 	}
 
-	return category, nil
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
 
+	return pets, nil
+}
+
+// petSortColumns maps the sort keys accepted by ListPets onto table columns
+var petSortColumns = map[string]string{
+	"id":        "p.id",
+	"name":      "p.name",
+	"createdAt": "p.created_at",
+}
+
+func (db *PostgresDBRepo) ListPets(ctx context.Context, filter entities.PetFilter) ([]entities.Pet, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	sortColumn, ok := petSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = petSortColumns["id"]
+	}
+
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	var (
+		conditions = []string{"p.is_deleted = FALSE"}
+		args       []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "p.status = ANY("+arg(filter.Statuses)+")")
+	}
+
+	if filter.Category != "" {
+		conditions = append(conditions, "c.name = "+arg(filter.Category))
+	}
+
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, "p.name ILIKE "+arg(escapeLike(filter.NamePrefix)+"%"))
+	}
+
+	// pet must carry every requested tag
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, `p.id IN (
+				SELECT pt.pet_id FROM pet_tags pt JOIN tags t ON t.id = pt.tag_id
+				WHERE t.name = ANY(`+arg(filter.Tags)+`)
+				GROUP BY pt.pet_id
+				HAVING COUNT(DISTINCT t.name) = `+arg(len(filter.Tags))+`)`)
+	}
+
+	query := `SELECT p.id, p.category_id, c.name, p.name, p.status, COALESCE(p.created_by, ''), COALESCE(p.updated_by, ''), COUNT(*) OVER()
+				 FROM pets p
+				 JOIN categories c ON c.id = p.category_id
+				 WHERE ` + strings.Join(conditions, " AND ") + `
+				 ORDER BY ` + sortColumn + " " + direction + `, p.id ` + direction + `
+				 LIMIT ` + arg(filter.Limit) + ` OFFSET ` + arg(filter.Offset)
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var total int
+	pets := make([]entities.Pet, 0)
+	for rows.Next() {
+		var pet entities.Pet
+		if err = rows.Scan(&pet.Id, &pet.Category.Id, &pet.Category.Name, &pet.Name, &pet.Status, &pet.CreatedBy, &pet.UpdatedBy, &total); err != nil {
+			return nil, 0, queryError("failed to scan row", err)
+		}
+		pets = append(pets, pet)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, queryError("failed to read rows", err)
+	}
+
+	// an offset past the end returns no rows and therefore no window count
+	if len(pets) == 0 && filter.Offset > 0 {
+		countQuery := `SELECT COUNT(*) FROM pets p JOIN categories c ON c.id = p.category_id WHERE ` +
+			strings.Join(conditions, " AND ")
+		if err = db.conn.QueryRowContext(ctx, countQuery, args[:len(args)-2]...).Scan(&total); err != nil {
+			return nil, 0, queryError("failed to execute query", err)
+		}
+	}
+
+	return pets, total, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 func (db *PostgresDBRepo) GetPetCategoryByName(ctx context.Context, categoryName string) (entities.Category, error) {
@@ -127,9 +267,9 @@ func (db *PostgresDBRepo) GetPetCategoryByName(ctx context.Context, categoryName
 	err := db.conn.QueryRowContext(ctx, query, categoryName).Scan(&category.Id, &category.Name)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return entities.Category{}, errors.New("category not found")
+		return entities.Category{}, e.NotFound("category not found")
 	} else if err != nil {
-		return entities.Category{}, e.Wrap("failed to execute query", err)
+		return entities.Category{}, queryError("failed to execute query", err)
 	}
 
 	return category, nil
@@ -143,7 +283,7 @@ func (db *PostgresDBRepo) CreatePetCategory(ctx context.Context, categoryName st
 
 	var categoryId int
 	if err := db.conn.QueryRowContext(ctx, query, categoryName).Scan(&categoryId); err != nil {
-		return entities.Category{}, e.Wrap("failed to execute query", err)
+		return entities.Category{}, queryError("failed to execute query", err)
 	}
 
 	category := entities.Category{Name: categoryName, Id: categoryId}
@@ -151,69 +291,147 @@ func (db *PostgresDBRepo) CreatePetCategory(ctx context.Context, categoryName st
 	return category, nil
 }
 
-func (db *PostgresDBRepo) GetPhotoUrlsByPetId(ctx context.Context, petId int) ([]entities.PhotoUrl, error) {
-	query := `SELECT id, pet_id, url FROM photo_urls WHERE pet_id = $1`
+// GetPhotoUrlsByPetIds returns photo urls with their thumbnails grouped by pet id
+func (db *PostgresDBRepo) GetPhotoUrlsByPetIds(ctx context.Context, petIds []int) (map[int][]entities.PhotoUrl, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
 
-	rows, err := db.conn.QueryContext(ctx, query, petId)
-	if errors.Is(err, sql.ErrNoRows) {
-		return []entities.PhotoUrl{}, nil
-	} else if err != nil {
-		return nil, e.Wrap("failed to execute query", err)
+	query := `SELECT p.id, p.pet_id, p.url, t.id, t.name, t.width, t.height, t.url
+				 FROM photo_urls p
+				 LEFT JOIN photo_thumbnails t ON t.photo_url_id = p.id
+				 WHERE p.pet_id = ANY($1)
+				 ORDER BY p.pet_id, p.id, t.width`
+
+	rows, err := db.conn.QueryContext(ctx, query, petIds)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
 	}
 	defer rows.Close()
 
-	photoUrls := make([]entities.PhotoUrl, 0)
+	photoUrls := make(map[int][]entities.PhotoUrl, len(petIds))
 	for rows.Next() {
-		var photoUrl entities.PhotoUrl
-		if err = rows.Scan(&photoUrl.Id, &photoUrl.PetId, &photoUrl.Url); err != nil {
-			return nil, e.Wrap("failed to scan row", err)
+		var (
+			photoUrl entities.PhotoUrl
+			tId      sql.NullInt64
+			tName    sql.NullString
+			tWidth   sql.NullInt64
+			tHeight  sql.NullInt64
+			tUrl     sql.NullString
+		)
+		if err = rows.Scan(&photoUrl.Id, &photoUrl.PetId, &photoUrl.Url, &tId, &tName, &tWidth, &tHeight, &tUrl); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+
+		petPhotoUrls := photoUrls[photoUrl.PetId]
+		// rows of the same photo url follow each other, one per thumbnail
+		if n := len(petPhotoUrls); n == 0 || petPhotoUrls[n-1].Id != photoUrl.Id {
+			petPhotoUrls = append(petPhotoUrls, photoUrl)
 		}
-		photoUrls = append(photoUrls, photoUrl)
+
+		if tId.Valid {
+			last := &petPhotoUrls[len(petPhotoUrls)-1]
+			last.Thumbnails = append(last.Thumbnails, entities.Thumbnail{
+				Id:         int(tId.Int64),
+				PhotoUrlId: photoUrl.Id,
+				Name:       tName.String,
+				Width:      int(tWidth.Int64),
+				Height:     int(tHeight.Int64),
+				Url:        tUrl.String,
+			})
+		}
+
+		photoUrls[photoUrl.PetId] = petPhotoUrls
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
 	}
 
 	return photoUrls, nil
 }
 
-func (db *PostgresDBRepo) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) error {
+func (db *PostgresDBRepo) CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `INSERT INTO photo_urls(pet_id, url) VALUES ($1, $2)`
+	query := `INSERT INTO photo_urls(pet_id, url) VALUES ($1, $2) returning id`
 
-	if _, err := db.conn.ExecContext(ctx, query, petId, photoUrl); err != nil {
-		return e.Wrap("failed to execute query", err)
+	var photoUrlId int
+	if err := db.conn.QueryRowContext(ctx, query, petId, photoUrl).Scan(&photoUrlId); err != nil {
+		return 0, queryError("failed to execute query", err)
 	}
 
-	return nil
+	return photoUrlId, nil
 }
 
 func (db *PostgresDBRepo) DeletePhotoUrlsByPetId(ctx context.Context, petId int) error {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `DELETE FROM photo_urls WHERE pet_id = $1`
+	query := `DELETE FROM photo_thumbnails WHERE photo_url_id IN (SELECT id FROM photo_urls WHERE pet_id = $1)`
+
+	if _, err := db.conn.ExecContext(ctx, query, petId); err != nil {
+		return queryError("failed to execute query", err)
+	}
+
+	query = `DELETE FROM photo_urls WHERE pet_id = $1`
 
 	if _, err := db.conn.ExecContext(ctx, query, petId); err != nil {
-		return e.Wrap("failed to execute query", err)
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
 }
 
-func (db *PostgresDBRepo) GetTagById(ctx context.Context, tagId int) (entities.Tag, error) {
+func (db *PostgresDBRepo) CreatePhotoThumbnail(ctx context.Context, thumbnail entities.Thumbnail) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `SELECT id, name FROM tags WHERE id = $1`
+	query := `INSERT INTO photo_thumbnails(photo_url_id, name, width, height, url) VALUES ($1, $2, $3, $4, $5) returning id`
 
-	var tag entities.Tag
-	if err := db.conn.QueryRowContext(ctx, query, tagId).Scan(&tag.Id, &tag.Name); errors.Is(err, sql.ErrNoRows) {
-		return entities.Tag{}, errors.New("tag not found")
-	} else if err != nil {
-		return entities.Tag{}, e.Wrap("failed to execute query", err)
+	var thumbnailId int
+	if err := db.conn.QueryRowContext(ctx, query, thumbnail.PhotoUrlId, thumbnail.Name,
+		thumbnail.Width, thumbnail.Height, thumbnail.Url).Scan(&thumbnailId); err != nil {
+		return 0, queryError("failed to execute query", err)
 	}
 
-	return tag, nil
+	return thumbnailId, nil
+}
+
+// GetTagsByPetIds returns tags grouped by pet id
+func (db *PostgresDBRepo) GetTagsByPetIds(ctx context.Context, petIds []int) (map[int][]entities.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT pt.pet_id, t.id, t.name
+				 FROM pet_tags pt
+				 JOIN tags t ON t.id = pt.tag_id
+				 WHERE pt.pet_id = ANY($1)
+				 ORDER BY pt.pet_id, pt.id`
+
+	rows, err := db.conn.QueryContext(ctx, query, petIds)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]entities.Tag, len(petIds))
+	for rows.Next() {
+		var (
+			petId int
+			tag   entities.Tag
+		)
+		if err = rows.Scan(&petId, &tag.Id, &tag.Name); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		tags[petId] = append(tags[petId], tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return tags, nil
 }
 
 func (db *PostgresDBRepo) GetTagByName(ctx context.Context, tagName string) (entities.Tag, error) {
@@ -224,9 +442,9 @@ func (db *PostgresDBRepo) GetTagByName(ctx context.Context, tagName string) (ent
 
 	var tag entities.Tag
 	if err := db.conn.QueryRowContext(ctx, query, tagName).Scan(&tag.Id, &tag.Name); errors.Is(err, sql.ErrNoRows) {
-		return entities.Tag{}, errors.New("tag not found")
+		return entities.Tag{}, e.NotFound("tag not found")
 	} else if err != nil {
-		return entities.Tag{}, e.Wrap("failed to execute query", err)
+		return entities.Tag{}, queryError("failed to execute query", err)
 	}
 
 	return tag, nil
@@ -240,7 +458,7 @@ func (db *PostgresDBRepo) CreateTag(ctx context.Context, tagName string) (entiti
 
 	var tagId int
 	if err := db.conn.QueryRowContext(ctx, query, tagName).Scan(&tagId); err != nil {
-		return entities.Tag{}, e.Wrap("failed to execute query", err)
+		return entities.Tag{}, queryError("failed to execute query", err)
 	}
 
 	tag := entities.Tag{Id: tagId, Name: tagName}
@@ -258,40 +476,14 @@ func (db *PostgresDBRepo) GetPetTagPair(ctx context.Context, petId int, tagId in
 	err := db.conn.QueryRowContext(ctx, query, petId, tagId).Scan(&petTag.Id, &petTag.PetId, &petTag.TagId)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return entities.PetTag{}, errors.New("pet_tag not found")
+		return entities.PetTag{}, e.NotFound("pet_tag not found")
 	} else if err != nil {
-		return entities.PetTag{}, e.Wrap("failed to execute query", err)
+		return entities.PetTag{}, queryError("failed to execute query", err)
 	}
 
 	return petTag, nil
 }
 
-func (db *PostgresDBRepo) GetPetTagPairsByPetId(ctx context.Context, petId int) ([]entities.PetTag, error) {
-	ctx, cancel := context.WithTimeout(ctx, db.timeout)
-	defer cancel()
-
-	query := `SELECT id, pet_id, tag_id FROM pet_tags WHERE pet_id = $1`
-
-	rows, err := db.conn.QueryContext(ctx, query, petId)
-	if errors.Is(err, sql.ErrNoRows) {
-		return []entities.PetTag{}, nil
-	} else if err != nil {
-		return nil, e.Wrap("failed to execute query", err)
-	}
-	defer rows.Close()
-
-	petTagPairs := make([]entities.PetTag, 0)
-	for rows.Next() {
-		var petTag entities.PetTag
-		if err = rows.Scan(&petTag.Id, &petTag.PetId, &petTag.TagId); err != nil {
-			return nil, e.Wrap("failed to scan row", err)
-		}
-		petTagPairs = append(petTagPairs, petTag)
-	}
-
-	return petTagPairs, nil
-}
-
 func (db *PostgresDBRepo) DeletePetTagsByPetId(ctx context.Context, petId int) error {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
@@ -299,7 +491,7 @@ func (db *PostgresDBRepo) DeletePetTagsByPetId(ctx context.Context, petId int) e
 	query := `DELETE FROM pet_tags WHERE pet_id = $1`
 
 	if _, err := db.conn.ExecContext(ctx, query, petId); err != nil {
-		return e.Wrap("failed to execute query", err)
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
@@ -313,7 +505,7 @@ func (db *PostgresDBRepo) CreatePetTagPair(ctx context.Context, petId int, tagId
 
 	var petTagId int
 	if err := db.conn.QueryRowContext(ctx, query, petId, tagId).Scan(&petTagId); err != nil {
-		return entities.PetTag{}, e.Wrap("failed to execute query", err)
+		return entities.PetTag{}, queryError("failed to execute query", err)
 	}
 
 	petTag := entities.PetTag{Id: petTagId, PetId: petId, TagId: tagId}
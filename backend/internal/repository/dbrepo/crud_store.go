@@ -12,17 +12,17 @@ func (db *PostgresDBRepo) GetOrderById(ctx context.Context, orderId int) (entiti
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `SELECT id, pet_id, quantity, ship_date, status, is_complete 
+	query := `SELECT id, COALESCE(user_id, 0), pet_id, quantity, ship_date, status, is_complete
 				 FROM store 
 				 WHERE id = $1 AND is_deleted = FALSE`
 
 	var order entities.Order
-	err := db.conn.QueryRowContext(ctx, query, orderId).Scan(&order.Id, &order.PetId, &order.Quantity,
+	err := db.conn.QueryRowContext(ctx, query, orderId).Scan(&order.Id, &order.UserId, &order.PetId, &order.Quantity,
 		&order.ShipDate, &order.Status, &order.IsComplete)
 	if errors.Is(err, sql.ErrNoRows) {
-		return entities.Order{}, errors.New("order not found")
+		return entities.Order{}, e.NotFound("order not found")
 	} else if err != nil {
-		return entities.Order{}, err
+		return entities.Order{}, queryError("failed to execute query", err)
 	}
 
 	return order, nil
@@ -32,18 +32,50 @@ func (db *PostgresDBRepo) CreateOrder(ctx context.Context, order entities.Order)
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `INSERT INTO store (pet_id, quantity, ship_date, status, is_complete, is_deleted)
-				 VALUES ($1, $2, $3, $4, $5, FALSE) RETURNING id;`
+	query := `INSERT INTO store (user_id, pet_id, quantity, ship_date, status, is_complete, is_deleted)
+				 VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, FALSE) RETURNING id;`
 
 	var orderId int
-	if err := db.conn.QueryRowContext(ctx, query, order.PetId, order.Quantity,
+	if err := db.conn.QueryRowContext(ctx, query, order.UserId, order.PetId, order.Quantity,
 		order.ShipDate, order.Status, order.IsComplete).Scan(&orderId); err != nil {
-		return 0, e.Wrap("failed to execute query", err)
+		return 0, queryError("failed to execute query", err)
 	}
 
 	return orderId, nil
 }
 
+func (db *PostgresDBRepo) GetInventory(ctx context.Context) ([]entities.InventoryItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT COALESCE(c.name, ''), p.status, COUNT(*)
+				 FROM pets p
+				 LEFT JOIN categories c ON c.id = p.category_id
+				 WHERE p.is_deleted = FALSE
+				 GROUP BY c.name, p.status`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	items := make([]entities.InventoryItem, 0)
+	for rows.Next() {
+		var item entities.InventoryItem
+		if err = rows.Scan(&item.Category, &item.Status, &item.Count); err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return items, nil
+}
+
 func (db *PostgresDBRepo) DeleteOrder(ctx context.Context, orderId int) error {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
@@ -51,7 +83,7 @@ func (db *PostgresDBRepo) DeleteOrder(ctx context.Context, orderId int) error {
 	query := `UPDATE store SET is_deleted = TRUE WHERE id = $1`
 
 	if _, err := db.conn.ExecContext(ctx, query, orderId); err != nil {
-		return e.Wrap("failed to execute query", err)
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package dbrepo
 
 import (
 	"backend/internal/lib/e"
+	ae "backend/internal/modules/auth/entities"
 	"backend/internal/modules/user/entities"
 	"context"
 	"database/sql"
@@ -12,12 +13,71 @@ func (db *PostgresDBRepo) GetUserByUsername(ctx context.Context, username string
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `SELECT id, username, first_name, last_name, email, password, phone, user_status 
+	query := `SELECT id, username, first_name, last_name, email, password, phone, user_status, role,
+					 email_verified_at IS NOT NULL
 			    FROM users 
 				 WHERE username = $1 AND is_deleted = FALSE`
 
+	user, err := scanUser(db.conn.QueryRowContext(ctx, query, username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.User{}, e.NotFound("user not found")
+	} else if err != nil {
+		return entities.User{}, queryError("failed to execute query", err)
+	}
+
+	return user, nil
+}
+
+// GetUsersByEmail returns the users with the given email, which isn't unique, ignoring case
+func (db *PostgresDBRepo) GetUsersByEmail(ctx context.Context, email string) ([]entities.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT id, username, first_name, last_name, email, password, phone, user_status, role,
+					 email_verified_at IS NOT NULL
+			    FROM users 
+				 WHERE lower(email) = lower($1) AND is_deleted = FALSE
+				 ORDER BY id`
+
+	rows, err := db.conn.QueryContext(ctx, query, email)
+	if err != nil {
+		return nil, queryError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var users []entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, queryError("failed to scan row", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError("failed to read rows", err)
+	}
+
+	return users, nil
+}
+
+func (db *PostgresDBRepo) CountUsersByRole(ctx context.Context, role ae.Role) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT count(*) FROM users WHERE role = $1 AND is_deleted = FALSE`
+
+	var count int
+	if err := db.conn.QueryRowContext(ctx, query, role).Scan(&count); err != nil {
+		return 0, queryError("failed to execute query", err)
+	}
+
+	return count, nil
+}
+
+func scanUser(row interface{ Scan(dest ...any) error }) (entities.User, error) {
 	var user entities.User
-	err := db.conn.QueryRowContext(ctx, query, username).Scan(
+	err := row.Scan(
 		&user.Id,
 		&user.Username,
 		&user.FirstName,
@@ -26,43 +86,60 @@ func (db *PostgresDBRepo) GetUserByUsername(ctx context.Context, username string
 		&user.Password,
 		&user.Phone,
 		&user.UserStatus,
+		&user.Role,
+		&user.EmailVerified,
 	)
+	return user, err
+}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return entities.User{}, errors.New("user not found")
-	} else if err != nil {
-		return entities.User{}, err
-	}
+func (db *PostgresDBRepo) VerifyUserEmail(ctx context.Context, userId int, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
 
-	return user, nil
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, now())
+				 WHERE id = $1 AND email = $2 AND is_deleted = FALSE`
+
+	return db.execAffectsRow(ctx, query, userId, email)
 }
 
 func (db *PostgresDBRepo) UpdateUser(ctx context.Context, user entities.User) error {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `UPDATE users SET first_name = $1, last_name = $2, email = $3, password = $4, phone = $5, user_status = $6 WHERE username = $7`
+	// a new email has to be verified again
+	query := `UPDATE users SET first_name = $1, last_name = $2, email = $3, password = $4, phone = $5, user_status = $6, role = $7,
+					 email_verified_at = CASE WHEN email = $3 THEN email_verified_at END
+				 WHERE username = $8`
 
 	if _, err := db.conn.ExecContext(ctx, query, user.FirstName, user.LastName,
-		user.Email, user.Password, user.Phone, user.UserStatus, user.Username); err != nil {
-		return e.Wrap("failed to execute query", err)
+		user.Email, user.Password, user.Phone, user.UserStatus, user.Role, user.Username); err != nil {
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
 }
 
+func (db *PostgresDBRepo) UpdateUserPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `UPDATE users SET password = $3 WHERE id = $1 AND password = $2`
+
+	return db.execAffectsRow(ctx, query, userId, oldHash, newHash)
+}
+
 func (db *PostgresDBRepo) CreateUser(ctx context.Context, user entities.User) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, db.timeout)
 	defer cancel()
 
-	query := `INSERT INTO users (username, first_name, last_name, email, password, phone, user_status, is_deleted) 
-			  	 VALUES ($1, $2, $3, $4, $5, $6, $7, FALSE)
+	query := `INSERT INTO users (username, first_name, last_name, email, password, phone, user_status, role, is_deleted) 
+			  	 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, FALSE)
 				 RETURNING id`
 
 	var id int
 	if err := db.conn.QueryRowContext(ctx, query, user.Username, user.FirstName, user.LastName,
-		user.Email, user.Password, user.Phone, user.UserStatus).Scan(&id); err != nil {
-		return 0, e.Wrap("failed to execute query", err)
+		user.Email, user.Password, user.Phone, user.UserStatus, user.Role).Scan(&id); err != nil {
+		return 0, queryError("failed to execute query", err)
 	}
 
 	return id, nil
@@ -75,7 +152,40 @@ func (db *PostgresDBRepo) DeleteUser(ctx context.Context, username string) error
 	query := `UPDATE users SET is_deleted = TRUE WHERE username = $1`
 
 	if _, err := db.conn.ExecContext(ctx, query, username); err != nil {
-		return e.Wrap("failed to execute query", err)
+		return queryError("failed to execute query", err)
+	}
+
+	return nil
+}
+
+func (db *PostgresDBRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (entities.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `SELECT u.id, u.username, u.first_name, u.last_name, u.email, u.password, u.phone, u.user_status, u.role,
+					 u.email_verified_at IS NOT NULL
+			    FROM user_identities i
+				 JOIN users u ON u.id = i.user_id
+				 WHERE i.issuer = $1 AND i.subject = $2 AND u.is_deleted = FALSE`
+
+	user, err := scanUser(db.conn.QueryRowContext(ctx, query, issuer, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.User{}, e.NotFound("user not found")
+	} else if err != nil {
+		return entities.User{}, queryError("failed to execute query", err)
+	}
+
+	return user, nil
+}
+
+func (db *PostgresDBRepo) LinkIdentity(ctx context.Context, userId int, issuer, subject string) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	query := `INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)`
+
+	if _, err := db.conn.ExecContext(ctx, query, userId, issuer, subject); err != nil {
+		return queryError("failed to execute query", err)
 	}
 
 	return nil
@@ -1,14 +1,32 @@
 package dbrepo
 
 import (
+	"backend/internal/lib/e"
+	"backend/internal/repository"
+	"context"
 	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log"
 	"time"
 )
 
 const dbTimeout = time.Second * 3
 
+// uniqueViolation is the postgres error code reported when a unique constraint is violated
+const uniqueViolation = "23505"
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so the same queries run inside and outside a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type PostgresDBRepo struct {
-	conn    *sql.DB
+	db      *sql.DB
+	conn    querier
+	inTx    bool
 	timeout time.Duration
 }
 
@@ -21,6 +39,7 @@ func WithDBTimeout(timeout time.Duration) func(*PostgresDBRepo) {
 
 func NewPostgresDBRepo(conn *sql.DB, options ...func(repo *PostgresDBRepo)) *PostgresDBRepo {
 	db := &PostgresDBRepo{
+		db:      conn,
 		conn:    conn,
 		timeout: time.Second * 3, // default timout
 	}
@@ -33,5 +52,53 @@ func NewPostgresDBRepo(conn *sql.DB, options ...func(repo *PostgresDBRepo)) *Pos
 }
 
 func (db *PostgresDBRepo) Connection() *sql.DB {
-	return db.conn
+	return db.db
+}
+
+// WithTx runs fn against a repository bound to a single transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise. Calling WithTx on a repository
+// that is already inside a transaction reuses that transaction.
+func (db *PostgresDBRepo) WithTx(ctx context.Context, fn func(repo repository.Repository) error) (err error) {
+	if db.inTx {
+		return fn(db)
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return e.Internal("failed to begin transaction", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txRepo := &PostgresDBRepo{db: db.db, conn: tx, inTx: true, timeout: db.timeout}
+	if err = fn(txRepo); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, e.Internal("failed to roll back transaction", rbErr))
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return e.Internal("failed to commit transaction", err)
+	}
+
+	return nil
+}
+
+// queryError turns a database error into a domain error: unique constraint violations
+// become conflicts, anything else is an internal error. Conflicts are shown to the client,
+// so the violation, naming tables and constraints, is only logged.
+func queryError(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		log.Println("conflict:", err)
+		return e.Conflict("record already exists")
+	}
+
+	return e.Internal(msg, err)
 }
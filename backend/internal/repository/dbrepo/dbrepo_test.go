@@ -0,0 +1,27 @@
+package dbrepo
+
+import (
+	"backend/internal/lib/e"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"testing"
+)
+
+func TestQueryError(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: uniqueViolation, Message: `duplicate key value violates unique constraint "users_username_key"`}
+
+	err := queryError("failed to execute query", fmt.Errorf("insert: %w", pgErr))
+	if !errors.Is(err, e.ErrConflict) {
+		t.Errorf("queryError() = %v, want a conflict", err)
+	}
+	// conflicts are shown to the client, the constraint must not be
+	if strings.Contains(err.Error(), "users_username_key") || strings.Contains(err.Error(), "SQLSTATE") {
+		t.Errorf("queryError() = %q, want the violation left out", err)
+	}
+
+	if err = queryError("failed to execute query", errors.New("connection reset")); !errors.Is(err, e.ErrInternal) {
+		t.Errorf("queryError() = %v, want an internal error", err)
+	}
+}
@@ -1,38 +1,45 @@
 package repository
 
 import (
+	ae "backend/internal/modules/auth/entities"
 	pe "backend/internal/modules/pet/entities"
 	se "backend/internal/modules/store/entities"
 	ue "backend/internal/modules/user/entities"
 	"context"
 	"database/sql"
+	"time"
 )
 
 //go:generate mockgen -source=./repository.go -destination=../mocks/mock_repository/mock_repository.go
 
 type Repository interface {
 	Connection() *sql.DB
+	// WithTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+	// The repository passed to fn must be used for every query that belongs to the transaction.
+	WithTx(ctx context.Context, fn func(repo Repository) error) error
 	UserRepository
 	PetRepository
 	StoreRepository
+	AuthRepository
 }
 type PetRepository interface {
 	GetPetById(ctx context.Context, petId int) (pe.Pet, error)
-	CreatePet(ctx context.Context, categoryId int, petName string, petStatus string) (int, error)
+	CreatePet(ctx context.Context, categoryId int, petName string, petStatus string, createdBy string) (int, error)
 	UpdatePet(ctx context.Context, pet pe.Pet) error
 	DeletePet(ctx context.Context, petId int) error
 	GetPetsByStatus(ctx context.Context, petStatus string) ([]pe.Pet, error)
-	GetPetCategoryById(ctx context.Context, categoryId int) (pe.Category, error)
+	ListPets(ctx context.Context, filter pe.PetFilter) ([]pe.Pet, int, error)
+	GetPetsByTags(ctx context.Context, tagNames []string, matchAll bool) ([]pe.Pet, error)
 	GetPetCategoryByName(ctx context.Context, categoryName string) (pe.Category, error)
 	CreatePetCategory(ctx context.Context, categoryName string) (pe.Category, error)
-	GetPhotoUrlsByPetId(ctx context.Context, petId int) ([]pe.PhotoUrl, error)
+	GetPhotoUrlsByPetIds(ctx context.Context, petIds []int) (map[int][]pe.PhotoUrl, error)
 	DeletePhotoUrlsByPetId(ctx context.Context, petId int) error
-	CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) error
-	GetTagById(ctx context.Context, tagId int) (pe.Tag, error)
+	CreatePetPhotoUrl(ctx context.Context, petId int, photoUrl string) (int, error)
+	CreatePhotoThumbnail(ctx context.Context, thumbnail pe.Thumbnail) (int, error)
+	GetTagsByPetIds(ctx context.Context, petIds []int) (map[int][]pe.Tag, error)
 	GetTagByName(ctx context.Context, tagName string) (pe.Tag, error)
 	CreateTag(ctx context.Context, tagName string) (pe.Tag, error)
 	GetPetTagPair(ctx context.Context, petId int, tagId int) (pe.PetTag, error)
-	GetPetTagPairsByPetId(ctx context.Context, petId int) ([]pe.PetTag, error)
 	DeletePetTagsByPetId(ctx context.Context, petId int) error
 	CreatePetTagPair(ctx context.Context, petId int, tagId int) (pe.PetTag, error)
 }
@@ -41,11 +48,62 @@ type StoreRepository interface {
 	GetOrderById(ctx context.Context, orderId int) (se.Order, error)
 	CreateOrder(ctx context.Context, order se.Order) (int, error)
 	DeleteOrder(ctx context.Context, orderId int) error
+	GetInventory(ctx context.Context) ([]se.InventoryItem, error)
 }
 
 type UserRepository interface {
 	GetUserByUsername(ctx context.Context, username string) (ue.User, error)
+	GetUsersByEmail(ctx context.Context, email string) ([]ue.User, error)
+	CountUsersByRole(ctx context.Context, role ae.Role) (int, error)
+	// VerifyUserEmail marks the email of the user verified, it returns false if the user
+	// changed the email in the meantime
+	VerifyUserEmail(ctx context.Context, userId int, email string) (bool, error)
 	UpdateUser(ctx context.Context, user ue.User) error
+	// UpdateUserPassword replaces the password hash of the user if it still is oldHash,
+	// it returns false if the password changed in the meantime
+	UpdateUserPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error)
 	CreateUser(ctx context.Context, user ue.User) (int, error)
 	DeleteUser(ctx context.Context, username string) error
+	// GetUserByIdentity returns the user the account at an external provider is linked to
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (ue.User, error)
+	LinkIdentity(ctx context.Context, userId int, issuer, subject string) error
+}
+
+type AuthRepository interface {
+	CreateRefreshToken(ctx context.Context, token ae.RefreshToken) (int, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (ae.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, tokenId int) (bool, error)
+	// RevokeRefreshTokenFamily and RevokeUserRefreshTokens return the access tokens issued
+	// along with the refresh tokens they revoked, those have to be revoked as well
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) ([]ae.RevokedToken, error)
+	RevokeUserRefreshTokens(ctx context.Context, username string) ([]ae.RevokedToken, error)
+	ListSessions(ctx context.Context, username string, currentTokenId string) ([]ae.Session, error)
+	RevokeAccessTokens(ctx context.Context, tokens []ae.RevokedToken) error
+	GetRevokedAccessTokens(ctx context.Context, since time.Time) ([]ae.RevokedToken, error)
+	GetClientById(ctx context.Context, clientId string) (ae.Client, error)
+	GetLoginAttempts(ctx context.Context, keys []string) ([]ae.LoginAttempts, error)
+	RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (ae.LoginAttempts, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	GetTwoFactor(ctx context.Context, userId int) (ae.TwoFactor, error)
+	// SaveTwoFactor starts a new enrollment of the user, replacing the previous secret and recovery codes
+	SaveTwoFactor(ctx context.Context, twoFactor ae.TwoFactor, recoveryCodeHashes []string) error
+	EnableTwoFactor(ctx context.Context, userId int, step int64) error
+	DeleteTwoFactor(ctx context.Context, userId int) error
+	// UseTwoFactorStep and UseRecoveryCode return false if the code was already used
+	UseTwoFactorStep(ctx context.Context, userId int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	CreateUserToken(ctx context.Context, token ae.UserToken) error
+	// UseUserToken returns false if the token was already used, revoked or is expired
+	UseUserToken(ctx context.Context, tokenId string, purpose ae.TokenPurpose) (bool, error)
+	// RevokeUserTokens makes the unused tokens of the user for purpose unusable
+	RevokeUserTokens(ctx context.Context, userId int, purpose ae.TokenPurpose) error
+	CreateAPIKey(ctx context.Context, key ae.APIKey) (int, error)
+	// GetAPIKeyByHash returns the key along with the name and role of its owner, revoked keys
+	// and keys of deleted users are not found
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (ae.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyId int) error
+	ListAPIKeys(ctx context.Context, username string) ([]ae.APIKey, error)
+	// RevokeAPIKey returns false if the user has no such key or it was already revoked
+	RevokeAPIKey(ctx context.Context, username string, keyId int) (bool, error)
 }
@@ -0,0 +1,144 @@
+package filestore
+
+import (
+	"backend/internal/lib/e"
+	"backend/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const infoSuffix = ".info.json"
+
+// FileStorage keeps objects on the local filesystem under the root directory,
+// each one accompanied by a json sidecar file holding its ObjectInfo.
+type FileStorage struct {
+	root string
+}
+
+func NewFileStorage(root string) (*FileStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, e.Wrap("failed to create storage directory", err)
+	}
+
+	return &FileStorage{root: root}, nil
+}
+
+func (s *FileStorage) Put(ctx context.Context, key string, r io.Reader, info storage.ObjectInfo) (storage.ObjectInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to create object directory", err)
+	}
+
+	// write into a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to create temporary file", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to write object", err)
+	}
+
+	info.Key = key
+	info.Size = size
+
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to encode object info", err)
+	}
+
+	if err = os.WriteFile(filePath+infoSuffix, infoJSON, 0o644); err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to write object info", err)
+	}
+
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return storage.ObjectInfo{}, e.Wrap("failed to save object", err)
+	}
+
+	return info, nil
+}
+
+func (s *FileStorage) Get(_ context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	infoJSON, err := os.ReadFile(filePath + infoSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, storage.ObjectInfo{}, storage.ErrNotFound
+	} else if err != nil {
+		return nil, storage.ObjectInfo{}, e.Wrap("failed to read object info", err)
+	}
+
+	var info storage.ObjectInfo
+	if err = json.Unmarshal(infoJSON, &info); err != nil {
+		return nil, storage.ObjectInfo{}, e.Wrap("failed to decode object info", err)
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, storage.ObjectInfo{}, storage.ErrNotFound
+	} else if err != nil {
+		return nil, storage.ObjectInfo{}, e.Wrap("failed to open object", err)
+	}
+
+	return file, info, nil
+}
+
+func (s *FileStorage) Delete(_ context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(filePath); errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrNotFound
+	} else if err != nil {
+		return e.Wrap("failed to delete object", err)
+	}
+
+	if err = os.Remove(filePath + infoSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return e.Wrap("failed to delete object info", err)
+	}
+
+	return nil
+}
+
+// path maps an object key onto the filesystem, rejecting keys that would escape the root
+func (s *FileStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key ||
+		strings.HasPrefix(key, "../") || key == ".." || strings.HasSuffix(key, infoSuffix) {
+		return "", errors.New("invalid object key")
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
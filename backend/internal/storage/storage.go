@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+//go:generate mockgen -source=./storage.go -destination=../mocks/mock_storage/mock_storage.go
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage is a blob store for binary objects addressed by slash-separated keys,
+// e.g. "pets/1/3f2a9c.jpg". Backends are expected to be safe for concurrent use.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, info ObjectInfo) (ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type ObjectInfo struct {
+	Key         string            `json:"key"`
+	ContentType string            `json:"contentType"`
+	Size        int64             `json:"size"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
@@ -0,0 +1 @@
+DROP TABLE photo_thumbnails;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- photo thumbnails
+CREATE TABLE IF NOT EXISTS photo_thumbnails
+(
+    id           SERIAL PRIMARY KEY,
+    photo_url_id INTEGER,
+    name         VARCHAR(63),
+    width        INTEGER,
+    height       INTEGER,
+    url          VARCHAR(511)
+);
+
+CREATE INDEX IF NOT EXISTS photo_thumbnails_photo_url_id_idx ON photo_thumbnails (photo_url_id);
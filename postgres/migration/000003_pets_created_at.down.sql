@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS pets_name_idx;
+
+DROP INDEX IF EXISTS pets_created_at_idx;
+
+ALTER TABLE pets DROP COLUMN created_at;
\ No newline at end of file
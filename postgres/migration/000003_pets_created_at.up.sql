@@ -0,0 +1,6 @@
+-- pets creation time
+ALTER TABLE pets
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS pets_created_at_idx ON pets (created_at);
+CREATE INDEX IF NOT EXISTS pets_name_idx ON pets (name);
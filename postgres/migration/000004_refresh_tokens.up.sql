@@ -0,0 +1,15 @@
+-- refresh tokens, stored as sha256 hashes and rotated on every use;
+-- tokens issued from the same login share a family that is revoked as a whole on reuse
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id         SERIAL PRIMARY KEY,
+    family_id  VARCHAR(63) NOT NULL,
+    user_id    INTEGER     NOT NULL,
+    token_hash VARCHAR(63) NOT NULL UNIQUE,
+    expires_at TIMESTAMP   NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    used_at    TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS access_token_id;
+DROP TABLE revoked_tokens;
\ No newline at end of file
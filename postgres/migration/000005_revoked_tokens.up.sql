@@ -0,0 +1,15 @@
+-- access tokens revoked before their expiry, looked up by the jti claim;
+-- rows can be dropped once expires_at has passed
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    token_id   VARCHAR(63) PRIMARY KEY,
+    expires_at TIMESTAMP   NOT NULL,
+    revoked_at TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_revoked_at_idx ON revoked_tokens (revoked_at);
+
+-- the access token issued along with each refresh token, revoked with its session
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_token_id VARCHAR(63);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
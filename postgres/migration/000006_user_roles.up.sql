@@ -0,0 +1,3 @@
+-- user roles: customers place orders, staff manage pets, admins manage users
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(15) NOT NULL DEFAULT 'customer';
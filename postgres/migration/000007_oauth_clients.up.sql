@@ -0,0 +1,12 @@
+-- machine clients of the OAuth2 client credentials grant; secrets are stored as bcrypt
+-- hashes and scopes as a space separated list, e.g. 'read:pets write:pets'
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    id          SERIAL PRIMARY KEY,
+    client_id   VARCHAR(63)  NOT NULL UNIQUE,
+    name        VARCHAR(255) NOT NULL DEFAULT '',
+    secret_hash VARCHAR(255) NOT NULL,
+    scopes      VARCHAR(255) NOT NULL DEFAULT '',
+    is_disabled BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP    NOT NULL DEFAULT now()
+);
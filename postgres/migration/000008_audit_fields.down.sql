@@ -0,0 +1,7 @@
+ALTER TABLE pets
+    DROP COLUMN updated_by,
+    DROP COLUMN created_by;
+
+DROP INDEX IF EXISTS store_user_id_idx;
+
+ALTER TABLE store DROP COLUMN user_id;
\ No newline at end of file
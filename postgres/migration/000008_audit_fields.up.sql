@@ -0,0 +1,9 @@
+-- order ownership and pet audit fields; rows created before are left without an owner
+ALTER TABLE store
+    ADD COLUMN IF NOT EXISTS user_id INTEGER NULL REFERENCES users (id);
+
+CREATE INDEX IF NOT EXISTS store_user_id_idx ON store (user_id);
+
+ALTER TABLE pets
+    ADD COLUMN IF NOT EXISTS created_by VARCHAR(255) NULL,
+    ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255) NULL;
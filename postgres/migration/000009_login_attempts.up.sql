@@ -0,0 +1,9 @@
+-- failed logins per username ('user:<username>') and per client IP ('ip:<address>'),
+-- used to back off and lock out password guessing
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    key             VARCHAR(255) PRIMARY KEY,
+    failures        INTEGER   NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until    TIMESTAMP NULL
+);
@@ -0,0 +1,20 @@
+-- TOTP second factor of users, enabled_at stays empty until the enrollment is confirmed
+CREATE TABLE IF NOT EXISTS user_two_factor
+(
+    user_id        INTEGER PRIMARY KEY REFERENCES users (id),
+    secret         VARCHAR(64) NOT NULL,
+    enabled_at     TIMESTAMP   NULL,
+    last_used_step BIGINT      NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+-- single use codes to log in without the authenticator, stored as sha256 hashes
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id        SERIAL PRIMARY KEY,
+    user_id   INTEGER     NOT NULL REFERENCES users (id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at   TIMESTAMP   NULL
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
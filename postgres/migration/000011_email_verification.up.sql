@@ -0,0 +1,16 @@
+-- emails are unverified until the user follows the link mailed to them
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP NULL;
+
+-- tokens mailed to users for email verification and password reset, used_at makes them single-use
+CREATE TABLE IF NOT EXISTS user_tokens
+(
+    token_id   VARCHAR(64) PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id),
+    purpose    VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP   NOT NULL,
+    used_at    TIMESTAMP   NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_id_idx ON user_tokens (user_id);
@@ -0,0 +1,17 @@
+-- API keys of users for scripts and jobs; only the SHA-256 hash of a key is stored, the
+-- prefix tells keys apart in listings. Scopes are a space separated list as in oauth_clients
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER      NOT NULL REFERENCES users (id),
+    name         VARCHAR(64)  NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    key_hash     VARCHAR(64)  NOT NULL UNIQUE,
+    scopes       VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at   TIMESTAMP    NOT NULL,
+    last_used_at TIMESTAMP    NULL,
+    revoked_at   TIMESTAMP    NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
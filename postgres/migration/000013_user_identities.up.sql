@@ -0,0 +1,13 @@
+-- accounts of users at external OpenID providers; the subject is unique and never reassigned
+-- within its issuer, so the pair identifies the account even if its email changes
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER      NOT NULL REFERENCES users (id),
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);